@@ -0,0 +1,190 @@
+package matcher
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// adaptiveSampleRate is how often (in Test calls) condition costs are timed.
+const adaptiveSampleRate = 16
+
+// WithAdaptiveOrdering makes the matcher track how often each condition holds
+// and how long it takes, and reorder the query every n Test calls: AND
+// chains evaluate the conditions most likely to cheaply fail first and OR
+// branches the ones most likely to cheaply match first.
+//
+// Reordering never changes whether a context matches, but when several
+// conditions would fail to evaluate, a different error may be returned.
+// Queries calling functions are never reordered, because functions such as
+// COUNT_OVER keep state about the contexts that reach them.
+func WithAdaptiveOrdering(n int) Option {
+	return func(o *options) {
+		o.adaptiveEvery = n
+	}
+}
+
+// adaptiveStats counts evaluations of a condition or an AND chain. Fields
+// are accessed atomically.
+type adaptiveStats struct {
+	calls        int64
+	hits         int64
+	sampledCalls int64
+	sampledNanos int64
+}
+
+func (s *adaptiveStats) passRate() float64 {
+	calls := atomic.LoadInt64(&s.calls)
+	if calls == 0 {
+		return 0.5
+	}
+	return float64(atomic.LoadInt64(&s.hits)) / float64(calls)
+}
+
+func (s *adaptiveStats) cost() float64 {
+	calls := atomic.LoadInt64(&s.sampledCalls)
+	if calls == 0 {
+		return 1
+	}
+	return float64(atomic.LoadInt64(&s.sampledNanos)) / float64(calls)
+}
+
+// decay halves the counters so that the order follows changes in the data.
+func (s *adaptiveStats) decay() {
+	for _, p := range []*int64{&s.calls, &s.hits, &s.sampledCalls, &s.sampledNanos} {
+		atomic.StoreInt64(p, atomic.LoadInt64(p)/2)
+	}
+}
+
+type adaptiveBranch struct {
+	stats *adaptiveStats
+	and   []*Condition
+}
+
+// adaptive evaluates an expression in an order that is periodically
+// recomputed from the collected statistics.
+type adaptive struct {
+	every int64
+	tests int64 // atomic
+	stats map[*Condition]*adaptiveStats
+	order atomic.Value // []adaptiveBranch
+	mu    sync.Mutex   // held while reordering
+}
+
+func newAdaptive(e *Expression, every int) *adaptive {
+	a := &adaptive{every: int64(every), stats: make(map[*Condition]*adaptiveStats)}
+	order := make([]adaptiveBranch, len(e.Or))
+	for i, x := range e.Or {
+		order[i] = adaptiveBranch{stats: &adaptiveStats{}, and: append([]*Condition(nil), x.And...)}
+		for _, c := range x.And {
+			a.stats[c] = &adaptiveStats{}
+		}
+	}
+	a.order.Store(order)
+	return a
+}
+
+func (a *adaptive) eval(ctx Context) (bool, error) {
+	n := atomic.AddInt64(&a.tests, 1)
+	if a.every > 0 && n%a.every == 0 && a.mu.TryLock() {
+		a.reorder()
+		a.mu.Unlock()
+	}
+	sample := n%adaptiveSampleRate == 0
+
+	for _, x := range a.order.Load().([]adaptiveBranch) {
+		atomic.AddInt64(&x.stats.calls, 1)
+		matched := true
+		for _, c := range x.and {
+			s := a.stats[c]
+			var start time.Time
+			if sample {
+				start = time.Now()
+			}
+			b, err := c.Eval(ctx)
+			if sample {
+				atomic.AddInt64(&s.sampledNanos, int64(time.Since(start)))
+				atomic.AddInt64(&s.sampledCalls, 1)
+			}
+			atomic.AddInt64(&s.calls, 1)
+			if err != nil {
+				return false, err
+			}
+			if !b {
+				matched = false
+				break
+			}
+			atomic.AddInt64(&s.hits, 1)
+		}
+		if matched {
+			atomic.AddInt64(&x.stats.hits, 1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reorder sorts each AND chain by cost per rejection and the OR branches by
+// cost per match, cheapest first.
+func (a *adaptive) reorder() {
+	current := a.order.Load().([]adaptiveBranch)
+	order := make([]adaptiveBranch, len(current))
+	for i, x := range current {
+		and := append([]*Condition(nil), x.and...)
+		sort.SliceStable(and, func(i, j int) bool {
+			return a.rank(and[i]) < a.rank(and[j])
+		})
+		order[i] = adaptiveBranch{stats: x.stats, and: and}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return a.branchRank(order[i]) < a.branchRank(order[j])
+	})
+
+	for _, s := range a.stats {
+		s.decay()
+	}
+	for _, x := range order {
+		x.stats.decay()
+	}
+	a.order.Store(order)
+}
+
+// rank is the expected cost of a condition per context it rejects.
+func (a *adaptive) rank(c *Condition) float64 {
+	s := a.stats[c]
+	fail := 1 - s.passRate()
+	if fail <= 0 {
+		return math.Inf(1)
+	}
+	return s.cost() / fail
+}
+
+// branchRank is the expected cost of an AND chain per context it matches.
+func (a *adaptive) branchRank(x adaptiveBranch) float64 {
+	pass := x.stats.passRate()
+	if pass <= 0 {
+		return math.Inf(1)
+	}
+	cost := 0.0
+	for _, c := range x.and {
+		cost += a.stats[c].cost()
+	}
+	return cost / pass
+}
+
+// String returns the current evaluation order in query syntax.
+func (a *adaptive) String() string {
+	order := a.order.Load().([]adaptiveBranch)
+	parts := make([]string, len(order))
+	for i, x := range order {
+		and := make([]string, len(x.and))
+		for j, c := range x.and {
+			and[j] = c.String()
+		}
+		parts[i] = strings.Join(and, " AND ")
+	}
+	return strings.Join(parts, " OR ")
+}
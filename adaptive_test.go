@@ -0,0 +1,43 @@
+package matcher_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveOrdering(t *testing.T) {
+	assert := assert.New(t)
+	q := "always = 1 and rare = 1 or never = 1 and always = 1 or often = 1"
+	plain, err := matcher.NewMatcher(q)
+	assert.NoError(err)
+	m, err := matcher.NewMatcher(q, matcher.WithAdaptiveOrdering(50))
+	assert.NoError(err)
+	assert.Equal(plain.Expression.String(), m.EvaluationOrder())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				ctx := matcher.Context{
+					"always": 1.0,
+					"rare":   float64(i % 50),
+					"never":  0.0,
+					"often":  float64(i % 2),
+				}
+				want, err := plain.Test(&ctx)
+				assert.NoError(err)
+				got, err := m.Test(&ctx)
+				assert.NoError(err)
+				assert.Equal(want, got)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.Equal("often = 1 OR rare = 1 AND always = 1 OR never = 1 AND always = 1", m.EvaluationOrder())
+}
@@ -0,0 +1,147 @@
+package matcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one evaluation of a query, so that deployments can
+// keep why each context was accepted or rejected.
+type AuditRecord struct {
+	// Rule identifies the query, see WithRuleID.
+	Rule string `json:"rule"`
+	// Digest is the hex SHA-256 of the tested context in JSON, with keys
+	// sorted; values that can not be encoded are hashed in Go syntax.
+	Digest  string `json:"digest"`
+	Matched bool   `json:"matched"`
+	// Error is the evaluation error, if any.
+	Error    string        `json:"error,omitempty"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Predicates are the outcomes of the evaluated conditions, in
+	// evaluation order.
+	Predicates []AuditPredicate `json:"predicates"`
+}
+
+// AuditPredicate is the outcome of a condition.
+type AuditPredicate struct {
+	Predicate string `json:"predicate"`
+	// Branch is the index of the OR branch of the condition.
+	Branch int    `json:"branch"`
+	Result bool   `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditSink receives batches of audit records. Audit is called by the
+// goroutine completing a batch and must not retain records after it
+// returns.
+type AuditSink interface {
+	Audit(records []AuditRecord)
+}
+
+// AuditFunc adapts a function to an AuditSink.
+type AuditFunc func(records []AuditRecord)
+
+// Audit calls f(records).
+func (f AuditFunc) Audit(records []AuditRecord) {
+	f(records)
+}
+
+// Auditor batches the audit records of the matchers created with WithAudit
+// and hands them to a sink. It is safe for concurrent use.
+type Auditor struct {
+	sink AuditSink
+	size int
+
+	mu  sync.Mutex
+	buf []AuditRecord
+}
+
+// NewAuditor returns an Auditor passing records to sink in batches of
+// batchSize, or one by one if batchSize is less than 2. Call Flush to pass
+// the records of an incomplete batch, e.g. on shutdown.
+func NewAuditor(sink AuditSink, batchSize int) *Auditor {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &Auditor{sink: sink, size: batchSize}
+}
+
+// WithAudit records every Test of the matcher with a. Audited queries are
+// evaluated in query order, so WithAdaptiveOrdering has no effect.
+func WithAudit(a *Auditor) Option {
+	return func(o *options) {
+		o.audit = a
+	}
+}
+
+// WithRuleID sets the rule ID of audit records. NewRuleSet sets it to the
+// rule name.
+func WithRuleID(id string) Option {
+	return func(o *options) {
+		o.ruleID = id
+	}
+}
+
+// Flush passes the buffered records to the sink.
+func (a *Auditor) Flush() {
+	a.mu.Lock()
+	batch := a.buf
+	a.buf = nil
+	a.mu.Unlock()
+	if len(batch) > 0 {
+		a.sink.Audit(batch)
+	}
+}
+
+// add buffers a record, passing the batch to the sink once it is complete.
+func (a *Auditor) add(r AuditRecord) {
+	a.mu.Lock()
+	a.buf = append(a.buf, r)
+	var batch []AuditRecord
+	if len(a.buf) >= a.size {
+		batch = a.buf
+		a.buf = nil
+	}
+	a.mu.Unlock()
+	if batch != nil {
+		a.sink.Audit(batch)
+	}
+}
+
+// eval evaluates the query of m against ctx and records the evaluation.
+func (a *Auditor) eval(m *Matcher, ctx Context) (bool, error) {
+	r := AuditRecord{Rule: m.ruleID, Digest: digest(ctx), Time: time.Now()}
+	var b *budgetCheck
+	if m.budget != nil {
+		b = m.budget.check()
+	}
+	ok, err := m.Expression.walk(ctx, b, func(branch int, c *Condition, ok bool, err error) {
+		p := AuditPredicate{Predicate: c.String(), Branch: branch, Result: ok}
+		if err != nil {
+			p.Error = err.Error()
+		}
+		r.Predicates = append(r.Predicates, p)
+	})
+	r.Duration = time.Since(r.Time)
+	r.Matched = ok
+	if err != nil {
+		r.Error = err.Error()
+	}
+	a.add(r)
+	return ok, err
+}
+
+// digest returns the hex SHA-256 of a context.
+func digest(ctx Context) string {
+	b, err := json.Marshal(ctx)
+	if err != nil {
+		b = []byte(fmt.Sprintf("%#v", ctx))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,54 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	assert := assert.New(t)
+	var batches [][]matcher.AuditRecord
+	a := matcher.NewAuditor(matcher.AuditFunc(func(records []matcher.AuditRecord) {
+		batches = append(batches, records)
+	}), 2)
+	s, err := matcher.NewRuleSet([]matcher.Rule{{Name: "adult", Query: "age >= 18 and country = \"JP\" or vip = TRUE"}},
+		matcher.WithAudit(a), matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(err)
+
+	_, err = s.EvaluateFirst(&matcher.Context{"age": 20.0, "country": "US", "vip": true})
+	assert.NoError(err)
+	assert.Empty(batches)
+	_, err = s.EvaluateFirst(&matcher.Context{"age": true})
+	assert.Error(err)
+	assert.Len(batches, 1)
+
+	r := batches[0][0]
+	assert.Equal("adult", r.Rule)
+	assert.True(r.Matched)
+	assert.Len(r.Digest, 64)
+	assert.Equal([]matcher.AuditPredicate{
+		{Predicate: "age >= 18", Branch: 0, Result: true},
+		{Predicate: `country = "JP"`, Branch: 0, Result: false},
+		{Predicate: "vip = TRUE", Branch: 1, Result: true},
+	}, r.Predicates)
+
+	r = batches[0][1]
+	assert.False(r.Matched)
+	assert.NotEmpty(r.Error)
+	assert.NotEqual(batches[0][0].Digest, r.Digest)
+	assert.Len(r.Predicates, 1)
+	assert.NotEmpty(r.Predicates[0].Error)
+
+	m, err := matcher.NewMatcher("a = 1", matcher.WithAudit(a), matcher.WithRuleID("r"))
+	assert.NoError(err)
+	_, err = m.Test(&matcher.Context{"a": 1.0})
+	assert.NoError(err)
+	assert.Len(batches, 1)
+	a.Flush()
+	assert.Len(batches, 2)
+	assert.Equal("r", batches[1][0].Rule)
+	a.Flush()
+	assert.Len(batches, 2)
+}
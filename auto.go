@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// WithAutoVariables makes every evaluation see the variables
+//
+//   - _now, the current time in RFC 3339 format, e.g.
+//     "2024-05-01T14:30:00+02:00",
+//   - _today, the current date, e.g. "2024-05-01",
+//   - _weekday, the English name of the day, e.g. "Wednesday",
+//   - _hour, the hour of the day from 0 to 23,
+//   - _eval_id, a random identifier of the evaluation,
+//
+// so time-of-day rules like `_hour >= 9 AND _hour < 17 AND _weekday <>
+// "Sunday"` work without callers adding these fields. The time comes from the
+// clock set with WithClock, in its location. Variables are only computed
+// when the query refers to them, and keys of the tested context take
+// precedence over them.
+func WithAutoVariables() Option {
+	return func(o *options) {
+		o.auto = true
+	}
+}
+
+// autoVariables are the keys of the automatic variables.
+var autoVariables = map[string]bool{"_now": true, "_today": true, "_weekday": true, "_hour": true, "_eval_id": true}
+
+// autoVars computes the automatic variables of an evaluation on demand.
+type autoVars struct {
+	now   func() time.Time
+	t     time.Time
+	valid bool
+}
+
+// Lookup implements Resolver.
+func (a *autoVars) Lookup(key string) (interface{}, bool) {
+	if key == "_eval_id" {
+		var b [8]byte
+		rand.Read(b[:])
+		return hex.EncodeToString(b[:]), true
+	}
+	if !a.valid {
+		a.t, a.valid = a.now(), true
+	}
+	switch key {
+	case "_now":
+		return a.t.Format(time.RFC3339), true
+	case "_today":
+		return a.t.Format("2006-01-02"), true
+	case "_weekday":
+		return a.t.Weekday().String(), true
+	case "_hour":
+		return float64(a.t.Hour()), true
+	}
+	return nil, false
+}
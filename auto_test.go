@@ -0,0 +1,66 @@
+package matcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoVariables(t *testing.T) {
+	assert := assert.New(t)
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*3600))}
+	m, err := matcher.NewMatcher(`_hour >= 9 and _hour < 17 and _weekday <> "Sunday" and _today = "2024-05-01" and _now = "2024-05-01T14:30:00+02:00"`,
+		matcher.WithAutoVariables(), matcher.WithClock(clock.now))
+	assert.NoError(err)
+
+	c := matcher.Context{"a": 1}
+	ok, err := m.Test(&c)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(matcher.Context{"a": 1}, c)
+
+	clock.advance(5 * time.Hour)
+	ok, err = m.Test(&c)
+	assert.NoError(err)
+	assert.False(ok)
+
+	c = matcher.Context{"_hour": 10, "_now": "2024-05-01T14:30:00+02:00"}
+	matched, err := m.TestBatch([]matcher.Context{{}, c})
+	assert.NoError(err)
+	assert.Equal([]bool{false, true}, matched)
+}
+
+func TestAutoVariablesEvalID(t *testing.T) {
+	m, err := matcher.NewMatcher(`_eval_id <> NULL and _eval_id <> ""`, matcher.WithAutoVariables())
+	assert.NoError(t, err)
+	c := matcher.Context{}
+	ok, err := m.Test(&c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	m, err = matcher.NewMatcher(`_eval_id <> NULL`)
+	assert.NoError(t, err)
+	ok, err = m.Test(&c)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAutoVariablesFunctions(t *testing.T) {
+	assert := assert.New(t)
+	// BIG reads x, which none of its arguments name.
+	big := func([]*matcher.Arg) (matcher.Func, error) {
+		return func(c matcher.Context) (interface{}, error) {
+			x, _ := c["x"].(float64)
+			return x > 1, nil
+		}, nil
+	}
+	m, err := matcher.NewMatcher("BIG() and _hour >= 0", matcher.WithAutoVariables(), matcher.WithFunction("BIG", big), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	c := matcher.Context{"x": 2.0}
+	ok, err := m.Test(&c)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(matcher.Context{"x": 2.0}, c)
+}
@@ -0,0 +1,66 @@
+package matcher
+
+// TestBatch tests many contexts at once and reports which of them match.
+//
+// Instead of evaluating the whole query for one context after the other, each
+// condition is evaluated across all contexts still undecided by the previous
+// ones, which keeps the condition's state hot and skips contexts that already
+// failed an AND chain or matched an OR branch. On error, the first error met
+// in condition order is returned.
+func (m *Matcher) TestBatch(ctxs []Context) ([]bool, error) {
+	if m.now != nil {
+		resolved := make([]Context, len(ctxs))
+		for i, c := range ctxs {
+			resolved[i] = make(Context, len(m.symbols()))
+			m.resolve(c, resolved[i])
+		}
+		ctxs = resolved
+	}
+	return m.Expression.EvalBatch(ctxs)
+}
+
+// EvalBatch evaluates the expression against each context, see
+// Matcher.TestBatch.
+func (e *Expression) EvalBatch(ctxs []Context) ([]bool, error) {
+	matched := make([]bool, len(ctxs))
+	rows := make([]int, 0, len(ctxs))
+	for _, x := range e.Or {
+		// Only contexts not matched by a previous branch are evaluated.
+		rows = rows[:0]
+		for i, ok := range matched {
+			if !ok {
+				rows = append(rows, i)
+			}
+		}
+
+		var err error
+		for _, c := range x.And {
+			if len(rows) == 0 {
+				break
+			}
+			if rows, err = c.evalRows(ctxs, rows); err != nil {
+				return nil, err
+			}
+		}
+		for _, i := range rows {
+			matched[i] = true
+		}
+	}
+	return matched, nil
+}
+
+// evalRows evaluates the condition for the given rows of ctxs and returns the
+// rows it holds for, reusing the rows slice.
+func (x *Condition) evalRows(ctxs []Context, rows []int) ([]int, error) {
+	out := rows[:0]
+	for _, i := range rows {
+		b, err := x.Eval(ctxs[i])
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package matcher_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func batchContexts(n int) []matcher.Context {
+	ctxs := make([]matcher.Context, n)
+	for i := range ctxs {
+		ctxs[i] = matcher.Context{
+			"id":     float64(i),
+			"age":    float64(i % 90),
+			"status": fmt.Sprintf("s%d", i%5),
+			"active": i%3 == 0,
+		}
+	}
+	return ctxs
+}
+
+func TestBatchMatchesTest(t *testing.T) {
+	queries := []string{
+		"age > 30",
+		"age > 30 and status = \"s1\"",
+		"status = \"s2\" or active = TRUE and age < 10",
+		"id = 3 or id = 7 or missing = 1",
+	}
+	ctxs := batchContexts(100)
+
+	for _, q := range queries {
+		t.Run(q, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := matcher.NewMatcher(q)
+			assert.NoError(err)
+
+			got, err := m.TestBatch(ctxs)
+			assert.NoError(err)
+			for i := range ctxs {
+				want, err := m.Test(&ctxs[i])
+				assert.NoError(err)
+				assert.Equal(want, got[i], "context %d", i)
+			}
+		})
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	m, err := matcher.NewMatcher("status = TRUE", matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, err)
+
+	_, err = m.TestBatch(batchContexts(3))
+	assert.Error(t, err)
+}
+
+const batchQuery = "age > 30 and status = \"s1\" or active = TRUE and age < 10"
+
+func BenchmarkRowByRow(b *testing.B) {
+	m, _ := matcher.NewMatcher(batchQuery)
+	ctxs := batchContexts(10000)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for j := range ctxs {
+			m.Test(&ctxs[j])
+		}
+	}
+}
+
+func BenchmarkBatch(b *testing.B) {
+	m, _ := matcher.NewMatcher(batchQuery)
+	ctxs := batchContexts(10000)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.TestBatch(ctxs)
+	}
+}
@@ -0,0 +1,131 @@
+package matcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrBudgetExceeded is returned by Test when an evaluation does more work
+// than allowed with WithEvalBudget.
+var ErrBudgetExceeded = errors.New("matcher: evaluation budget exceeded")
+
+// WithEvalBudget limits the work of a single Test call: it evaluates at most
+// maxPredicates conditions and fails with ErrBudgetExceeded as soon as the
+// values matched against LIKE patterns, the results of calls and the list
+// elements of ANY and ALL included, add up to more than maxRegexBytes bytes.
+// A limit of 0 means no limit. This bounds the latency of untrusted rules run
+// inline in request paths.
+//
+// Conditions skipped by short-circuiting do not count. Queries with a
+// budget are evaluated in query order, so WithAdaptiveOrdering has no
+// effect.
+func WithEvalBudget(maxPredicates, maxRegexBytes int) Option {
+	return func(o *options) {
+		o.budget = &evalBudget{predicates: maxPredicates, regexBytes: maxRegexBytes}
+	}
+}
+
+// evalBudget holds the limits set with WithEvalBudget.
+type evalBudget struct {
+	predicates int
+	regexBytes int
+}
+
+// check returns the counter of the work of one evaluation.
+func (b *evalBudget) check() *budgetCheck {
+	return &budgetCheck{limits: b}
+}
+
+// budgetCheck counts the work of one evaluation against its limits.
+type budgetCheck struct {
+	limits     *evalBudget
+	predicates int
+	regexBytes int
+}
+
+// condition counts a condition about to be evaluated.
+func (b *budgetCheck) condition() error {
+	if b.predicates++; b.limits.predicates > 0 && b.predicates > b.limits.predicates {
+		return fmt.Errorf("%w: more than %d conditions", ErrBudgetExceeded, b.limits.predicates)
+	}
+	return nil
+}
+
+// matched counts the bytes of the operand v a condition was evaluated on
+// when it matches a pattern.
+func (b *budgetCheck) matched(c *Condition, v interface{}) error {
+	if c.Compare == nil || !isLike(c.Compare.Operator) {
+		return nil
+	}
+	switch v := v.(type) {
+	case string:
+		b.regexBytes += len(v)
+	case []string:
+		for _, s := range v {
+			b.regexBytes += len(s)
+		}
+	case []interface{}:
+		for _, e := range v {
+			s, _ := e.(string)
+			b.regexBytes += len(s)
+		}
+	case json.RawMessage:
+		b.regexBytes += len(v)
+	}
+	if b.limits.regexBytes > 0 && b.regexBytes > b.limits.regexBytes {
+		return fmt.Errorf("%w: more than %d bytes matched", ErrBudgetExceeded, b.limits.regexBytes)
+	}
+	return nil
+}
+
+// walk evaluates e against ctx like Eval, in query order. b, if not nil,
+// counts each condition and its operand and aborts the evaluation once a
+// limit is exceeded; after, if not nil, receives the outcome of each
+// condition. Both see the conditions of groups instead of the groups, with
+// the branch of the outermost group.
+func (e *Expression) walk(ctx Context, b *budgetCheck, after func(branch int, c *Condition, ok bool, err error)) (bool, error) {
+	for i, x := range e.Or {
+		matched := true
+		for _, c := range x.And {
+			var ok bool
+			var err error
+			if c.Group != nil {
+				var inner func(int, *Condition, bool, error)
+				if after != nil {
+					inner = func(_ int, c *Condition, ok bool, err error) { after(i, c, ok, err) }
+				}
+				if ok, err = c.Group.walk(ctx, b, inner); err == nil && c.Not {
+					ok = !ok
+				}
+			} else {
+				if b != nil {
+					if err := b.condition(); err != nil {
+						return false, err
+					}
+				}
+				var v interface{}
+				v, _, ok, err = c.eval(ctx)
+				if b != nil && err == nil {
+					if err = b.matched(c, v); err != nil {
+						ok = false
+					}
+				}
+				if after != nil {
+					after(i, c, ok, err)
+				}
+			}
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,48 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalBudget(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("a = 1 and b = 2 or c = 3", matcher.WithEvalBudget(2, 0))
+	assert.NoError(err)
+
+	// Short-circuiting keeps the evaluation within the budget.
+	ok, err := m.Test(&matcher.Context{"a": 1.0, "b": 2.0})
+	assert.NoError(err)
+	assert.True(ok)
+	ok, err = m.Test(&matcher.Context{"a": 0.0, "c": 3.0})
+	assert.NoError(err)
+	assert.True(ok)
+
+	ok, err = m.Test(&matcher.Context{"a": 1.0, "b": 0.0, "c": 3.0})
+	assert.ErrorIs(err, matcher.ErrBudgetExceeded)
+	assert.False(ok)
+
+	m, err = matcher.NewMatcher("a = 1 and b = 2 or c = 3", matcher.WithEvalBudget(0, 0), matcher.WithAdaptiveOrdering(1))
+	assert.NoError(err)
+	ok, err = m.Test(&matcher.Context{"a": 1.0, "b": 0.0, "c": 3.0})
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal("a = 1 AND b = 2 OR c = 3", m.EvaluationOrder())
+}
+
+func TestEvalBudgetRegexBytes(t *testing.T) {
+	assert := assert.New(t)
+	for _, q := range []string{`body LIKE "%x%"`, `LOWER(body) LIKE "%x%"`, `ANY(tags) LIKE "%x%"`} {
+		m, err := matcher.NewMatcher(q, matcher.WithEvalBudget(0, 8), matcher.WithSyntaxVersion(2))
+		assert.NoError(err, q)
+
+		ok, err := m.Test(&matcher.Context{"body": "abcx", "tags": []interface{}{"ab", "cx"}})
+		assert.NoError(err, q)
+		assert.True(ok, q)
+
+		_, err = m.Test(&matcher.Context{"body": "abcdefghi", "tags": []interface{}{"abcde", "fghi"}})
+		assert.ErrorIs(err, matcher.ErrBudgetExceeded, q)
+	}
+}
@@ -0,0 +1,89 @@
+package matcher
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the number of compiled matchers kept by Cached.
+const DefaultCacheSize = 1024
+
+var matcherCache = &lruCache{size: DefaultCacheSize}
+
+// Cached returns a compiled Matcher for q, parsing the query only when it is
+// not among the most recently used ones. It is safe for concurrent use.
+//
+// The returned Matcher is shared between callers and must not be modified.
+func Cached(q string) (*Matcher, error) {
+	if m, ok := matcherCache.get(q); ok {
+		return m, nil
+	}
+	m, err := NewMatcher(q)
+	if err != nil {
+		return nil, err
+	}
+	matcherCache.add(q, m)
+	return m, nil
+}
+
+// SetCacheSize changes how many compiled matchers Cached keeps, evicting the
+// least recently used ones if needed. A size of 0 or less disables caching.
+func SetCacheSize(n int) {
+	matcherCache.resize(n)
+}
+
+type lruEntry struct {
+	query   string
+	matcher *Matcher
+}
+
+// lruCache is a least recently used cache of matchers keyed by query.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func (c *lruCache) get(q string) (*Matcher, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[q]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).matcher, true
+}
+
+func (c *lruCache) add(q string, m *Matcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil {
+		c.order = list.New()
+		c.items = make(map[string]*list.Element)
+	}
+	if e, ok := c.items[q]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[q] = c.order.PushFront(&lruEntry{query: q, matcher: m})
+	c.evict()
+}
+
+func (c *lruCache) resize(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = n
+	c.evict()
+}
+
+// evict drops the least recently used entries above the size limit. The
+// caller holds c.mu.
+func (c *lruCache) evict() {
+	for c.order != nil && c.order.Len() > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).query)
+	}
+}
@@ -0,0 +1,33 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCached(t *testing.T) {
+	assert := assert.New(t)
+	defer matcher.SetCacheSize(matcher.DefaultCacheSize)
+	matcher.SetCacheSize(2)
+
+	a, err := matcher.Cached("a = 1")
+	assert.NoError(err)
+	again, err := matcher.Cached("a = 1")
+	assert.NoError(err)
+	assert.Same(a, again)
+
+	_, err = matcher.Cached("b = 1")
+	assert.NoError(err)
+	_, err = matcher.Cached("c = 1")
+	assert.NoError(err)
+
+	// "a = 1" was the least recently used query and got evicted.
+	evicted, err := matcher.Cached("a = 1")
+	assert.NoError(err)
+	assert.NotSame(a, evicted)
+
+	_, err = matcher.Cached("a =")
+	assert.Error(err)
+}
@@ -0,0 +1,88 @@
+package matcher
+
+import (
+	"context"
+	"sync"
+)
+
+// ChanOption configures FilterChan.
+type ChanOption func(*chanOptions)
+
+type chanOptions struct {
+	buffer  int
+	workers int
+}
+
+// WithChanBuffer sets the capacity of the channels returned by FilterChan, 0 by
+// default.
+func WithChanBuffer(n int) ChanOption {
+	return func(o *chanOptions) {
+		o.buffer = n
+	}
+}
+
+// WithChanWorkers makes FilterChan test n contexts concurrently. The contexts
+// are then sent in the order their tests finish rather than the input
+// order.
+func WithChanWorkers(n int) ChanOption {
+	return func(o *chanOptions) {
+		o.workers = n
+	}
+}
+
+// FilterChan sends the contexts received from in that match m to the
+// returned channel, and the errors of contexts failing to evaluate, which
+// are dropped, to the error channel. Both channels are closed once in is
+// closed or ctx is done, and must be drained until then.
+func FilterChan(ctx context.Context, m *Matcher, in <-chan Context, opts ...ChanOption) (<-chan Context, <-chan error) {
+	o := chanOptions{workers: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+
+	out := make(chan Context, o.buffer)
+	errs := make(chan error, o.buffer)
+	var wg sync.WaitGroup
+	wg.Add(o.workers)
+	for i := 0; i < o.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				var c Context
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case c, ok = <-in:
+					if !ok {
+						return
+					}
+				}
+				matched, err := m.Test(&c)
+				switch {
+				case err != nil:
+					select {
+					case errs <- err:
+					case <-ctx.Done():
+						return
+					}
+				case matched:
+					select {
+					case out <- c:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+		close(errs)
+	}()
+	return out, errs
+}
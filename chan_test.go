@@ -0,0 +1,64 @@
+package matcher_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterChan(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("n >= 5", matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(err)
+
+	for _, opts := range [][]matcher.ChanOption{nil, {matcher.WithChanWorkers(4), matcher.WithChanBuffer(8)}} {
+		in := make(chan matcher.Context)
+		go func() {
+			for i := 0; i < 10; i++ {
+				in <- matcher.Context{"n": float64(i)}
+			}
+			in <- matcher.Context{"n": true}
+			close(in)
+		}()
+
+		out, errs := matcher.FilterChan(context.Background(), m, in, opts...)
+		var got []float64
+		var errors []error
+		for out != nil || errs != nil {
+			select {
+			case c, ok := <-out:
+				if !ok {
+					out = nil
+					continue
+				}
+				got = append(got, c["n"].(float64))
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				errors = append(errors, err)
+			}
+		}
+		sort.Float64s(got)
+		assert.Equal([]float64{5, 6, 7, 8, 9}, got)
+		assert.Len(errors, 1)
+		assert.ErrorIs(errors[0], matcher.ErrTypeMismatch)
+	}
+}
+
+func TestFilterChanCancel(t *testing.T) {
+	m, err := matcher.NewMatcher("n >= 0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan matcher.Context)
+	out, errs := matcher.FilterChan(ctx, m, in)
+	cancel()
+	_, ok := <-out
+	assert.False(t, ok)
+	_, ok = <-errs
+	assert.False(t, ok)
+}
@@ -0,0 +1,83 @@
+package matcher
+
+import (
+	"errors"
+	"time"
+)
+
+// changed compiles CHANGED(field[, key]), which holds when the value of field
+// differs from its value in the previous context with the same value of key,
+// or in the previous context at all without a key. The first context of a key
+// is not a change. Contexts without field are ignored.
+func changed(c *Call, o *options) (Func, error) {
+	args := c.Args
+	if len(args) < 1 || len(args) > 2 || args[0].Symbol == "" || len(args) == 2 && args[1].Symbol == "" {
+		return nil, errors.New("want CHANGED(field[, key])")
+	}
+	field := args[0].Symbol
+	var keyField string
+	if len(args) == 2 {
+		keyField = args[1].Symbol
+	}
+	store, prefix := o.stateStore(), o.statePrefix(c)
+	return func(ctx Context) (interface{}, error) {
+		v, ok := lookup(ctx, field)
+		if !ok {
+			return false, nil
+		}
+		key := prefix
+		if keyField != "" {
+			k, _ := lookup(ctx, keyField)
+			key += keyString(k)
+		}
+		value := keyString(v)
+		old, found, err := store.Swap(key, value, 0)
+		if err != nil {
+			return false, err
+		}
+		return found && old != value, nil
+	}, nil
+}
+
+// firstSeen compiles FIRST_SEEN(field, window), which holds when the value of
+// field was not seen within the last window. Contexts without field do not
+// match.
+func firstSeen(c *Call, o *options) (Func, error) {
+	args := c.Args
+	if len(args) != 2 || args[0].Symbol == "" || args[1].Duration == nil {
+		return nil, errors.New("want FIRST_SEEN(field, window)")
+	}
+	field, window := args[0].Symbol, time.Duration(*args[1].Duration)
+	store, prefix := o.stateStore(), o.statePrefix(c)
+	return func(ctx Context) (interface{}, error) {
+		v, ok := lookup(ctx, field)
+		if !ok {
+			return false, nil
+		}
+		_, found, err := store.Swap(prefix+keyString(v), "", window)
+		if err != nil {
+			return false, err
+		}
+		return !found, nil
+	}, nil
+}
+
+// dedup compiles DEDUP(field, window), which holds for the first context with
+// a value of field and then not again until window has passed since that
+// first context. Contexts without field always match, as they can not be
+// duplicates.
+func dedup(c *Call, o *options) (Func, error) {
+	args := c.Args
+	if len(args) != 2 || args[0].Symbol == "" || args[1].Duration == nil {
+		return nil, errors.New("want DEDUP(field, window)")
+	}
+	field, window := args[0].Symbol, time.Duration(*args[1].Duration)
+	store, prefix := o.stateStore(), o.statePrefix(c)
+	return func(ctx Context) (interface{}, error) {
+		v, ok := lookup(ctx, field)
+		if !ok {
+			return true, nil
+		}
+		return store.Add(prefix+keyString(v), "", window)
+	}, nil
+}
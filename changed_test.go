@@ -0,0 +1,191 @@
+package matcher_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChanged(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("CHANGED(status, device_id)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	test := func(device string, status interface{}) bool {
+		ok, err := m.Test(&matcher.Context{"device_id": device, "status": status})
+		assert.NoError(err)
+		return ok
+	}
+	assert.False(test("a", "up"))
+	assert.False(test("b", "down"))
+	assert.False(test("a", "up"))
+	assert.True(test("a", "down"))
+	assert.False(test("b", "down"))
+	assert.True(test("b", 1.0))
+	assert.False(test("b", 1))
+
+	ok, err := m.Test(&matcher.Context{"device_id": "a"})
+	assert.NoError(err)
+	assert.False(ok)
+	assert.False(test("a", "down"))
+}
+
+func TestFirstSeen(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`FIRST_SEEN(device_id, 24h) and kind = "login"`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`FIRST_SEEN(device_id, 24h) AND kind = "login"`, m.Expression.String())
+
+	test := func(device string) bool {
+		ok, err := m.Test(&matcher.Context{"device_id": device, "kind": "login"})
+		assert.NoError(err)
+		return ok
+	}
+	assert.True(test("a"))
+	assert.False(test("a"))
+	assert.True(test("b"))
+	clock.advance(23 * time.Hour)
+	assert.False(test("a"))
+	clock.advance(23 * time.Hour)
+	assert.False(test("a")) // seen 23h ago
+	clock.advance(25 * time.Hour)
+	assert.True(test("a"))
+}
+
+// recordingStore is a StateStore counting its calls.
+type recordingStore struct {
+	*matcher.MemoryStore
+	mu   sync.Mutex
+	keys []string
+}
+
+func (s *recordingStore) Swap(key, value string, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	s.keys = append(s.keys, key)
+	s.mu.Unlock()
+	return s.MemoryStore.Swap(key, value, ttl)
+}
+
+func TestSharedStateStore(t *testing.T) {
+	assert := assert.New(t)
+	store := &recordingStore{MemoryStore: matcher.NewMemoryStore(0)}
+	opts := []matcher.Option{matcher.WithStateStore(store), matcher.WithRuleID("r"), matcher.WithSyntaxVersion(2)}
+	a, err := matcher.NewMatcher("first_seen(id, 1h)", opts...)
+	assert.NoError(err)
+	b, err := matcher.NewMatcher("FIRST_SEEN(id, 1h)", opts...)
+	assert.NoError(err)
+	other, err := matcher.NewMatcher("FIRST_SEEN(id, 1h)", matcher.WithStateStore(store), matcher.WithRuleID("other"),
+		matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	ok, err := a.Test(&matcher.Context{"id": "x"})
+	assert.NoError(err)
+	assert.True(ok)
+	ok, err = b.Test(&matcher.Context{"id": "x"})
+	assert.NoError(err)
+	assert.False(ok)
+	ok, err = other.Test(&matcher.Context{"id": "x"})
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal([]string{"rule r\x001\x00FIRST_SEEN(id, 1h)\x00x", "rule r\x001\x00FIRST_SEEN(id, 1h)\x00x",
+		"rule other\x001\x00FIRST_SEEN(id, 1h)\x00x"}, store.keys)
+}
+
+func TestChangedScope(t *testing.T) {
+	assert := assert.New(t)
+	// Each call remembers the previous value on its own.
+	m, err := matcher.NewMatcher("CHANGED(a) and CHANGED(a)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	for _, v := range []float64{1, 2} {
+		ok, err := m.Test(&matcher.Context{"a": v})
+		assert.NoError(err)
+		assert.False(ok)
+	}
+	ok, err := m.Test(&matcher.Context{"a": 3.0})
+	assert.NoError(err)
+	assert.True(ok)
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	assert := assert.New(t)
+	s := matcher.NewMemoryStore(2)
+	s.Swap("a", "1", 0)
+	s.Swap("b", "2", 0)
+	s.Swap("a", "3", 0)
+	s.Swap("c", "4", 0) // evicts b
+
+	old, found, err := s.Swap("a", "5", 0)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("3", old)
+	_, found, _ = s.Swap("b", "6", 0)
+	assert.False(found)
+}
+
+func TestDedup(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`status = "paid" and DEDUP(order_id, 10m)`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	test := func(order interface{}) bool {
+		ok, err := m.Test(&matcher.Context{"order_id": order, "status": "paid"})
+		assert.NoError(err)
+		return ok
+	}
+	assert.True(test(1.0))
+	assert.False(test(1.0))
+	assert.True(test(2.0))
+	clock.advance(9 * time.Minute)
+	assert.False(test(1)) // the window is not extended by duplicates
+	clock.advance(2 * time.Minute)
+	assert.True(test(1.0))
+	assert.False(test(1.0))
+
+	ok, err := m.Test(&matcher.Context{"status": "paid"})
+	assert.NoError(err)
+	assert.True(ok)
+}
+
+func TestDedupScope(t *testing.T) {
+	assert := assert.New(t)
+	store := matcher.NewMemoryStore(0)
+	test := func(q string, opts ...matcher.Option) bool {
+		m, err := matcher.NewMatcher(q, append(opts, matcher.WithStateStore(store), matcher.WithSyntaxVersion(2))...)
+		assert.NoError(err)
+		ok, err := m.Test(&matcher.Context{"id": 1.0})
+		assert.NoError(err)
+		return ok
+	}
+
+	assert.True(test("DEDUP(id, 10m)", matcher.WithRuleID("a")))
+	assert.False(test("DEDUP(id, 10m)", matcher.WithRuleID("a")))
+	assert.True(test("DEDUP(id, 10m)", matcher.WithRuleID("b")))
+	assert.True(test("DEDUP(id, 10m)"))
+	assert.True(test("DEDUP(id, 10m)"))
+	assert.True(test("DEDUP(id, 10m) and DEDUP(id, 10m)", matcher.WithRuleID("c")))
+}
+
+func TestMemoryStoreAdd(t *testing.T) {
+	assert := assert.New(t)
+	s := matcher.NewMemoryStore(0)
+	added, err := s.Add("a", "1", 0)
+	assert.NoError(err)
+	assert.True(added)
+	added, _ = s.Add("a", "2", 0)
+	assert.False(added)
+	old, found, _ := s.Swap("a", "3", 0)
+	assert.True(found)
+	assert.Equal("1", old)
+}
+
+func TestStatefulArgs(t *testing.T) {
+	for _, q := range []string{"CHANGED()", "CHANGED(1)", "CHANGED(a, 1)", "FIRST_SEEN(a)", "FIRST_SEEN(a, 5)", "DEDUP(a)", "DEDUP(5m, a)"} {
+		_, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, q)
+	}
+}
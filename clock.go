@@ -0,0 +1,20 @@
+package matcher
+
+import "time"
+
+// WithClock sets the clock used by time-dependent functions such as
+// COUNT_OVER instead of time.Now, e.g. to evaluate replayed events at their
+// own time or to control time in tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// clock returns the configured clock.
+func (o *options) clock() func() time.Time {
+	if o.now == nil {
+		return time.Now
+	}
+	return o.now
+}
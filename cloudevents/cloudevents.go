@@ -0,0 +1,191 @@
+// Package cloudevents builds matcher contexts from CloudEvents, so event
+// driven services can filter events by their attributes and payload.
+//
+// The context of an event has
+//
+//   - the context attributes under their names: id, source, specversion,
+//     type, and if present subject, time, datacontenttype, dataschema and
+//     every extension attribute,
+//   - the top-level fields of a JSON object payload as data_<name>, with
+//     characters that can not appear in a query identifier replaced by
+//     underscores,
+//   - any other payload as data: a JSON value decoded with numbers kept as
+//     json.Number, or the text of a non-JSON or base64 encoded payload.
+//
+// Events are accepted in the structured, batched and binary content modes of
+// the HTTP protocol binding.
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kuwa72/matcher"
+)
+
+// Media types of the structured and batched content modes.
+const (
+	StructuredContentType = "application/cloudevents+json"
+	BatchContentType      = "application/cloudevents-batch+json"
+)
+
+// ErrBatch is returned by FromRequest for requests in batched content mode,
+// which carry several events; use ParseBatch for their bodies.
+var ErrBatch = errors.New("cloudevents: batched content mode")
+
+// required are the context attributes every event must have.
+var required = []string{"id", "source", "specversion", "type"}
+
+// Parse returns the context of an event in structured content mode.
+func Parse(b []byte) (matcher.Context, error) {
+	var doc map[string]interface{}
+	if err := decode(b, &doc); err != nil {
+		return nil, err
+	}
+	return structured(doc)
+}
+
+// ParseBatch returns the contexts of the events of a batch.
+func ParseBatch(b []byte) ([]matcher.Context, error) {
+	var docs []map[string]interface{}
+	if err := decode(b, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]matcher.Context, len(docs))
+	for i, doc := range docs {
+		c, err := structured(doc)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+// FromRequest returns the context of the event in an HTTP request in
+// structured or binary content mode. The body is read.
+func FromRequest(r *http.Request) (matcher.Context, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case StructuredContentType:
+		return Parse(body)
+	case BatchContentType:
+		return nil, ErrBatch
+	}
+
+	c := matcher.Context{}
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "ce-") || len(values) == 0 {
+			continue
+		}
+		v, err := url.PathUnescape(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("cloudevents: header %s: %w", name, err)
+		}
+		c[strings.TrimPrefix(name, "ce-")] = v
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		c["datacontenttype"] = ct
+	}
+	if err := check(c); err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		if isJSON(mediaType) {
+			var data interface{}
+			if err := decode(body, &data); err != nil {
+				return nil, err
+			}
+			addData(c, data)
+		} else {
+			c["data"] = string(body)
+		}
+	}
+	return c, nil
+}
+
+// structured builds the context of an event decoded from JSON.
+func structured(doc map[string]interface{}) (matcher.Context, error) {
+	c := make(matcher.Context, len(doc))
+	for k, v := range doc {
+		if k != "data" && k != "data_base64" {
+			c[k] = v
+		}
+	}
+	if err := check(c); err != nil {
+		return nil, err
+	}
+	if s, ok := doc["data_base64"].(string); ok {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("cloudevents: data_base64: %w", err)
+		}
+		c["data"] = string(b)
+	} else if data, ok := doc["data"]; ok {
+		addData(c, data)
+	}
+	return c, nil
+}
+
+// check reports a missing required attribute.
+func check(c matcher.Context) error {
+	for _, name := range required {
+		if _, ok := c[name].(string); !ok {
+			return fmt.Errorf("cloudevents: missing attribute %s", name)
+		}
+	}
+	return nil
+}
+
+// addData adds a decoded JSON payload to c.
+func addData(c matcher.Context, data interface{}) {
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		c["data"] = data
+		return
+	}
+	for k, v := range obj {
+		c["data_"+identifier(k)] = v
+	}
+}
+
+// isJSON reports whether a media type is JSON, the default for events
+// without a content type.
+func isJSON(mediaType string) bool {
+	return mediaType == "" || mediaType == "application/json" || mediaType == "text/json" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// decode decodes JSON keeping numbers as json.Number.
+func decode(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("cloudevents: %w", err)
+	}
+	return nil
+}
+
+// identifier replaces the characters of name that can not appear in a query
+// identifier with underscores.
+func identifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
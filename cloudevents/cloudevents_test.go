@@ -0,0 +1,117 @@
+package cloudevents_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/cloudevents"
+	"github.com/stretchr/testify/assert"
+)
+
+const event = `{
+	"specversion": "1.0",
+	"id": "A234-1234-1234",
+	"source": "/orders",
+	"type": "com.example.order.created",
+	"subject": "123",
+	"time": "2024-04-05T17:31:00Z",
+	"tenant": "acme",
+	"datacontenttype": "application/json",
+	"data": {"total": 120.5, "currency": "EUR", "line-items": 3}
+}`
+
+func TestParse(t *testing.T) {
+	c, err := cloudevents.Parse([]byte(event))
+	assert.NoError(t, err)
+	assert.Equal(t, matcher.Context{
+		"specversion":     "1.0",
+		"id":              "A234-1234-1234",
+		"source":          "/orders",
+		"type":            "com.example.order.created",
+		"subject":         "123",
+		"time":            "2024-04-05T17:31:00Z",
+		"tenant":          "acme",
+		"datacontenttype": "application/json",
+		"data_total":      json.Number("120.5"),
+		"data_currency":   "EUR",
+		"data_line_items": json.Number("3"),
+	}, c)
+
+	m, err := matcher.NewMatcher(`type = "com.example.order.created" and tenant = "acme" and data_total > 100`)
+	assert.NoError(t, err)
+	ok, err := m.Test(&c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestParseData(t *testing.T) {
+	c, err := cloudevents.Parse([]byte(`{"specversion":"1.0","id":"1","source":"s","type":"t","data":42}`))
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("42"), c["data"])
+
+	c, err = cloudevents.Parse([]byte(`{"specversion":"1.0","id":"1","source":"s","type":"t","data_base64":"aGVsbG8="}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", c["data"])
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := cloudevents.Parse([]byte(`{"specversion":"1.0","id":"1","source":"s"}`))
+	assert.EqualError(t, err, "cloudevents: missing attribute type")
+	_, err = cloudevents.Parse([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestParseBatch(t *testing.T) {
+	cs, err := cloudevents.ParseBatch([]byte(`[` + event + `,{"specversion":"1.0","id":"2","source":"s","type":"t"}]`))
+	assert.NoError(t, err)
+	assert.Len(t, cs, 2)
+	assert.Equal(t, "2", cs[1]["id"])
+
+	_, err = cloudevents.ParseBatch([]byte(`[{"id":"1"}]`))
+	assert.EqualError(t, err, "event 0: cloudevents: missing attribute source")
+}
+
+func TestFromRequestBinary(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"total": 7}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	r.Header.Set("ce-specversion", "1.0")
+	r.Header.Set("ce-id", "1")
+	r.Header.Set("ce-source", "/orders")
+	r.Header.Set("ce-type", "com.example.order.created")
+	r.Header.Set("ce-subject", "order%20123")
+	r.Header.Set("X-Other", "ignored")
+
+	c, err := cloudevents.FromRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, matcher.Context{
+		"specversion":     "1.0",
+		"id":              "1",
+		"source":          "/orders",
+		"type":            "com.example.order.created",
+		"subject":         "order 123",
+		"datacontenttype": "application/json; charset=utf-8",
+		"data_total":      json.Number("7"),
+	}, c)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`plain`))
+	r.Header.Set("Content-Type", "text/plain")
+	_, err = cloudevents.FromRequest(r)
+	assert.EqualError(t, err, "cloudevents: missing attribute id")
+}
+
+func TestFromRequestStructured(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(event))
+	r.Header.Set("Content-Type", cloudevents.StructuredContentType+"; charset=utf-8")
+	c, err := cloudevents.FromRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", c["tenant"])
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[]`))
+	r.Header.Set("Content-Type", cloudevents.BatchContentType)
+	_, err = cloudevents.FromRequest(r)
+	assert.ErrorIs(t, err, cloudevents.ErrBatch)
+}
@@ -0,0 +1,38 @@
+package matcher
+
+import (
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// WithCollation compares string literals with context strings using the
+// collation rules of the given language instead of byte order, so that
+// ordering rules over names and titles follow the locale. Collation options
+// such as collate.IgnoreCase also apply to = and !=.
+func WithCollation(tag language.Tag, opts ...collate.Option) Option {
+	return func(o *options) {
+		o.collator = newCollatorPool(tag, opts)
+	}
+}
+
+// collatorPool hands out collators, which are not safe for concurrent use.
+type collatorPool struct {
+	pool sync.Pool
+}
+
+func newCollatorPool(tag language.Tag, opts []collate.Option) *collatorPool {
+	p := &collatorPool{}
+	p.pool.New = func() interface{} {
+		return collate.New(tag, opts...)
+	}
+	return p
+}
+
+// compare compares a and b with the collation rules.
+func (p *collatorPool) compare(a, b string) int {
+	c := p.pool.Get().(*collate.Collator)
+	defer p.pool.Put(c)
+	return c.CompareString(a, b)
+}
@@ -0,0 +1,63 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+func TestCollation(t *testing.T) {
+	cases := []struct {
+		query   string
+		value   string
+		opts    []collate.Option
+		bytes   bool
+		collate bool
+	}{
+		// byte order puts every lower case letter after "Z"
+		{`name < "Zoe"`, "adam", nil, false, true},
+		// byte order puts "é" after "f"
+		{`name < "f"`, "émile", nil, false, true},
+		{`name = "ADAM"`, "adam", []collate.Option{collate.IgnoreCase}, false, true},
+		{`name != "ADAM"`, "adam", []collate.Option{collate.IgnoreCase}, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := matcher.Context{"name": c.value}
+
+			m, err := matcher.NewMatcher(c.query)
+			assert.NoError(err)
+			ok, err := m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.bytes, ok)
+
+			m, err = matcher.NewMatcher(c.query, matcher.WithCollation(language.French, c.opts...))
+			assert.NoError(err)
+			ok, err = m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.collate, ok)
+		})
+	}
+}
+
+func TestCollationLanguages(t *testing.T) {
+	// "ö" sorts like "o" in German but after "z" in Swedish.
+	ctx := matcher.Context{"name": "östen"}
+
+	m, err := matcher.NewMatcher(`name < "zeta"`, matcher.WithCollation(language.German))
+	assert.NoError(t, err)
+	ok, err := m.Test(&ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	m, err = matcher.NewMatcher(`name < "zeta"`, matcher.WithCollation(language.Swedish))
+	assert.NoError(t, err)
+	ok, err = m.Test(&ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,87 @@
+package matcher
+
+// Weights of the components of a Complexity score.
+const (
+	predicateWeight = 1
+	callWeight      = 4
+	regexWeight     = 10
+	depthWeight     = 2
+)
+
+// Complexity estimates the evaluation cost of a query, so that services
+// accepting rules from their users can reject expensive ones up front.
+type Complexity struct {
+	// Score is Predicates + 4*Calls + 10*Regexes + 2*(Depth-1).
+	Score int `json:"score"`
+	// Predicates is the number of conditions.
+	Predicates int `json:"predicates"`
+	// Calls is the number of function calls, which may keep state or do
+	// more work than a comparison.
+	Calls int `json:"calls"`
+	// Regexes is the number of pattern matches.
+	Regexes int `json:"regexes"`
+	// Depth is the nesting depth of the query: 1 for a single condition, 2
+	// for conditions joined by AND or by OR, 3 for an OR of AND chains, and
+	// more with parenthesized groups.
+	Depth int `json:"depth"`
+	// Branches is the score of every OR branch, in query order.
+	Branches []int `json:"branches"`
+}
+
+// Complexity returns the complexity of the expression.
+func (e *Expression) Complexity() Complexity {
+	var c Complexity
+	for _, x := range e.Or {
+		score := 0
+		for _, cond := range x.And {
+			if cond.Group != nil {
+				g := cond.Group.Complexity()
+				c.Predicates += g.Predicates
+				c.Calls += g.Calls
+				c.Regexes += g.Regexes
+				score += g.Score - (g.Depth-1)*depthWeight
+				continue
+			}
+			s := predicateWeight
+			c.Predicates++
+			if cond.Call != nil {
+				c.Calls++
+				s += callWeight
+			}
+			if cond.Compare != nil && isLike(cond.Compare.Operator) {
+				c.Regexes++
+				s += regexWeight
+			}
+			score += s
+		}
+		c.Branches = append(c.Branches, score)
+	}
+	c.Depth = e.depth()
+	c.Score = c.Predicates*predicateWeight + c.Calls*callWeight + c.Regexes*regexWeight + (c.Depth-1)*depthWeight
+	return c
+}
+
+// depth returns the nesting depth of the expression.
+func (e *Expression) depth() int {
+	depth := 0
+	for _, x := range e.Or {
+		d := 1
+		for _, c := range x.And {
+			if c.Group != nil {
+				if g := c.Group.depth(); g > d {
+					d = g
+				}
+			}
+		}
+		if len(x.And) > 1 {
+			d++
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	if len(e.Or) > 1 {
+		depth++
+	}
+	return depth
+}
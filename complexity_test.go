@@ -0,0 +1,25 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplexity(t *testing.T) {
+	for _, tt := range []struct {
+		q    string
+		want matcher.Complexity
+	}{
+		{"a = 1", matcher.Complexity{Score: 1, Predicates: 1, Depth: 1, Branches: []int{1}}},
+		{"a = 1 or b = 2", matcher.Complexity{Score: 4, Predicates: 2, Depth: 2, Branches: []int{1, 1}}},
+		{"a = 1 and b = 2", matcher.Complexity{Score: 4, Predicates: 2, Depth: 2, Branches: []int{2}}},
+		{"a = 1 and ROLLOUT(id, 5) or COUNT_OVER(1m) > 3",
+			matcher.Complexity{Score: 15, Predicates: 3, Calls: 2, Depth: 3, Branches: []int{6, 5}}},
+	} {
+		m, err := matcher.NewMatcher(tt.q, matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, m.Expression.Complexity(), tt.q)
+	}
+}
@@ -0,0 +1,28 @@
+package matcher
+
+import "sync"
+
+var contextPool = sync.Pool{
+	New: func() interface{} { return make(Context) },
+}
+
+// AcquireContext returns an empty Context from a pool. Stream consumers
+// decoding one record after the other can hand it back with ReleaseContext
+// instead of allocating a new map per record.
+func AcquireContext() Context {
+	return contextPool.Get().(Context)
+}
+
+// ReleaseContext resets c and returns it to the pool. c must not be used
+// after it has been released.
+func ReleaseContext(c Context) {
+	c.Reset()
+	contextPool.Put(c)
+}
+
+// Reset removes all keys from the context, keeping its allocated space.
+func (c Context) Reset() {
+	for k := range c {
+		delete(c, k)
+	}
+}
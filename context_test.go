@@ -0,0 +1,32 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextReset(t *testing.T) {
+	c := matcher.Context{"a": 1.0, "b": "x"}
+	c.Reset()
+	assert.Empty(t, c)
+}
+
+func TestAcquireReleaseContext(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("a = 1")
+	assert.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		c := matcher.AcquireContext()
+		assert.Empty(c)
+		c["a"] = 1.0
+		c["b"] = "x"
+
+		ok, err := m.Test(&c)
+		assert.NoError(err)
+		assert.True(ok)
+		matcher.ReleaseContext(c)
+	}
+}
@@ -0,0 +1,336 @@
+package matcher
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+)
+
+// Dataset is a fixed set of contexts that is filtered repeatedly. Indexes
+// built with Index let Filter skip the contexts that cannot match a query
+// instead of evaluating the query against every context.
+//
+// A Dataset must not be modified while it is filtered.
+type Dataset struct {
+	records []Context
+	indexes map[string]*fieldIndex
+}
+
+// NewDataset returns a Dataset of records. The records must not be modified
+// after indexing.
+func NewDataset(records []Context) *Dataset {
+	return &Dataset{records: records, indexes: make(map[string]*fieldIndex)}
+}
+
+// Records returns the contexts of the dataset.
+func (d *Dataset) Records() []Context {
+	return d.records
+}
+
+// Index builds hash indexes for equality, sorted indexes for range and
+// trigram indexes for LIKE conditions on the given fields, which may be
+// paths like "address.city".
+func (d *Dataset) Index(fields ...string) {
+	for _, f := range fields {
+		d.indexes[f] = buildFieldIndex(d.records, f)
+	}
+}
+
+// Filter returns the records matching m, in dataset order, as m.Test
+// reports them.
+//
+// When every OR branch of the query has a condition on an indexed field,
+// only the records selected by those indexes are evaluated. Records skipped
+// this way are not evaluated at all, so errors they would have raised in
+// other conditions are not reported.
+func (d *Dataset) Filter(m *Matcher) ([]Context, error) {
+	rows := d.candidates(m)
+	var out []Context
+	if rows == nil {
+		for i := range d.records {
+			ok, err := m.Test(&d.records[i])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = append(out, d.records[i])
+			}
+		}
+		return out, nil
+	}
+
+	for _, i := range rows {
+		ok, err := m.Test(&d.records[i])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, d.records[i])
+		}
+	}
+	return out, nil
+}
+
+// candidates returns the sorted rows that may match m, or nil when a full
+// scan is needed. Fields the automatic variables of m may supply are not
+// looked up in indexes, which only know the values of the records.
+func (d *Dataset) candidates(m *Matcher) []int {
+	selected := make([]bool, len(d.records))
+	for _, x := range m.Expression.Or {
+		var best []int
+		found := false
+		for _, c := range x.And {
+			idx, ok := d.indexes[c.Symbol]
+			if !ok || c.Group != nil || c.Not || c.fn != nil || c.Compare.Field != "" || c.approximate() || c.exactDecimal() ||
+				c.opts != nil && (c.opts.normalize || c.opts.collator != nil) || m.now != nil && autoVariables[c.Symbol] {
+				continue
+			}
+			if rows, ok := idx.lookup(c); ok && (!found || len(rows) < len(best)) {
+				best, found = rows, true
+			}
+		}
+		if !found {
+			return nil
+		}
+		for _, i := range best {
+			selected[i] = true
+		}
+	}
+
+	rows := []int{}
+	for i, ok := range selected {
+		if ok {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+type numberEntry struct {
+	n   float64
+	row int
+}
+
+type textEntry struct {
+	s   string
+	row int
+}
+
+// fieldIndex indexes the values of one field.
+//
+// Number literals compare numerically with numbers and numeric strings and
+// lexically with other strings, while string literals compare with strings
+// only. Values of any other type are kept aside and are always candidates,
+// as are non-strings for string ordering, so that lookups never drop a row
+// the full evaluation would match or fail on.
+type fieldIndex struct {
+	numbers   map[float64][]int
+	strings   map[string][]int
+	byNumber  []numberEntry // numbers and numeric strings
+	byText    []textEntry   // non-numeric strings
+	byString  []textEntry   // all strings
+	others    []int         // present values that are neither numbers nor strings
+	nonString []int         // present values that are not strings
+	temporal  []int         // time.Time and time.Duration values, equal to some strings
+	raw       []int         // json.RawMessage values, which may hold strings
+
+	// trigrams are the sorted rows of the strings containing each trigram.
+	trigrams map[string][]int
+}
+
+func buildFieldIndex(records []Context, field string) *fieldIndex {
+	idx := &fieldIndex{numbers: make(map[float64][]int), strings: make(map[string][]int),
+		trigrams: make(map[string][]int)}
+	for i, r := range records {
+		v, ok := lookup(r, field)
+		if !ok {
+			continue
+		}
+		switch x := v.(type) {
+		case float64:
+			idx.nonString = append(idx.nonString, i)
+			if math.IsNaN(x) {
+				idx.others = append(idx.others, i)
+				continue
+			}
+			idx.numbers[x] = append(idx.numbers[x], i)
+			idx.byNumber = append(idx.byNumber, numberEntry{x, i})
+		case string:
+			idx.strings[x] = append(idx.strings[x], i)
+			idx.byString = append(idx.byString, textEntry{x, i})
+			for j := 0; j+3 <= len(x); j++ {
+				g := x[j : j+3]
+				if rows := idx.trigrams[g]; len(rows) == 0 || rows[len(rows)-1] != i {
+					idx.trigrams[g] = append(rows, i)
+				}
+			}
+			if n, ok := parseNumber(x); ok {
+				idx.numbers[n] = append(idx.numbers[n], i)
+				idx.byNumber = append(idx.byNumber, numberEntry{n, i})
+			} else {
+				idx.byText = append(idx.byText, textEntry{x, i})
+			}
+		case time.Time, time.Duration:
+			idx.nonString = append(idx.nonString, i)
+			idx.others = append(idx.others, i)
+			idx.temporal = append(idx.temporal, i)
+		case json.RawMessage:
+			idx.nonString = append(idx.nonString, i)
+			idx.others = append(idx.others, i)
+			idx.raw = append(idx.raw, i)
+		default:
+			idx.nonString = append(idx.nonString, i)
+			n, ok := toFloat(x)
+			if !ok || math.IsNaN(n) {
+				idx.others = append(idx.others, i)
+				continue
+			}
+			idx.numbers[n] = append(idx.numbers[n], i)
+			idx.byNumber = append(idx.byNumber, numberEntry{n, i})
+		}
+	}
+	sort.SliceStable(idx.byNumber, func(i, j int) bool { return idx.byNumber[i].n < idx.byNumber[j].n })
+	sort.SliceStable(idx.byText, func(i, j int) bool { return idx.byText[i].s < idx.byText[j].s })
+	sort.SliceStable(idx.byString, func(i, j int) bool { return idx.byString[i].s < idx.byString[j].s })
+	return idx
+}
+
+// lookup returns the rows that may satisfy the comparison of x, or false
+// when the comparison cannot use the index. Rows whose values x fails on, as
+// its mismatch policy or strict types make it, are candidates too.
+func (idx *fieldIndex) lookup(x *Condition) ([]int, bool) {
+	c := x.Compare
+	v := c.Value
+	fails := x.mismatchPolicy() == MismatchError
+	strict := x.opts != nil && x.opts.strict
+	var rows []int
+	switch {
+	case v.Float != nil:
+		f, text := *v.Float, v.floatText()
+		switch c.Operator {
+		case "=":
+			rows = append(rows, idx.numbers[f]...)
+			rows = append(rows, textRange(idx.byText, "=", text)...)
+		case ">", ">=", "<", "<=":
+			rows = append(rows, numberRange(idx.byNumber, c.Operator, f)...)
+			rows = append(rows, textRange(idx.byText, c.Operator, text)...)
+		default:
+			return nil, false
+		}
+		rows = append(rows, idx.others...)
+		if strict {
+			// Strings, numeric ones included, fail.
+			for _, e := range idx.byString {
+				rows = append(rows, e.row)
+			}
+		}
+	case v.String != nil:
+		switch c.Operator {
+		case "=":
+			rows = append(rows, idx.strings[*v.String]...)
+			rows = append(rows, idx.temporal...)
+			rows = append(rows, idx.raw...)
+			if fails {
+				rows = append(rows, idx.nonString...)
+			}
+		case ">", ">=", "<", "<=":
+			rows = append(rows, textRange(idx.byString, c.Operator, *v.String)...)
+			rows = append(rows, idx.nonString...)
+		case "LIKE":
+			var ok bool
+			if rows, ok = idx.like(*v.String); !ok {
+				return nil, false
+			}
+			rows = append(rows, idx.nonString...)
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	return rows, true
+}
+
+// like returns the rows of strings containing every trigram of the literal
+// text of the LIKE pattern p, or false when that text has no trigrams.
+func (idx *fieldIndex) like(p string) ([]int, bool) {
+	var lists [][]int
+	for _, lit := range likeLiterals(p) {
+		for j := 0; j+3 <= len(lit); j++ {
+			lists = append(lists, idx.trigrams[lit[j:j+3]])
+		}
+	}
+	if len(lists) == 0 {
+		return nil, false
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	rows := lists[0]
+	for _, l := range lists[1:] {
+		rows = intersect(rows, l)
+	}
+	return rows, true
+}
+
+// intersect returns the rows in both sorted a and b.
+func intersect(a, b []int) []int {
+	var out []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// numberRange returns the rows of sorted entries whose number satisfies
+// "n op f".
+func numberRange(entries []numberEntry, op string, f float64) []int {
+	lo, hi := 0, len(entries)
+	switch op {
+	case ">":
+		lo = sort.Search(len(entries), func(i int) bool { return entries[i].n > f })
+	case ">=":
+		lo = sort.Search(len(entries), func(i int) bool { return entries[i].n >= f })
+	case "<":
+		hi = sort.Search(len(entries), func(i int) bool { return entries[i].n >= f })
+	case "<=":
+		hi = sort.Search(len(entries), func(i int) bool { return entries[i].n > f })
+	}
+	rows := make([]int, 0, hi-lo)
+	for _, e := range entries[lo:hi] {
+		rows = append(rows, e.row)
+	}
+	return rows
+}
+
+// textRange returns the rows of sorted entries whose string satisfies
+// "s op t".
+func textRange(entries []textEntry, op string, t string) []int {
+	lo, hi := 0, len(entries)
+	switch op {
+	case "=":
+		lo = sort.Search(len(entries), func(i int) bool { return entries[i].s >= t })
+		hi = sort.Search(len(entries), func(i int) bool { return entries[i].s > t })
+	case ">":
+		lo = sort.Search(len(entries), func(i int) bool { return entries[i].s > t })
+	case ">=":
+		lo = sort.Search(len(entries), func(i int) bool { return entries[i].s >= t })
+	case "<":
+		hi = sort.Search(len(entries), func(i int) bool { return entries[i].s >= t })
+	case "<=":
+		hi = sort.Search(len(entries), func(i int) bool { return entries[i].s > t })
+	}
+	rows := make([]int, 0, hi-lo)
+	for _, e := range entries[lo:hi] {
+		rows = append(rows, e.row)
+	}
+	return rows
+}
@@ -0,0 +1,280 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+func datasetRecords(n int) []matcher.Context {
+	records := make([]matcher.Context, n)
+	for i := range records {
+		r := matcher.Context{
+			"id":     float64(i),
+			"status": fmt.Sprintf("s%d", i%5),
+		}
+		switch i % 4 {
+		case 0:
+			r["score"] = float64(i % 100)
+		case 1:
+			r["score"] = fmt.Sprint(i % 100) // numeric string
+		case 2:
+			r["score"] = "n/a"
+		}
+		records[i] = r
+	}
+	return records
+}
+
+func TestDatasetFilterMatchesScan(t *testing.T) {
+	queries := []string{
+		"id = 42",
+		"status = \"s3\"",
+		"status >= \"s3\" and id < 50",
+		"score > 90",
+		"score = 7 or status = \"s1\" and id <= 10",
+		"score < 5 and status = \"s0\"",
+		"score = \"n/a\" and id > 990",
+		"id != 3 or status = \"s2\"", // no usable index, full scan
+	}
+	records := datasetRecords(1000)
+	scan := matcher.NewDataset(records)
+	indexed := matcher.NewDataset(records)
+	indexed.Index("id", "status", "score")
+
+	for _, q := range queries {
+		t.Run(q, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := matcher.NewMatcher(q)
+			assert.NoError(err)
+
+			want, err := scan.Filter(m)
+			assert.NoError(err)
+			got, err := indexed.Filter(m)
+			assert.NoError(err)
+			assert.Equal(want, got)
+		})
+	}
+}
+
+func TestDatasetFilterNormalization(t *testing.T) {
+	assert := assert.New(t)
+	records := []matcher.Context{{"name": "caf\u00e9"}, {"name": "cafe\u0301"}, {"name": "Cafe"}}
+	d := matcher.NewDataset(records)
+	d.Index("name")
+	for _, opt := range []matcher.Option{
+		matcher.WithUnicodeNormalization(norm.NFC),
+		matcher.WithCollation(language.French, collate.IgnoreCase),
+	} {
+		m, err := matcher.NewMatcher(`name = "caf\u00e9"`, opt)
+		assert.NoError(err)
+		var want []matcher.Context
+		for _, r := range records {
+			if ok, _ := m.Test(&r); ok {
+				want = append(want, r)
+			}
+		}
+		got, err := d.Filter(m)
+		assert.NoError(err)
+		assert.Equal(want, got)
+		assert.GreaterOrEqual(len(got), 2)
+	}
+}
+
+func TestDatasetFilterMatcherOptions(t *testing.T) {
+	assert := assert.New(t)
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)}
+	records := []matcher.Context{
+		{"id": 1.0, "price": 60.0, "qty": 2.0},
+		{"id": 2.0, "price": 10.0, "qty": 2.0, "_hour": 3.0},
+		{"id": 3.0, "price": "x", "qty": 1.0},
+	}
+	d := matcher.NewDataset(records)
+	d.Index("id", "_hour", "total")
+
+	m, err := matcher.NewMatcher(`_hour = 14 AND id < 3`, matcher.WithAutoVariables(), matcher.WithClock(clock.now))
+	assert.NoError(err)
+	got, err := d.Filter(m)
+	assert.NoError(err)
+	assert.Equal(records[:1], got)
+
+	total := func(c matcher.Context) interface{} {
+		p, ok := c["price"].(float64)
+		if !ok {
+			return nil
+		}
+		return p * c["qty"].(float64)
+	}
+	m, err = matcher.NewMatcher(`total > 100`, matcher.WithDerivedField("total", total, "price", "qty"))
+	assert.NoError(err)
+	got, err = d.Filter(m)
+	assert.NoError(err)
+	assert.Equal(records[:1], got)
+
+	m, err = matcher.NewMatcher(`price > 1`, matcher.WithStrictTypes())
+	assert.NoError(err)
+	_, err = d.Filter(m)
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+}
+
+func BenchmarkDatasetScan(b *testing.B) {
+	m, _ := matcher.NewMatcher("id = 4242 or status = \"s1\" and id < 100")
+	d := matcher.NewDataset(datasetRecords(100000))
+
+	for i := 0; i < b.N; i++ {
+		d.Filter(m)
+	}
+}
+
+func BenchmarkDatasetIndexed(b *testing.B) {
+	m, _ := matcher.NewMatcher("id = 4242 or status = \"s1\" and id < 100")
+	d := matcher.NewDataset(datasetRecords(100000))
+	d.Index("id", "status")
+
+	for i := 0; i < b.N; i++ {
+		d.Filter(m)
+	}
+}
+
+func TestDatasetFilterLike(t *testing.T) {
+	words := []string{"hello", "yellow", "jello", "mellow", "help", "fellow", "hall", "café hello", "100%", "a_b"}
+	var records []matcher.Context
+	for i := 0; i < 200; i++ {
+		r := matcher.Context{"id": float64(i), "name": words[i%len(words)]}
+		switch i % 50 {
+		case 7:
+			r["name"] = 42.0
+		case 8:
+			r["name"] = nil
+		case 9:
+			delete(r, "name")
+		}
+		records = append(records, r)
+	}
+	scan := matcher.NewDataset(records)
+	indexed := matcher.NewDataset(records)
+	indexed.Index("name")
+
+	for _, q := range []string{
+		`name LIKE "%ello%"`,
+		`name LIKE "%llow"`,
+		`name LIKE "hel%"`,
+		`name LIKE "_ello"`,
+		`name LIKE "%ell_w"`,
+		`name LIKE "café%"`,
+		`name LIKE "100\\%"`,
+		`name LIKE "a\\_b"`,
+		`name LIKE "%xyz%"`,
+		`name LIKE "h%"`, // no trigram, full scan
+		`name NOT LIKE "%ello%"`,
+		`name LIKE "%ello%" and id < 50 or name LIKE "%all%"`,
+	} {
+		t.Run(q, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+			want, err := scan.Filter(m)
+			assert.NoError(err)
+			got, err := indexed.Filter(m)
+			assert.NoError(err)
+			assert.Equal(want, got)
+		})
+	}
+
+	// Only the records containing the trigrams of the pattern, and those
+	// that are not strings, are evaluated.
+	evals := 0
+	count := func([]*matcher.Arg) (matcher.Func, error) {
+		return func(matcher.Context) (interface{}, error) {
+			evals++
+			return true, nil
+		}, nil
+	}
+	m, err := matcher.NewMatcher(`SEEN() and name LIKE "%ellow"`, matcher.WithFunction("SEEN", count), matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	got, err := indexed.Filter(m)
+	assert.NoError(t, err)
+	assert.Len(t, got, 60)
+	assert.Equal(t, 60+8, evals)
+}
+
+func TestDatasetFilterPaths(t *testing.T) {
+	records := []matcher.Context{
+		{"address": map[string]interface{}{"city": "Tokyo", "zip": 100.0}},
+		{"address": map[string]interface{}{"city": "Osaka", "zip": 530.0}},
+		{"address": json.RawMessage(`{"city": "Tokyo", "zip": 150}`)},
+		{"address.city": "Tokyo"},
+		{"address": "Tokyo"},
+	}
+	scan := matcher.NewDataset(records)
+	indexed := matcher.NewDataset(records)
+	indexed.Index("address.city", "address.zip")
+
+	for _, q := range []string{
+		`address.city = "Tokyo"`,
+		`address.city LIKE "%kyo"`,
+		`address.zip >= 150`,
+		`address.zip = 100 or address.city = "Osaka"`,
+	} {
+		t.Run(q, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+			want, err := scan.Filter(m)
+			assert.NoError(err)
+			assert.NotEmpty(want)
+			got, err := indexed.Filter(m)
+			assert.NoError(err)
+			assert.Equal(want, got)
+		})
+	}
+}
+
+func TestDatasetFilterRawMessage(t *testing.T) {
+	records := []matcher.Context{
+		{"a": json.RawMessage(`"x"`)},
+		{"a": "x"},
+		{"a": json.RawMessage(`5`)},
+		{"a": "y"},
+	}
+	scan := matcher.NewDataset(records)
+	indexed := matcher.NewDataset(records)
+	indexed.Index("a")
+	for _, q := range []string{`a = "x"`, `a = 5`, `a LIKE "x%"`, `a >= "x"`} {
+		m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		want, wantErr := scan.Filter(m)
+		got, err := indexed.Filter(m)
+		assert.Equal(t, wantErr, err, q)
+		assert.Equal(t, want, got, q)
+	}
+}
+
+func TestDatasetFilterMismatchErrors(t *testing.T) {
+	records := []matcher.Context{{"a": "x"}, {"a": 5.0}, {"a": "5"}, {"a": true}}
+	scan := matcher.NewDataset(records)
+	indexed := matcher.NewDataset(records)
+	indexed.Index("a")
+	for _, opt := range []matcher.Option{matcher.WithMismatchPolicy(matcher.MismatchError), matcher.WithStrictTypes()} {
+		for _, q := range []string{`a = "x"`, `a = 5`, `a > 1`} {
+			m, err := matcher.NewMatcher(q, opt)
+			assert.NoError(t, err)
+			want, wantErr := scan.Filter(m)
+			got, err := indexed.Filter(m)
+			assert.Equal(t, want, got, q)
+			assert.Equal(t, wantErr, err, q)
+		}
+	}
+
+	m, err := matcher.NewMatcher(`a = "x"`, matcher.WithStrictTypes())
+	assert.NoError(t, err)
+	_, err = indexed.Filter(m)
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+}
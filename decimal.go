@@ -0,0 +1,82 @@
+package matcher
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// WithExactDecimals compares numeric strings and json.Number values with
+// number literals as exact decimals instead of converting them to float64,
+// so a monetary amount "10.10" equals 10.1 and "0.1000000000000000001" does
+// not. Decode JSON with json.Decoder.UseNumber to keep its numbers exact.
+//
+// *big.Rat context values are always compared exactly with number literals,
+// with or without this option. A literal stands for the decimal number it is
+// written as, not for its nearest float64.
+func WithExactDecimals() Option {
+	return func(o *options) {
+		o.exact = true
+	}
+}
+
+// compileDecimal wraps the comparison with a number literal so that it
+// compares *big.Rat values, and with exact also numeric strings and
+// json.Number values, as exact decimals.
+func (x *Condition) compileDecimal(f float64, exact bool) {
+	next := x.test
+	if next == nil {
+		next = x.compare
+	}
+	op := x.Compare.Operator
+	var lit *big.Rat
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		lit = parseDecimal(x.Compare.Value.source())
+		if lit == nil {
+			lit, _ = new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	}
+	x.test = func(ctxVal interface{}) (bool, error) {
+		var r *big.Rat
+		switch v := ctxVal.(type) {
+		case *big.Rat:
+			r = v
+		case string:
+			if exact {
+				r = parseDecimal(v)
+			}
+		case json.Number:
+			if exact {
+				r = parseDecimal(string(v))
+			}
+		}
+		if r == nil {
+			return next(ctxVal)
+		}
+		switch {
+		case lit != nil:
+			return holds(op, r.Cmp(lit)), nil
+		case math.IsInf(f, 1):
+			return holds(op, -1), nil
+		case math.IsInf(f, -1):
+			return holds(op, 1), nil
+		}
+		return next(math.NaN())
+	}
+}
+
+// parseDecimal parses a decimal number, or returns nil if s is not one.
+func parseDecimal(s string) *big.Rat {
+	if _, ok := parseNumber(s); !ok {
+		return nil
+	}
+	r, _ := new(big.Rat).SetString(s)
+	return r
+}
+
+// exactDecimal reports whether the condition compares numbers as exact
+// decimals, which float64 based indexes can not answer.
+func (x *Condition) exactDecimal() bool {
+	return x.opts != nil && x.opts.exact && x.Compare != nil && x.Compare.Value != nil && x.Compare.Value.Float != nil
+}
@@ -0,0 +1,72 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimalRat(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		value string
+		want  bool
+	}{
+		{`amount = 10.10`, "10.1", true},
+		{`amount = 0.3`, "3/10", true},
+		{`amount = 0.3`, "0.30000000000000004", false},
+		{`amount < 0.1`, "0.09999999999999999999", true},
+		{`amount >= 100`, "100", true},
+		{`amount <> 1`, "1.0000000000000000001", true},
+		{`amount < Inf`, "1e400", true},
+	} {
+		r, ok := new(big.Rat).SetString(tc.value)
+		assert.True(t, ok)
+		m, err := matcher.NewMatcher(tc.query, matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		c := matcher.Context{"amount": r}
+		got, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "%s with %s", tc.query, tc.value)
+	}
+}
+
+func TestExactDecimals(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`amount = 0.1`)
+	assert.NoError(err)
+	c := matcher.Context{"amount": "0.1000000000000000001"}
+	ok, err := m.Test(&c)
+	assert.NoError(err)
+	assert.True(ok, "compared as float64 by default")
+
+	m, err = matcher.NewMatcher(`amount = 0.1`, matcher.WithExactDecimals())
+	assert.NoError(err)
+	ok, err = m.Test(&c)
+	assert.NoError(err)
+	assert.False(ok)
+
+	for _, v := range []interface{}{"0.10", json.Number("0.1"), 0.1} {
+		c = matcher.Context{"amount": v}
+		ok, err = m.Test(&c)
+		assert.NoError(err)
+		assert.True(ok, "%#v", v)
+	}
+	c = matcher.Context{"amount": "abc"}
+	ok, err = m.Test(&c)
+	assert.NoError(err)
+	assert.False(ok)
+}
+
+func TestExactDecimalsDataset(t *testing.T) {
+	d := matcher.NewDataset([]matcher.Context{{"amount": "0.09999999999999999999"}, {"amount": "0.2"}})
+	d.Index("amount")
+	m, err := matcher.NewMatcher(`amount < 0.1`, matcher.WithExactDecimals())
+	assert.NoError(t, err)
+	got, err := d.Filter(m)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+}
@@ -0,0 +1,37 @@
+package matcher
+
+// DerivedFunc computes the value of a derived field from a context. A nil
+// result is null.
+type DerivedFunc func(c Context) interface{}
+
+// derived is a field defined with WithDerivedField.
+type derived struct {
+	fn     Func
+	inputs []string
+}
+
+// WithDerivedField defines a field computed by fn, so queries can use a
+// convenient name for a value combined from several raw fields:
+//
+//	matcher.WithDerivedField("full_name", func(c matcher.Context) interface{} {
+//		return fmt.Sprint(c["first_name"], " ", c["last_name"])
+//	}, "first_name", "last_name")
+//
+// The field is computed lazily, each time a condition on it is evaluated, and
+// only for contexts that do not have a key of that name. inputs lists the
+// keys fn reads: TestReader and TestResolver only provide the keys a query
+// refers to, including these. Derived fields can not be used as function
+// arguments.
+func WithDerivedField(name string, fn DerivedFunc, inputs ...string) Option {
+	return func(o *options) {
+		if o.derived == nil {
+			o.derived = make(map[string]derived)
+		}
+		o.derived[name] = derived{
+			fn: func(c Context) (interface{}, error) {
+				return fn(c), nil
+			},
+			inputs: inputs,
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package matcher_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func fullName(calls *int) matcher.Option {
+	return matcher.WithDerivedField("full_name", func(c matcher.Context) interface{} {
+		*calls++
+		return fmt.Sprint(c["first_name"], " ", c["last_name"])
+	}, "first_name", "last_name")
+}
+
+func TestDerivedField(t *testing.T) {
+	assert := assert.New(t)
+	calls := 0
+	m, err := matcher.NewMatcher(`active = TRUE and full_name = "Ada Lovelace"`, fullName(&calls))
+	assert.NoError(err)
+
+	c := matcher.Context{"active": true, "first_name": "Ada", "last_name": "Lovelace"}
+	ok, err := m.Test(&c)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(1, calls)
+	assert.NotContains(c, "full_name")
+
+	c["active"] = false
+	ok, err = m.Test(&c)
+	assert.NoError(err)
+	assert.False(ok)
+	assert.Equal(1, calls, "not computed when not reached")
+
+	c = matcher.Context{"active": true, "full_name": "Ada Lovelace"}
+	ok, err = m.Test(&c)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(1, calls, "context keys win")
+}
+
+func TestDerivedFieldReader(t *testing.T) {
+	calls := 0
+	m, err := matcher.NewMatcher(`full_name = "Ada Lovelace"`, fullName(&calls))
+	assert.NoError(t, err)
+	ok, err := m.TestReader(strings.NewReader(`{"first_name": "Ada", "x": [1, 2], "last_name": "Lovelace"}`))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = m.TestResolver(matcher.Layer(matcher.Context{"first_name": "Ada"}, matcher.Context{"last_name": "Lovelace"}))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestDerivedFieldNull(t *testing.T) {
+	m, err := matcher.NewMatcher(`domain = NULL`, matcher.WithDerivedField("domain", func(c matcher.Context) interface{} {
+		if email, ok := c["email"].(string); ok {
+			return email[strings.IndexByte(email, '@')+1:]
+		}
+		return nil
+	}, "email"))
+	assert.NoError(t, err)
+	c := matcher.Context{}
+	ok, err := m.Test(&c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	c = matcher.Context{"email": "ada@example.com"}
+	ok, err = m.Test(&c)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,155 @@
+// Package dockerevents applies matcher queries to the event stream of the
+// Docker Engine API, the same events `docker events` prints.
+//
+// An event is tested against a context with the fields type, action, id,
+// scope and time, and the actor attributes, which include the container
+// labels and name and image. Characters of attribute names that can not
+// appear in a query identifier are replaced by underscores, so the label
+// com.docker.compose.service is queried as com_docker_compose_service. The
+// fields win over attributes with the same name.
+package dockerevents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kuwa72/matcher"
+)
+
+// DefaultHost is the Docker daemon address used when neither Client.Host nor
+// DOCKER_HOST is set.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Event is a message of the Docker events API.
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Scope    string `json:"scope"`
+	Time     int64  `json:"time"`
+	TimeNano int64  `json:"timeNano"`
+
+	// Raw is the event as received.
+	Raw json.RawMessage `json:"-"`
+}
+
+// Context returns the context queries are tested against.
+func (e *Event) Context() matcher.Context {
+	c := make(matcher.Context, len(e.Actor.Attributes)+5)
+	for k, v := range e.Actor.Attributes {
+		c[key(k)] = v
+	}
+	c["type"] = e.Type
+	c["action"] = e.Action
+	c["id"] = e.Actor.ID
+	c["scope"] = e.Scope
+	c["time"] = float64(e.Time)
+	return c
+}
+
+func key(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Client reads events from a Docker daemon.
+type Client struct {
+	// Host is the daemon address: unix:///path/to/socket, tcp://host:port
+	// or an http(s) URL. DOCKER_HOST or DefaultHost is used when empty.
+	Host string
+}
+
+// Watch streams events until ctx is done or the connection fails, and calls
+// fn for every event matching m. Watch returns the first error of fn or of
+// testing an event.
+func (c *Client) Watch(ctx context.Context, m *matcher.Matcher, fn func(*Event) error) error {
+	client, base, err := c.httpClient()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/events", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /events: %s", resp.Status)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		ev := &Event{Raw: append(json.RawMessage(nil), line...)}
+		if err := json.Unmarshal(line, ev); err != nil {
+			return err
+		}
+		c := ev.Context()
+		ok, err := m.Test(&c)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return sc.Err()
+}
+
+// httpClient returns a client talking to the daemon and the base URL of the
+// API.
+func (c *Client) httpClient() (*http.Client, string, error) {
+	host := c.Host
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, "", err
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		return &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}}, "http://docker", nil
+	case "tcp":
+		return &http.Client{}, "http://" + u.Host, nil
+	case "http", "https":
+		return &http.Client{}, strings.TrimSuffix(host, "/"), nil
+	}
+	return nil, "", fmt.Errorf("unsupported Docker host %s", host)
+}
@@ -0,0 +1,83 @@
+package dockerevents_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/dockerevents"
+	"github.com/stretchr/testify/assert"
+)
+
+const events = `{"Type":"container","Action":"start","Actor":{"ID":"c1","Attributes":{"name":"web-1","image":"nginx","com.docker.compose.service":"web"}},"scope":"local","time":1714550400}
+{"Type":"container","Action":"die","Actor":{"ID":"c1","Attributes":{"name":"web-1","image":"nginx","exitCode":"137","com.docker.compose.service":"web"}},"scope":"local","time":1714550460}
+{"Type":"network","Action":"connect","Actor":{"ID":"n1","Attributes":{"name":"bridge","container":"c1"}},"scope":"local","time":1714550400}
+`
+
+func eventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, events)
+	})
+}
+
+func watch(t *testing.T, c *dockerevents.Client, q string) []string {
+	m, err := matcher.NewMatcher(q)
+	assert.NoError(t, err)
+	var got []string
+	err = c.Watch(context.Background(), m, func(ev *dockerevents.Event) error {
+		got = append(got, ev.Actor.ID+" "+ev.Action)
+		return nil
+	})
+	assert.NoError(t, err)
+	return got
+}
+
+func TestWatch(t *testing.T) {
+	srv := httptest.NewServer(eventsHandler())
+	defer srv.Close()
+	c := &dockerevents.Client{Host: "tcp://" + strings.TrimPrefix(srv.URL, "http://")}
+
+	assert.Equal(t, []string{"c1 die"}, watch(t, c, `type = "container" and action = "die" and exitCode = 137`))
+	assert.Equal(t, []string{"c1 start", "c1 die"}, watch(t, c, `com_docker_compose_service = "web"`))
+	assert.Equal(t, []string{"n1 connect"}, watch(t, c, `name = "bridge"`))
+}
+
+func TestWatchUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skip("unix sockets are not available:", err)
+	}
+	srv := &httptest.Server{Listener: l, Config: &http.Server{Handler: eventsHandler()}}
+	srv.Start()
+	defer srv.Close()
+
+	c := &dockerevents.Client{Host: "unix://" + sock}
+	assert.Equal(t, []string{"c1 start"}, watch(t, c, `action = "start"`))
+}
+
+func TestWatchStopsOnError(t *testing.T) {
+	srv := httptest.NewServer(eventsHandler())
+	defer srv.Close()
+	c := &dockerevents.Client{Host: srv.URL}
+
+	m, err := matcher.NewMatcher(`type = "container"`)
+	assert.NoError(t, err)
+	calls := 0
+	err = c.Watch(context.Background(), m, func(*dockerevents.Event) error {
+		calls++
+		return fmt.Errorf("stop")
+	})
+	assert.EqualError(t, err, "stop")
+	assert.Equal(t, 1, calls)
+}
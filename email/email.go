@@ -0,0 +1,127 @@
+// Package email builds matcher contexts from email messages, so queries can
+// drive mail routing and quarantine decisions, like Sieve scripts.
+//
+// The context of a message has
+//
+//   - every header as header_<name>, lower case with dashes replaced by
+//     underscores and RFC 2047 encoded words decoded, e.g. header_x_spam_flag;
+//     repeated headers are joined with ", ",
+//   - subject, the decoded Subject,
+//   - from, the address of the first From mailbox, and from_domain, its
+//     domain in lower case,
+//   - to and cc, the addresses of all recipients joined with ", ",
+//   - content_type, the media type of the message,
+//   - size, the size of the whole message in bytes,
+//   - has_attachment and attachment_count, counting the MIME parts that are
+//     attachments or carry a file name.
+package email
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+
+	"github.com/kuwa72/matcher"
+)
+
+// maxDepth limits the nesting of multipart bodies that is searched for
+// attachments.
+const maxDepth = 10
+
+// Read reads a message from r and returns its context. The whole message is
+// read.
+func Read(r io.Reader) (matcher.Context, error) {
+	cr := &countingReader{r: r}
+	msg, err := mail.ReadMessage(bufio.NewReader(cr))
+	if err != nil {
+		return nil, err
+	}
+
+	c := matcher.Context{}
+	dec := new(mime.WordDecoder)
+	for name, values := range msg.Header {
+		decoded := make([]string, len(values))
+		for i, v := range values {
+			if d, err := dec.DecodeHeader(v); err == nil {
+				v = d
+			}
+			decoded[i] = v
+		}
+		c["header_"+strings.ToLower(strings.ReplaceAll(name, "-", "_"))] = strings.Join(decoded, ", ")
+	}
+	if s, ok := c["header_subject"]; ok {
+		c["subject"] = s
+	}
+	if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+		c["from"] = from[0].Address
+		if i := strings.LastIndexByte(from[0].Address, '@'); i >= 0 {
+			c["from_domain"] = strings.ToLower(from[0].Address[i+1:])
+		}
+	}
+	for _, h := range []string{"To", "Cc"} {
+		if list, err := msg.Header.AddressList(h); err == nil {
+			addrs := make([]string, len(list))
+			for i, a := range list {
+				addrs[i] = a.Address
+			}
+			c[strings.ToLower(h)] = strings.Join(addrs, ", ")
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	c["content_type"] = mediaType
+	n := 0
+	if strings.HasPrefix(mediaType, "multipart/") {
+		n = attachments(msg.Body, params["boundary"], 0)
+	}
+	c["has_attachment"] = n > 0
+	c["attachment_count"] = float64(n)
+
+	if _, err := io.Copy(io.Discard, msg.Body); err != nil {
+		return nil, err
+	}
+	c["size"] = float64(cr.n)
+	return c, nil
+}
+
+// attachments counts the attachments of a multipart body. Malformed parts
+// end the search.
+func attachments(r io.Reader, boundary string, depth int) int {
+	if boundary == "" || depth > maxDepth {
+		return 0
+	}
+	n := 0
+	mr := multipart.NewReader(r, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			return n
+		}
+		disposition, dparams, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+		mediaType, params, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		switch {
+		case strings.HasPrefix(mediaType, "multipart/"):
+			n += attachments(p, params["boundary"], depth+1)
+		case disposition == "attachment", dparams["filename"] != "", params["name"] != "":
+			n++
+		}
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,84 @@
+package email_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/email"
+	"github.com/stretchr/testify/assert"
+)
+
+const plain = "From: Alice <alice@Example.COM>\r\n" +
+	"To: bob@example.org, Carol <carol@example.net>\r\n" +
+	"Subject: =?UTF-8?B?44GT44KT44Gr44Gh44Gv?=\r\n" +
+	"X-Spam-Flag: YES\r\n" +
+	"\r\n" +
+	"Hello\r\n"
+
+const withAttachment = "From: billing@shop.example\r\n" +
+	"To: bob@example.org\r\n" +
+	"Subject: Invoice\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"See attached.\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=invoice.pdf\r\n" +
+	"Content-Disposition: attachment; filename=invoice.pdf\r\n" +
+	"\r\n" +
+	"JVBERi0=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Disposition: inline; filename=logo.png\r\n" +
+	"\r\n" +
+	"iVBO\r\n" +
+	"--outer--\r\n"
+
+func TestRead(t *testing.T) {
+	assert := assert.New(t)
+	c, err := email.Read(strings.NewReader(plain))
+	assert.NoError(err)
+	assert.Equal("こんにちは", c["subject"])
+	assert.Equal("alice@Example.COM", c["from"])
+	assert.Equal("example.com", c["from_domain"])
+	assert.Equal("bob@example.org, carol@example.net", c["to"])
+	assert.Equal("YES", c["header_x_spam_flag"])
+	assert.Equal("text/plain", c["content_type"])
+	assert.Equal(false, c["has_attachment"])
+	assert.Equal(float64(len(plain)), c["size"])
+	_, ok := c["cc"]
+	assert.False(ok)
+
+	c, err = email.Read(strings.NewReader(withAttachment))
+	assert.NoError(err)
+	assert.Equal("multipart/mixed", c["content_type"])
+	assert.Equal(true, c["has_attachment"])
+	assert.Equal(2.0, c["attachment_count"])
+	assert.Equal(float64(len(withAttachment)), c["size"])
+}
+
+func TestQuarantineRule(t *testing.T) {
+	m, err := matcher.NewMatcher(`header_x_spam_flag = "YES" or has_attachment = TRUE and from_domain <> "example.org"`)
+	assert.NoError(t, err)
+
+	for _, msg := range []string{plain, withAttachment} {
+		c, err := email.Read(strings.NewReader(msg))
+		assert.NoError(t, err)
+		ok, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	_, err := email.Read(strings.NewReader("not a message"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package matcher
+
+import (
+	"os"
+	"strings"
+)
+
+// EnvironOption configures ContextFromEnviron.
+type EnvironOption func(*environ)
+
+// environ holds the settings applied by EnvironOption values.
+type environ struct {
+	prefix string
+	lower  bool
+}
+
+// WithEnvPrefix keeps only the variables whose names start with prefix and
+// removes it from their names: with prefix "APP_", APP_REGION is region.
+func WithEnvPrefix(prefix string) EnvironOption {
+	return func(e *environ) { e.prefix = prefix }
+}
+
+// WithEnvLowerCase maps variable names to lower case.
+func WithEnvLowerCase() EnvironOption {
+	return func(e *environ) { e.lower = true }
+}
+
+// ContextFromEnviron maps the environment of the process to a context of
+// strings, so deployment scripts can test it with queries like
+// DEPLOY_ENV = "prod" AND REPLICAS >= 3. Characters of variable names that
+// can not appear in a query identifier are replaced by underscores.
+func ContextFromEnviron(opts ...EnvironOption) Context {
+	var e environ
+	for _, opt := range opts {
+		opt(&e)
+	}
+	c := Context{}
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" || !strings.HasPrefix(name, e.prefix) {
+			continue
+		}
+		name = strings.TrimPrefix(name, e.prefix)
+		if e.lower {
+			name = strings.ToLower(name)
+		}
+		if name != "" {
+			c[identifier(name)] = value
+		}
+	}
+	return c
+}
@@ -0,0 +1,28 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextFromEnviron(t *testing.T) {
+	t.Setenv("DEPLOY_ENV", "prod")
+	t.Setenv("APP_REGION", "eu-west-1")
+	t.Setenv("APP_REPLICAS", "3")
+	t.Setenv("APP_", "ignored")
+
+	c := matcher.ContextFromEnviron()
+	assert.Equal(t, "prod", c["DEPLOY_ENV"])
+	assert.Equal(t, "eu-west-1", c["APP_REGION"])
+
+	c = matcher.ContextFromEnviron(matcher.WithEnvPrefix("APP_"), matcher.WithEnvLowerCase())
+	assert.Equal(t, matcher.Context{"region": "eu-west-1", "replicas": "3"}, c)
+
+	m, err := matcher.NewMatcher(`region = "eu-west-1" and replicas >= 3`)
+	assert.NoError(t, err)
+	ok, err := m.Test(&c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,66 @@
+package matcher
+
+import (
+	"math"
+	"strconv"
+)
+
+// WithFloatEpsilon makes number comparisons tolerate rounding errors: a
+// number within eps of the literal equals it, so `ratio = 0.3` matches
+// 0.30000000000000004, and the ordering operators agree with that equality.
+// eps is an absolute difference; zero, the default, compares exactly.
+func WithFloatEpsilon(eps float64) Option {
+	return func(o *options) {
+		o.epsilon = math.Abs(eps)
+	}
+}
+
+// compileEpsilon specializes the comparison of a number literal for
+// WithFloatEpsilon. Values that are not numbers fall back to compare.
+func (x *Condition) compileEpsilon(f, eps float64) {
+	x.Compare.Value.text = strconv.FormatFloat(f, 'f', -1, 64)
+	op := x.Compare.Operator
+	x.test = func(ctxVal interface{}) (bool, error) {
+		var n float64
+		switch v := ctxVal.(type) {
+		case float64:
+			n = v
+		case string:
+			p, ok := parseNumber(v)
+			if !ok {
+				return x.compare(ctxVal)
+			}
+			n = p
+		case bool:
+			return x.compare(ctxVal)
+		default:
+			p, ok := toFloat(v)
+			if !ok {
+				return x.compare(ctxVal)
+			}
+			n = p
+		}
+		if math.IsNaN(n) || math.IsNaN(f) {
+			return op == "<>" || op == "!=", nil
+		}
+		return holds(op, approxCompare(n, f, eps)), nil
+	}
+}
+
+// approxCompare compares a and b, treating numbers at most eps apart as
+// equal.
+func approxCompare(a, b, eps float64) int {
+	switch {
+	case a == b || math.Abs(a-b) <= eps:
+		return 0
+	case a < b:
+		return -1
+	}
+	return 1
+}
+
+// approximate reports whether the condition compares numbers with an
+// epsilon, which the exact lookups of a dataset index can not serve.
+func (x *Condition) approximate() bool {
+	return x.opts != nil && x.opts.epsilon > 0 && x.Compare != nil && x.Compare.Value != nil && x.Compare.Value.Float != nil
+}
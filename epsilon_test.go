@@ -0,0 +1,61 @@
+package matcher_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloatEpsilon(t *testing.T) {
+	a, b := 0.1, 0.2
+	sum := a + b // 0.30000000000000004
+	cases := []struct {
+		query string
+		value interface{}
+		match bool
+	}{
+		{"a = 0.3", sum, true},
+		{"a != 0.3", sum, false},
+		{"a > 0.3", sum, false},
+		{"a >= 0.3", sum, true},
+		{"a < 0.3", sum, false},
+		{"a <= 0.3", sum, true},
+		{"a = 0.3", 0.31, false},
+		{"a > 0.3", 0.31, true},
+		{"a = 0.5", float32(0.5), true},
+		{"a = 0.3", "0.30000000001", true},
+		{"a = 1", 1, true},
+		{"a = Inf", math.Inf(1), true},
+		{"a > Inf", math.Inf(1), false},
+		{"a = 0.3", math.NaN(), false},
+		{"a != 0.3", math.NaN(), true},
+	}
+
+	for _, c := range cases {
+		m, err := matcher.NewMatcher(c.query, matcher.WithFloatEpsilon(1e-9), matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		ok, err := m.Test(&matcher.Context{"a": c.value})
+		assert.NoError(t, err)
+		assert.Equal(t, c.match, ok, "%s with %v", c.query, c.value)
+	}
+
+	m, err := matcher.NewMatcher("a = 0.3")
+	assert.NoError(t, err)
+	ok, err := m.Test(&matcher.Context{"a": sum})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestFloatEpsilonDataset(t *testing.T) {
+	a, b := 0.1, 0.2
+	d := matcher.NewDataset([]matcher.Context{{"a": a + b}, {"a": 0.4}})
+	d.Index("a")
+
+	m, err := matcher.NewMatcher("a = 0.3", matcher.WithFloatEpsilon(1e-9))
+	assert.NoError(t, err)
+	got, err := d.Filter(m)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+}
@@ -0,0 +1,88 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+)
+
+// ErrInternal matches every *InternalError with errors.Is.
+var ErrInternal = errors.New("matcher: internal error")
+
+// InternalError reports a panic recovered while evaluating a condition,
+// which is a bug in the matcher or in a value it was given.
+type InternalError struct {
+	// Predicate is the failing condition in query syntax.
+	Predicate string
+	// Panic is the recovered value.
+	Panic interface{}
+}
+
+func (e *InternalError) Error() string {
+	return fmt.Sprintf("matcher: internal error evaluating %s: %v", e.Predicate, e.Panic)
+}
+
+// Is reports whether target is ErrInternal.
+func (e *InternalError) Is(target error) bool {
+	return target == ErrInternal
+}
+
+// ParseError is returned by NewMatcher for a query that does not parse. Its
+// message shows the offending query line with a caret under the error
+// position.
+type ParseError struct {
+	// Query is the query that failed to parse.
+	Query string
+	// Line and Column locate the error, both starting at 1.
+	Line, Column int
+	// Message describes the error, including the expected tokens when
+	// they are known.
+	Message string
+
+	err error
+}
+
+// conditionGrammar is how the parser describes the part of a condition after
+// the optional NOT, which is a condition to the user.
+const conditionGrammar = `(("(" Expression ")") | ((Call | ((<ident> | <nonfinite>) ("." (<ident> | <nonfinite>))*)) Compare?))`
+
+// operandGrammar is how the parser describes the right-hand side of a
+// comparison, which is a value to the user even when it names a field.
+const operandGrammar = `(Value | ((<ident> | <nonfinite>) ("." (<ident> | <nonfinite>))*))`
+
+func newParseError(q string, err participle.Error) *ParseError {
+	pos := err.Position()
+	msg := strings.Replace(err.Message(), conditionGrammar, "Condition", 1)
+	msg = strings.Replace(msg, operandGrammar, "Value", 1)
+	return &ParseError{Query: q, Line: pos.Line, Column: pos.Column, Message: msg, err: err}
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d:%d: %s", e.Line, e.Column, e.Message)
+
+	lines := strings.Split(e.Query, "\n")
+	if e.Line < 1 || e.Line > len(lines) {
+		return b.String()
+	}
+	line := []rune(lines[e.Line-1])
+	b.WriteString("\n  ")
+	b.WriteString(string(line))
+	b.WriteString("\n  ")
+	for i := 0; i < e.Column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte('^')
+	return b.String()
+}
+
+// Unwrap returns the underlying parser error.
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
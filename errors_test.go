@@ -0,0 +1,52 @@
+package matcher_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPanicIsRecovered(t *testing.T) {
+	assert := assert.New(t)
+
+	// A condition built by hand without a value can not be evaluated.
+	e := &matcher.Expression{Or: []*matcher.OrCondition{{And: []*matcher.Condition{
+		{Symbol: "b", Compare: &matcher.Compare{Operator: ">"}},
+	}}}}
+	ok, err := e.Eval(matcher.Context{"b": 2.0})
+	assert.False(ok)
+	assert.ErrorIs(err, matcher.ErrInternal)
+
+	var ie *matcher.InternalError
+	assert.True(errors.As(err, &ie))
+	assert.Equal("b > ?", ie.Predicate)
+	assert.NotNil(ie.Panic)
+}
+
+func TestParseError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := matcher.NewMatcher("a = 1 and = 2")
+
+	var pe *matcher.ParseError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(1, pe.Line)
+	assert.Equal(11, pe.Column)
+	assert.Contains(pe.Message, "expected Condition")
+	assert.Equal("1:11: unexpected token \"=\" (expected Condition)\n"+
+		"  a = 1 and = 2\n"+
+		"            ^", err.Error())
+}
+
+func TestParseErrorMultiline(t *testing.T) {
+	assert := assert.New(t)
+	_, err := matcher.NewMatcher("a = 1 and\n\tb > ")
+
+	var pe *matcher.ParseError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(2, pe.Line)
+	assert.Equal("2:6: unexpected token \"<EOF>\" (expected Value)\n"+
+		"  \tb > \n"+
+		"  \t    ^", err.Error())
+}
@@ -0,0 +1,271 @@
+package matcher
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ExampleContexts synthesizes sample payloads for a query: a minimal context
+// matching e, and a near miss that only differs from it in one field and
+// does not match. Rule authors can use them to test their rules end to end.
+//
+// The first OR branch that can be satisfied is used. Branches calling
+// functions other than EXISTS, MISSING, ANY and ALL are skipped, as their
+// outcome depends on more than the context, and they are also not
+// considered when checking that the near miss does not match. Branches with
+// parenthesized groups or comparing two fields are also skipped. nearMiss
+// is nil if no change of a single field makes the query miss. Values on
+// which any condition of the query fails to evaluate, such as type
+// mismatches, are never used.
+func ExampleContexts(e *Expression) (match, nearMiss Context, err error) {
+	for _, x := range e.Or {
+		if !pureBranch(x) {
+			continue
+		}
+		match, ok := x.example(e)
+		if !ok {
+			continue
+		}
+		for _, c := range x.And {
+			if nearMiss, ok = e.nearMiss(match, x, c); ok {
+				return match, nearMiss, nil
+			}
+		}
+		return match, nil, nil
+	}
+	return nil, nil, errors.New("matcher: no example satisfies the query")
+}
+
+// pureBranch reports whether the outcome of a branch only depends on the
+// context.
+func pureBranch(x *OrCondition) bool {
+	for _, c := range x.And {
+		if c.Call != nil && !c.quantified && (c.Compare != nil || !isExistence(c.Call)) ||
+			c.Group != nil && !c.Group.pure() {
+			return false
+		}
+	}
+	return true
+}
+
+func isExistence(c *Call) bool {
+	name := strings.ToUpper(c.Name)
+	return (name == "EXISTS" || name == "MISSING") && len(c.Args) == 1 && c.Args[0].Symbol != ""
+}
+
+// holdsIn reports whether a condition of a pure branch holds for ctx.
+func (x *Condition) holdsIn(ctx Context) bool {
+	if x.Call != nil && !x.quantified {
+		_, ok := lookup(ctx, x.Call.Args[0].Symbol)
+		return ok == (strings.ToUpper(x.Call.Name) == "EXISTS") != x.Not
+	}
+	ok, err := x.Eval(ctx)
+	return ok && err == nil
+}
+
+// example returns a context satisfying every condition of the branch of e,
+// which must not have groups.
+func (x *OrCondition) example(e *Expression) (Context, bool) {
+	var fields []string
+	conds := map[string][]*Condition{}
+	missing := map[string]bool{}
+	for _, c := range x.And {
+		if c.Group != nil || c.Compare != nil && c.Compare.Field != "" {
+			return nil, false
+		}
+		field := c.Symbol
+		if c.Call != nil {
+			field = c.Call.Args[0].Symbol
+			if !c.quantified && (strings.ToUpper(c.Call.Name) == "MISSING") != c.Not {
+				missing[field] = true
+			}
+		}
+		if _, seen := conds[field]; !seen {
+			fields = append(fields, field)
+		}
+		conds[field] = append(conds[field], c)
+	}
+
+	ctx := Context{}
+	for _, f := range fields {
+		if missing[f] {
+			continue
+		}
+		v, ok := satisfy(e, f, conds[f], nil)
+		if !ok {
+			return nil, false
+		}
+		ctx[f] = v
+	}
+	for _, c := range x.And {
+		if !c.holdsIn(ctx) {
+			return nil, false
+		}
+	}
+	return ctx, true
+}
+
+// satisfy returns the first value of field for which every condition holds
+// and, if violate is not nil, violate does not.
+func satisfy(e *Expression, field string, conds []*Condition, violate *Condition) (interface{}, bool) {
+	values := satisfying(e, field, conds, violate)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// satisfying returns the values of field, among those near the literals of
+// the conditions, for which every condition holds and, if violate is not
+// nil, violate does not, and on which no condition of e fails.
+func satisfying(e *Expression, field string, conds []*Condition, violate *Condition) []interface{} {
+	var tested []*Condition
+	for _, c := range e.leaves() {
+		if (c.Call == nil || c.quantified) && c.field() == field {
+			tested = append(tested, c)
+		}
+	}
+	var candidates []interface{}
+	for _, c := range conds {
+		candidates = append(candidates, c.candidates()...)
+	}
+	if violate != nil {
+		candidates = append(candidates, violate.candidates()...)
+	}
+	candidates = append(candidates, "x", 0.0, true, nil)
+	var out []interface{}
+	for _, v := range candidates {
+		ctx := Context{field: v}
+		ok := true
+		for _, c := range conds {
+			if c != violate && !c.holdsIn(ctx) {
+				ok = false
+				break
+			}
+		}
+		for _, c := range tested {
+			if _, err := c.Eval(ctx); err != nil {
+				ok = false
+			}
+		}
+		if ok && (violate == nil || !violate.holdsIn(ctx)) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// candidates returns values near the literal of a comparison, in lists for
+// ANY and ALL.
+func (x *Condition) candidates() []interface{} {
+	if x.Compare == nil {
+		return nil
+	}
+	values := x.near()
+	if x.quantified {
+		for i, v := range values {
+			values[i] = []interface{}{v}
+		}
+		values = append(values, []interface{}{})
+	}
+	return values
+}
+
+// near returns values near the literal of a comparison.
+func (x *Condition) near() []interface{} {
+	v := x.Compare.Value
+	switch {
+	case v.Float != nil:
+		f := *v.Float
+		return []interface{}{f, f + 1, f - 1, f + 0.5, f - 0.5}
+	case v.String != nil && isLike(x.Compare.Operator):
+		return []interface{}{likeExample(*v.String), ""}
+	case v.String != nil:
+		s := *v.String
+		out := []interface{}{s, s + "a", ""}
+		if s != "" {
+			out = append(out, s[:len(s)-1])
+		}
+		return out
+	case v.Boolean != nil:
+		return []interface{}{bool(*v.Boolean), !bool(*v.Boolean)}
+	case v.Duration != nil:
+		d := time.Duration(*v.Duration)
+		return []interface{}{Duration(d).literal(), Duration(d + time.Second).literal(), Duration(d / 2).literal()}
+	case v.Time != nil:
+		t, _ := parseTime(string(*v.Time), time.UTC)
+		return []interface{}{t.Format(time.RFC3339Nano), t.Add(time.Second).Format(time.RFC3339Nano),
+			t.Add(-time.Second).Format(time.RFC3339Nano)}
+	}
+	return []interface{}{nil, "x"}
+}
+
+// nearMiss returns a copy of match, which satisfies the branch x, in which
+// the condition c of x does not hold while the other conditions of x on the
+// same field still do, and which does not match e.
+func (e *Expression) nearMiss(match Context, x *OrCondition, c *Condition) (Context, bool) {
+	field := c.field()
+	var values []interface{}
+	if c.Call != nil && !c.quantified {
+		if _, ok := match[field]; !ok {
+			values = []interface{}{"x"}
+		}
+	} else {
+		var same []*Condition
+		for _, o := range x.And {
+			if (o.Call == nil || o.quantified) && o.field() == field {
+				same = append(same, o)
+			}
+		}
+		values = satisfying(e, field, same, c)
+	}
+
+	miss := make(Context, len(match))
+	for k, v := range match {
+		miss[k] = v
+	}
+	// Removing the field is the last resort, and the only way to violate
+	// EXISTS.
+	for i := 0; i <= len(values); i++ {
+		if i < len(values) {
+			miss[field] = values[i]
+		} else if c.Call == nil || c.quantified || values == nil {
+			delete(miss, field)
+		} else {
+			break
+		}
+		if !e.matchesPure(miss) {
+			return miss, true
+		}
+	}
+	return nil, false
+}
+
+// field returns the field a condition of a pure branch tests.
+func (x *Condition) field() string {
+	if x.Call != nil {
+		return x.Call.Args[0].Symbol
+	}
+	return x.Symbol
+}
+
+// matchesPure reports whether a pure branch of e matches ctx.
+func (e *Expression) matchesPure(ctx Context) bool {
+	for _, x := range e.Or {
+		if !pureBranch(x) {
+			continue
+		}
+		matched := true
+		for _, o := range x.And {
+			if !o.holdsIn(ctx) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExampleContexts(t *testing.T) {
+	for _, q := range []string{
+		`a = 1`,
+		`a = 1 and b = "x" and c = TRUE`,
+		`age >= 18 and age < 65 and country <> "JP"`,
+		`name > "m" and name <= "n"`,
+		`status = NULL or status >= 500`,
+		`EXISTS(token) and MISSING(debug) and elapsed > 5m`,
+		`ROLLOUT(user, 10) or region = "eu"`,
+		`a = 1 and b = 2 or a = 1`,
+	} {
+		m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		match, miss, err := matcher.ExampleContexts(m.Expression)
+		assert.NoError(t, err, q)
+
+		ok, err := m.Test(&match)
+		assert.NoError(t, err)
+		assert.True(t, ok, "%s: %v", q, match)
+
+		if assert.NotNil(t, miss, q) {
+			ok, err = m.Test(&miss)
+			assert.NoError(t, err)
+			assert.False(t, ok, "%s: %v", q, miss)
+		}
+	}
+}
+
+func TestExampleContextsShape(t *testing.T) {
+	m, err := matcher.NewMatcher(`age >= 18 and country = "JP"`)
+	assert.NoError(t, err)
+	match, miss, err := matcher.ExampleContexts(m.Expression)
+	assert.NoError(t, err)
+	assert.Equal(t, matcher.Context{"age": float64(18), "country": "JP"}, match)
+	assert.Equal(t, matcher.Context{"age": float64(17), "country": "JP"}, miss)
+}
+
+func TestExampleContextsUnsatisfiable(t *testing.T) {
+	for _, q := range []string{`a > 1 and a < 1`, `MISSING(a) and a = 1`, `COUNT_OVER(1m) > 5`} {
+		m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		_, _, err = matcher.ExampleContexts(m.Expression)
+		assert.Error(t, err, q)
+	}
+}
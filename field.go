@@ -0,0 +1,55 @@
+package matcher
+
+import "time"
+
+// against returns a copy of a comparison of two fields that compares with
+// the value the right-hand field has in ctx as a literal, compiled with the
+// options of x. If there is no such literal, it returns nil and the result
+// of the comparison: false if ctx does not have the field, and a mismatch if
+// it holds a list or an object.
+func (x *Condition) against(ctx Context) (*Condition, bool, error) {
+	rhs, ok := lookup(ctx, x.Compare.Field)
+	if !ok {
+		return nil, false, nil
+	}
+	rhs, err := x.decodeRaw(rhs)
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := literalOf(rhs)
+	if !ok {
+		b, err := x.mismatch(rhs)
+		return nil, b, err
+	}
+	c := *x
+	c.Compare = &Compare{Operator: x.Compare.Operator, Value: v}
+	c.test = nil
+	if x.opts != nil {
+		c.compileLiteral(x.opts)
+	}
+	return &c, false, nil
+}
+
+// literalOf returns the literal of a context value, or false for lists,
+// objects and values of other types.
+func literalOf(v interface{}) (*Value, bool) {
+	switch v := v.(type) {
+	case nil:
+		return &Value{Null: true}, true
+	case string:
+		return &Value{String: &v}, true
+	case bool:
+		b := Boolean(v)
+		return &Value{Boolean: &b}, true
+	case time.Duration:
+		d := Duration(v)
+		return &Value{Duration: &d}, true
+	case time.Time:
+		t := Timestamp(v.Format(time.RFC3339Nano))
+		return &Value{Time: &t}, true
+	}
+	if f, ok := toFloat(v); ok {
+		return &Value{Float: &f}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,67 @@
+package matcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		query string
+		ctx   matcher.Context
+		want  bool
+	}{
+		{`updated_at > created_at`, matcher.Context{"updated_at": 2.0, "created_at": 1.0}, true},
+		{`updated_at > created_at`, matcher.Context{"updated_at": 1.0, "created_at": 2.0}, false},
+		{`updated_at > created_at`, matcher.Context{"updated_at": now.Add(time.Second), "created_at": now}, true},
+		{`updated_at > created_at`, matcher.Context{"updated_at": 2.0}, false},
+		{`a = b`, matcher.Context{"a": "x", "b": "x"}, true},
+		{`a != b`, matcher.Context{"a": "x", "b": "y"}, true},
+		{`a = b`, matcher.Context{"a": nil, "b": nil}, true},
+		{`a = b`, matcher.Context{"a": true, "b": true}, true},
+		{`a != b`, matcher.Context{"a": 1.0, "b": "x"}, true},
+		{`spent <= user.limit`, matcher.Context{"spent": 5, "user": map[string]interface{}{"limit": 10}}, true},
+		{`name LIKE pattern`, matcher.Context{"name": "John", "pattern": "J%"}, true},
+		{`ANY(scores) > threshold`, matcher.Context{"scores": []interface{}{1.0, 7.0}, "threshold": 5.0}, true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&tt.ctx)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %v", tt.query, tt.ctx)
+	}
+
+	m, err := matcher.NewMatcher(`a = b`, matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	_, err = m.Test(&matcher.Context{"a": 1.0, "b": []interface{}{1.0}})
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+}
+
+func TestFieldString(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`updated_at > created_at AND a.b = c.d`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`updated_at > created_at AND a.b = c.d`, m.Expression.String())
+
+	rows := m.Expression.Rows()
+	assert.Equal(matcher.Row{Group: 0, Field: "updated_at", Operator: ">", Type: "field", Value: "created_at"}, rows[0])
+	e, err := matcher.FromRows(rows)
+	assert.NoError(err)
+	assert.Equal(m.Expression.String(), e.String())
+
+	m, err = matcher.NewMatcher(`a = a`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Len(m.Warnings(), 1)
+
+	_, err = matcher.NewMatcher(`a > b`, matcher.WithSyntaxVersion(1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+	_, err = matcher.FromRows([]matcher.Row{{Field: "a", Operator: "=", Type: "field", Value: "1x"}})
+	assert.Error(err)
+}
@@ -0,0 +1,113 @@
+package matcher
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the expression in query syntax.
+func (e *Expression) String() string {
+	parts := make([]string, len(e.Or))
+	for i, x := range e.Or {
+		parts[i] = x.String()
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// String returns the AND chain in query syntax.
+func (e *OrCondition) String() string {
+	parts := make([]string, len(e.And))
+	for i, x := range e.And {
+		parts[i] = x.String()
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// String returns the condition in query syntax, e.g. `age > 30`.
+func (x *Condition) String() string {
+	s := x.operand()
+	if x.Not {
+		s = "NOT " + s
+	}
+	return s
+}
+
+// operand returns the condition without NOT in query syntax.
+func (x *Condition) operand() string {
+	if x.Group != nil {
+		return "(" + x.Group.String() + ")"
+	}
+	lhs := x.Symbol
+	if x.Call != nil {
+		lhs = x.Call.String()
+		if x.Compare == nil {
+			return lhs
+		}
+	}
+	return lhs + " " + x.Compare.Operator + " " + x.Compare.operand()
+}
+
+// operand returns the right-hand side of the comparison in query syntax.
+func (c *Compare) operand() string {
+	if c.Field != "" {
+		return c.Field
+	}
+	return c.Value.literal()
+}
+
+// String returns the call in query syntax, e.g. `ROLLOUT(user_id, 25)`.
+func (c *Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		switch {
+		case a.Duration != nil:
+			args[i] = a.Duration.literal()
+		case a.Value != nil:
+			args[i] = a.Value.literal()
+		default:
+			args[i] = a.Symbol
+		}
+	}
+	return c.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// literal returns the value in query syntax, or ? for a missing value in a
+// condition built by hand.
+func (v *Value) literal() string {
+	switch {
+	case v == nil:
+		return "?"
+	case v.Float != nil:
+		if n := v.integer(); n != nil {
+			return n.String()
+		}
+		return strconv.FormatFloat(*v.Float, 'g', -1, 64)
+	case v.String != nil:
+		return strconv.Quote(*v.String)
+	case v.Boolean != nil:
+		if *v.Boolean {
+			return "TRUE"
+		}
+		return "FALSE"
+	case v.Duration != nil:
+		return v.Duration.literal()
+	case v.Time != nil:
+		return string(*v.Time)
+	default:
+		return "NULL"
+	}
+}
+
+// literal returns the duration in query syntax, without zero minutes and
+// seconds: 5m instead of 5m0s.
+func (d Duration) literal() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package matcher
+
+// Frozen is an immutable context. One decoded record can be frozen once and
+// then be tested by many matchers on many goroutines with TestResolver,
+// without defensive copies: Test never modifies the context it is given,
+// and nothing can modify a Frozen.
+//
+// Values are shared with callers of Lookup; nested maps and slices must be
+// treated as read-only.
+type Frozen struct {
+	c Context
+}
+
+// Freeze returns an immutable deep copy of c.
+func Freeze(c Context) Frozen {
+	return Frozen{c: deepCopy(c).(map[string]interface{})}
+}
+
+// Lookup implements Resolver.
+func (f Frozen) Lookup(key string) (interface{}, bool) {
+	v, ok := f.c[key]
+	return v, ok
+}
+
+// Len returns the number of keys.
+func (f Frozen) Len() int {
+	return len(f.c)
+}
+
+// With returns a copy of f with key set to value, sharing the other values
+// with f.
+func (f Frozen) With(key string, value interface{}) Frozen {
+	c := make(Context, len(f.c)+1)
+	for k, v := range f.c {
+		c[k] = v
+	}
+	c[key] = deepCopy(value)
+	return Frozen{c: c}
+}
+
+// Context returns a mutable deep copy of f.
+func (f Frozen) Context() Context {
+	return deepCopy(f.c).(map[string]interface{})
+}
+
+// deepCopy copies the maps and slices of a decoded JSON like value.
+func deepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case Context:
+		return deepCopy(map[string]interface{}(x))
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			c[k] = deepCopy(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(x))
+		for i, v := range x {
+			c[i] = deepCopy(v)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+package matcher_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	assert := assert.New(t)
+	c := matcher.Context{"a": 1, "tags": []interface{}{"x"}, "meta": map[string]interface{}{"k": "v"}}
+	f := matcher.Freeze(c)
+	c["a"] = 2
+	c["tags"].([]interface{})[0] = "y"
+	c["meta"].(map[string]interface{})["k"] = "w"
+
+	v, _ := f.Lookup("a")
+	assert.Equal(1, v)
+	assert.Equal(matcher.Context{"a": 1, "tags": []interface{}{"x"}, "meta": map[string]interface{}{"k": "v"}}, f.Context())
+
+	g := f.With("a", 3)
+	v, _ = g.Lookup("a")
+	assert.Equal(3, v)
+	v, _ = f.Lookup("a")
+	assert.Equal(1, v)
+	assert.Equal(3, g.Len())
+
+	copied := f.Context()
+	copied["a"] = 4
+	v, _ = f.Lookup("a")
+	assert.Equal(1, v)
+}
+
+func TestFrozenConcurrent(t *testing.T) {
+	f := matcher.Freeze(matcher.Context{"status": 503, "path": "/api", "user": "bob"})
+	var ms []*matcher.Matcher
+	for _, q := range []string{`status >= 500`, `path = "/api" and user <> "alice"`, `EXISTS(user) and COUNT_OVER(1m) > 0`, `status = 200`} {
+		m, err := matcher.NewMatcher(q, matcher.WithAdaptiveOrdering(1), matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		ms = append(ms, m)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for k, m := range ms {
+					ok, err := m.TestResolver(f)
+					assert.NoError(t, err)
+					assert.Equal(t, k < 3, ok)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
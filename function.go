@@ -0,0 +1,140 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Call is a function call in a query, e.g. `ROLLOUT(user_id, 25)`. A call
+// without a comparison is a predicate and must return a bool; otherwise its
+// result is compared like a context value, e.g. `COUNT_OVER(5m) > 10`.
+type Call struct {
+	Name string `parser:"@Ident '('"`
+	Args []*Arg `parser:"( @@ ( ',' @@ )* )? ')'"`
+}
+
+// Arg is an argument of a function call: a duration such as 5m or 1h30m,
+// the name of a context field, or a literal.
+type Arg struct {
+	Duration *Duration `parser:"  @Duration"`
+	Symbol   string    `parser:"| @( (Ident | NonFinite) ( '.' (Ident | NonFinite) )* )"`
+	Value    *Value    `parser:"| @@"`
+}
+
+// Duration is a duration literal, written like time.ParseDuration accepts
+// it without a sign.
+type Duration time.Duration
+
+func (d *Duration) Capture(values []string) error {
+	v, err := time.ParseDuration(values[0])
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Func evaluates a function call against a context.
+type Func func(ctx Context) (interface{}, error)
+
+// FuncFactory compiles a call with the given arguments into a Func, or
+// reports why the arguments are invalid. It is called once for every call in
+// a query, so state kept by the returned Func belongs to that call of that
+// Matcher.
+//
+// A Func should only read the context fields named by its arguments, which
+// are the only fields TestReader decodes.
+type FuncFactory func(args []*Arg) (Func, error)
+
+// factory is a FuncFactory that may depend on the whole call and the matcher
+// options.
+type factory func(c *Call, o *options) (Func, error)
+
+// builtins are the functions available in every query.
+var builtins = map[string]factory{
+	"ROLLOUT":    rollout,
+	"COUNT_OVER": countOver,
+	"CHANGED":    changed,
+	"FIRST_SEEN": firstSeen,
+	"DEDUP":      dedup,
+	"RATE_LIMIT": rateLimit,
+	"EXISTS":     exists(true),
+	"MISSING":    exists(false),
+	"ANY":        quantifier,
+	"ALL":        quantifier,
+	"LOWER":      lower,
+	"UPPER":      upper,
+	"TRIM":       trim,
+	"LEN":        scalar(length),
+	"ABS":        scalar(abs),
+}
+
+// WithFunction makes the function name available to the query. Function
+// names are case-insensitive; a function added this way replaces a built-in
+// function with the same name.
+func WithFunction(name string, f FuncFactory) Option {
+	return func(o *options) {
+		if o.funcs == nil {
+			o.funcs = make(map[string]factory)
+		}
+		o.funcs[strings.ToUpper(name)] = func(c *Call, _ *options) (Func, error) {
+			return f(c.Args)
+		}
+	}
+}
+
+// compile looks up the function and compiles the call. The name is
+// normalized to upper case.
+func (c *Call) compile(o *options) (Func, error) {
+	name := strings.ToUpper(c.Name)
+	c.Name = name
+	f, ok := o.funcs[name]
+	if !ok {
+		f, ok = builtins[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown function %s", ErrInvalidQuery, c.Name)
+	}
+	o.calls++
+	fn, err := f(c, o)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidQuery, c, err)
+	}
+	return fn, nil
+}
+
+// exists returns the factory of EXISTS(field), which holds when the context
+// has the field, even with a null value, or of MISSING(field) if want is
+// false.
+func exists(want bool) factory {
+	return func(c *Call, _ *options) (Func, error) {
+		if len(c.Args) != 1 || c.Args[0].Symbol == "" {
+			return nil, fmt.Errorf("want %s(field)", c.Name)
+		}
+		field := c.Args[0].Symbol
+		return func(ctx Context) (interface{}, error) {
+			_, ok := lookup(ctx, field)
+			return ok == want, nil
+		}, nil
+	}
+}
+
+// hasCalls reports whether the expression calls a function.
+func (e *Expression) hasCalls() bool {
+	for _, c := range e.leaves() {
+		if c.Call != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// predicate returns the result of a call used without a comparison.
+func (x *Condition) predicate(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s: returned %T, not a boolean", x, v)
+	}
+	return b, nil
+}
@@ -0,0 +1,74 @@
+package matcher_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+// upper returns the string value of its field argument in upper case.
+func upper(args []*matcher.Arg) (matcher.Func, error) {
+	if len(args) != 1 || args[0].Symbol == "" {
+		return nil, errors.New("want UPPER(field)")
+	}
+	field := args[0].Symbol
+	return func(ctx matcher.Context) (interface{}, error) {
+		s, _ := ctx[field].(string)
+		return strings.ToUpper(s), nil
+	}, nil
+}
+
+func TestFunction(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`upper(name) = "BOB" and age > 20`, matcher.WithFunction("UPPER", upper), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`UPPER(name) = "BOB" AND age > 20`, m.Expression.String())
+
+	ok, err := m.Test(&matcher.Context{"name": "bob", "age": 30.0})
+	assert.NoError(err)
+	assert.True(ok)
+
+	ok, err = m.TestReader(strings.NewReader(`{"name": "bob", "other": [1, 2], "age": 30}`))
+	assert.NoError(err)
+	assert.True(ok)
+
+	ok, err = m.Test(&matcher.Context{"name": "alice", "age": 30.0})
+	assert.NoError(err)
+	assert.False(ok)
+}
+
+func TestFunctionPredicate(t *testing.T) {
+	assert := assert.New(t)
+	isAdmin := func(args []*matcher.Arg) (matcher.Func, error) {
+		return func(ctx matcher.Context) (interface{}, error) {
+			return ctx["role"] == "admin", nil
+		}, nil
+	}
+	m, err := matcher.NewMatcher("IS_ADMIN() or id = 1", matcher.WithFunction("is_admin", isAdmin), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal("IS_ADMIN() OR id = 1", m.Expression.String())
+
+	ok, err := m.Test(&matcher.Context{"role": "admin"})
+	assert.NoError(err)
+	assert.True(ok)
+
+	m, err = matcher.NewMatcher("UPPER(name)", matcher.WithFunction("UPPER", upper), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	_, err = m.Test(&matcher.Context{"name": "bob"})
+	assert.EqualError(err, "UPPER(name): returned string, not a boolean")
+}
+
+func TestFunctionErrors(t *testing.T) {
+	_, err := matcher.NewMatcher("NOPE(a)", matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(t, err, matcher.ErrInvalidQuery)
+	assert.EqualError(t, err, "matcher: invalid query: unknown function NOPE")
+
+	_, err = matcher.NewMatcher(`UPPER(1, "x", 5m)`, matcher.WithFunction("UPPER", upper), matcher.WithSyntaxVersion(2))
+	assert.EqualError(t, err, `matcher: invalid query: UPPER(1, "x", 5m): want UPPER(field)`)
+
+	_, err = matcher.NewMatcher("a and b = 1")
+	assert.EqualError(t, err, "matcher: invalid query: a: missing comparison")
+}
@@ -0,0 +1,131 @@
+package matcher
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generated is a random query with sample contexts, see Generator.
+type Generated struct {
+	// Query is written in the latest version of the grammar.
+	Query string
+	// Match is a context the query matches.
+	Match Context
+	// Miss is a context the query does not match, nil if none was found.
+	Miss Context
+}
+
+// Generator produces random valid queries with contexts that match them and
+// that do not, for property-based tests of rules, of code evaluating them and
+// of the matcher itself.
+type Generator struct {
+	// Rand is the source of randomness.
+	Rand *rand.Rand
+	// Schema maps the fields queries may use to the type of their values:
+	// "number", "string", "boolean" or "duration". If it is empty, the
+	// fields a to e are used with any of these types.
+	Schema map[string]string
+	// MaxBranches and MaxConditions bound the number of OR branches and of
+	// conditions per branch, 3 and 4 by default.
+	MaxBranches, MaxConditions int
+}
+
+// NewGenerator returns a generator seeded with seed, using schema.
+func NewGenerator(seed int64, schema map[string]string) *Generator {
+	return &Generator{Rand: rand.New(rand.NewSource(seed)), Schema: schema}
+}
+
+var generatedTypes = []string{"number", "string", "boolean", "duration"}
+
+// Generate returns a random query that some context matches, with such a
+// context and, if possible, a near miss from ExampleContexts.
+func (g *Generator) Generate() Generated {
+	for {
+		q := g.query()
+		m, err := NewMatcher(q, WithSyntaxVersion(SyntaxVersion))
+		if err != nil {
+			panic(fmt.Sprintf("matcher: generated invalid query %q: %v", q, err))
+		}
+		match, miss, err := ExampleContexts(m.Expression)
+		if err == nil {
+			return Generated{Query: q, Match: match, Miss: miss}
+		}
+	}
+}
+
+// query returns a random query.
+func (g *Generator) query() string {
+	fields, types := g.fields()
+	branches := make([]string, 1+g.Rand.Intn(orDefault(g.MaxBranches, 3)))
+	for i := range branches {
+		conds := make([]string, 1+g.Rand.Intn(orDefault(g.MaxConditions, 4)))
+		for j := range conds {
+			f := fields[g.Rand.Intn(len(fields))]
+			conds[j] = g.condition(f, types[f])
+		}
+		branches[i] = strings.Join(conds, " AND ")
+	}
+	return strings.Join(branches, " OR ")
+}
+
+// fields returns the fields of the schema in a stable order, with their
+// types.
+func (g *Generator) fields() ([]string, map[string]string) {
+	types := g.Schema
+	if len(types) == 0 {
+		types = make(map[string]string)
+		for _, f := range []string{"a", "b", "c", "d", "e"} {
+			types[f] = generatedTypes[g.Rand.Intn(len(generatedTypes))]
+		}
+	}
+	fields := make([]string, 0, len(types))
+	for f := range types {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields, types
+}
+
+// condition returns a random condition on field of type typ.
+func (g *Generator) condition(field, typ string) string {
+	switch g.Rand.Intn(12) {
+	case 0:
+		return "EXISTS(" + field + ")"
+	case 1:
+		return field + " " + g.pick("=", "<>") + " NULL"
+	}
+	ordered := []string{"=", "<>", "!=", ">", ">=", "<", "<="}
+	switch typ {
+	case "number":
+		n := float64(g.Rand.Intn(21) - 10)
+		if g.Rand.Intn(3) == 0 {
+			n += 0.5
+		}
+		return field + " " + g.pick(ordered...) + " " + strconv.FormatFloat(n, 'f', -1, 64)
+	case "boolean":
+		return field + " " + g.pick("=", "<>", "!=") + " " + g.pick("TRUE", "FALSE")
+	case "duration":
+		d := time.Duration(1+g.Rand.Intn(120)) * time.Second
+		return field + " " + g.pick(ordered...) + " " + Duration(d).literal()
+	}
+	b := make([]byte, g.Rand.Intn(4))
+	for i := range b {
+		b[i] = "abcxyz"[g.Rand.Intn(6)]
+	}
+	return field + " " + g.pick(ordered...) + " " + strconv.Quote(string(b))
+}
+
+func (g *Generator) pick(choices ...string) string {
+	return choices[g.Rand.Intn(len(choices))]
+}
+
+func orDefault(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
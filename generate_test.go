@@ -0,0 +1,45 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator(t *testing.T) {
+	assert := assert.New(t)
+	for _, schema := range []map[string]string{
+		nil,
+		{"age": "number", "name": "string", "vip": "boolean", "ttl": "duration"},
+	} {
+		g := matcher.NewGenerator(1, schema)
+		for i := 0; i < 300; i++ {
+			gen := g.Generate()
+			m, err := matcher.NewMatcher(gen.Query, matcher.WithSyntaxVersion(2))
+			assert.NoError(err, gen.Query)
+
+			// The canonical form parses back to the same query.
+			again, err := matcher.NewMatcher(m.Expression.String(), matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+			assert.Equal(m.Expression.String(), again.Expression.String())
+
+			ok, err := m.Test(&gen.Match)
+			assert.NoError(err, gen.Query)
+			assert.True(ok, "%s: %v", gen.Query, gen.Match)
+			if gen.Miss != nil {
+				ok, err = m.Test(&gen.Miss)
+				assert.NoError(err, gen.Query)
+				assert.False(ok, "%s: %v", gen.Query, gen.Miss)
+			}
+			if schema != nil {
+				for k := range gen.Match {
+					assert.Contains(schema, k)
+				}
+			}
+		}
+	}
+
+	a, b := matcher.NewGenerator(7, nil), matcher.NewGenerator(7, nil)
+	assert.Equal(a.Generate(), b.Generate())
+}
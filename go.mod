@@ -6,12 +6,13 @@ require (
 	github.com/alecthomas/kong v0.6.0
 	github.com/alecthomas/participle/v2 v2.0.0-alpha9
 	github.com/alecthomas/repr v0.1.0
+	github.com/klauspost/compress v1.15.15
 	github.com/stretchr/testify v1.7.2
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
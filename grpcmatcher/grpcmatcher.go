@@ -0,0 +1,183 @@
+// Package grpcmatcher provides gRPC server interceptors testing incoming calls
+// against matcher rules, so traffic rules written for HTTP edges apply to gRPC
+// services as well. It is a module of its own to keep gRPC out of the
+// dependencies of the matcher module.
+//
+// The context of a call has the keys
+//
+//   - method, the full method name such as "/helloworld.Greeter/SayHello",
+//   - service and method_name, its two parts,
+//   - header_<name> for every metadata entry except binary ones, all values
+//     joined with ", ", with dashes replaced by underscores,
+//   - client_ip, the address of the peer without the port,
+//
+// and, with Interceptor.Messages, the scalar fields of the request message of
+// a unary call under their proto names. Enums are the names of their values,
+// numbers are float64; repeated, map, bytes and message fields are left out,
+// as are fields with presence that are not set. Message fields win over
+// metadata.
+package grpcmatcher
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/kuwa72/matcher"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Action is the Action of a matcher.Rule rejecting the calls it matches with
+// a status. Rules with any other action only annotate calls.
+type Action struct {
+	Code    codes.Code
+	Message string
+}
+
+// Interceptor evaluates every call against its rules. A call matching a rule
+// whose Action rejects it fails with that status, taken from the first such
+// rule in priority order; otherwise the handler is called with the matched
+// rules available through Matched. A rule failing to evaluate fails the call
+// with codes.Internal.
+//
+// An Interceptor is safe for concurrent use by multiple goroutines.
+type Interceptor struct {
+	// Messages adds the fields of unary request messages to the context.
+	Messages bool
+
+	rules *matcher.RuleSet
+}
+
+// New compiles the queries of rules with opts.
+func New(rules []matcher.Rule, opts ...matcher.Option) (*Interceptor, error) {
+	s, err := matcher.NewRuleSet(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Interceptor{rules: s}, nil
+}
+
+// Unary returns the interceptor for unary calls.
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var msg proto.Message
+		if i.Messages {
+			msg, _ = req.(proto.Message)
+		}
+		ctx, err := i.evaluate(ctx, info.FullMethod, msg)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the interceptor for streaming calls. Only the call itself is
+// evaluated, not the messages sent over the stream.
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.evaluate(ss.Context(), info.FullMethod, nil)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// matchedKey is the context.Context key of the matched rules.
+type matchedKey struct{}
+
+// Matched returns the rules matching the call of ctx, in priority order.
+func Matched(ctx context.Context) []*matcher.Rule {
+	rules, _ := ctx.Value(matchedKey{}).([]*matcher.Rule)
+	return rules
+}
+
+// evaluate tests a call against the rules and returns the context for its
+// handler.
+func (i *Interceptor) evaluate(ctx context.Context, method string, msg proto.Message) (context.Context, error) {
+	c := callContext(ctx, method, msg)
+	matched, err := i.rules.EvaluateAll(&c)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	for _, r := range matched {
+		if a, ok := r.Action.(Action); ok && a.Code != codes.OK {
+			return nil, status.Error(a.Code, a.Message)
+		}
+	}
+	return context.WithValue(ctx, matchedKey{}, matched), nil
+}
+
+// callContext builds the context of a call.
+func callContext(ctx context.Context, method string, msg proto.Message) matcher.Context {
+	c := matcher.Context{"method": method}
+	if parts := strings.SplitN(strings.TrimPrefix(method, "/"), "/", 2); len(parts) == 2 {
+		c["service"], c["method_name"] = parts[0], parts[1]
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, v := range md {
+			if !strings.HasSuffix(k, "-bin") {
+				c["header_"+strings.ReplaceAll(k, "-", "_")] = strings.Join(v, ", ")
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		c["client_ip"] = addr
+	}
+	if msg != nil {
+		addFields(c, msg.ProtoReflect())
+	}
+	return c
+}
+
+// addFields adds the scalar fields of m to c.
+func addFields(c matcher.Context, m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for n := 0; n < fields.Len(); n++ {
+		fd := fields.Get(n)
+		if fd.IsList() || fd.IsMap() || fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+		name := string(fd.Name())
+		switch fd.Kind() {
+		case protoreflect.BoolKind:
+			c[name] = v.Bool()
+		case protoreflect.StringKind:
+			c[name] = v.String()
+		case protoreflect.EnumKind:
+			if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+				c[name] = string(ev.Name())
+			} else {
+				c[name] = float64(v.Enum())
+			}
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+			protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			c[name] = float64(v.Int())
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+			protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			c[name] = float64(v.Uint())
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			c[name] = v.Float()
+		}
+	}
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
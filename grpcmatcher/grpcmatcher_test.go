@@ -0,0 +1,106 @@
+package grpcmatcher_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/grpcmatcher"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newInterceptor(t *testing.T) *grpcmatcher.Interceptor {
+	i, err := grpcmatcher.New([]matcher.Rule{
+		{Name: "blocked", Query: `client_ip = "192.0.2.66"`,
+			Action: grpcmatcher.Action{Code: codes.PermissionDenied, Message: "blocked"}},
+		{Name: "admin", Query: `service = "admin.Admin" and header_x_role <> "admin"`,
+			Action: grpcmatcher.Action{Code: codes.Unauthenticated, Message: "admins only"}},
+		{Name: "old", Query: `seconds < 1000`},
+		{Name: "beta", Query: `header_x_client_version >= "2"`},
+	})
+	assert.NoError(t, err)
+	return i
+}
+
+func call(i *grpcmatcher.Interceptor, ip, method string, req interface{}, md ...string) ([]string, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md...))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+	var names []string
+	_, err := i.Unary()(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		names = []string{}
+		for _, r := range grpcmatcher.Matched(ctx) {
+			names = append(names, r.Name)
+		}
+		return nil, nil
+	})
+	return names, err
+}
+
+func TestUnary(t *testing.T) {
+	assert := assert.New(t)
+	i := newInterceptor(t)
+
+	names, err := call(i, "192.0.2.1", "/shop.Cart/Add", nil, "x-client-version", "2.1")
+	assert.NoError(err)
+	assert.Equal([]string{"beta"}, names)
+
+	_, err = call(i, "192.0.2.66", "/shop.Cart/Add", nil)
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+
+	_, err = call(i, "192.0.2.1", "/admin.Admin/Drop", nil, "x-role", "user")
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+	names, err = call(i, "192.0.2.1", "/admin.Admin/Drop", nil, "x-role", "admin")
+	assert.NoError(err)
+	assert.Equal([]string{}, names)
+}
+
+func TestUnaryMessages(t *testing.T) {
+	assert := assert.New(t)
+	i := newInterceptor(t)
+	req := &timestamppb.Timestamp{Seconds: 10}
+
+	names, err := call(i, "192.0.2.1", "/shop.Cart/Add", req)
+	assert.NoError(err)
+	assert.Equal([]string{}, names)
+
+	i.Messages = true
+	names, err = call(i, "192.0.2.1", "/shop.Cart/Add", req)
+	assert.NoError(err)
+	assert.Equal([]string{"old"}, names)
+}
+
+// stream is a grpc.ServerStream with a fixed context.
+type stream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stream) Context() context.Context { return s.ctx }
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+	i := newInterceptor(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-role", "user"))
+	info := &grpc.StreamServerInfo{FullMethod: "/admin.Admin/Watch"}
+	called := false
+	err := i.Stream()(nil, &stream{ctx: ctx}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+	assert.False(called)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-client-version", "3"))
+	err = i.Stream()(nil, &stream{ctx: ctx}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		assert.Len(grpcmatcher.Matched(ss.Context()), 1)
+		return nil
+	})
+	assert.NoError(err)
+}
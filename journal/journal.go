@@ -0,0 +1,107 @@
+// Package journal applies matcher queries to systemd journal entries in the
+// JSON format written by journalctl --output=json.
+//
+// An entry is tested against a context with its fields under their journal
+// names, such as MESSAGE, PRIORITY, _SYSTEMD_UNIT and _PID. Journal fields
+// are strings, so numbers like PRIORITY are compared numerically with number
+// literals: PRIORITY <= 3 selects errors and worse. Binary values, which
+// journalctl writes as arrays of bytes, are converted to strings, and of a
+// field with several values only the first one is kept.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kuwa72/matcher"
+)
+
+// Entry is a journal entry.
+type Entry struct {
+	// Raw is the entry as read.
+	Raw json.RawMessage
+
+	fields map[string]interface{}
+}
+
+// Context returns the context queries are tested against.
+func (e *Entry) Context() matcher.Context {
+	c := make(matcher.Context, len(e.fields))
+	for k, v := range e.fields {
+		if s, ok := value(v); ok {
+			c[k] = s
+		}
+	}
+	return c
+}
+
+// Field returns the value of a field, or "" if the entry does not have it.
+func (e *Entry) Field(name string) string {
+	s, _ := value(e.fields[name])
+	return s
+}
+
+// Time returns the wall clock time of the entry, taken from
+// __REALTIME_TIMESTAMP, or the zero time if it is missing.
+func (e *Entry) Time() time.Time {
+	us, err := strconv.ParseInt(e.Field("__REALTIME_TIMESTAMP"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMicro(us)
+}
+
+// value converts a JSON field value to a string.
+func value(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case []interface{}:
+		if len(x) == 0 {
+			return "", false
+		}
+		if _, ok := x[0].(float64); !ok {
+			return value(x[0])
+		}
+		b := make([]byte, len(x))
+		for i, n := range x {
+			f, _ := n.(float64)
+			b[i] = byte(f)
+		}
+		return string(b), true
+	}
+	return "", false
+}
+
+// Filter reads entries from r, one JSON object per line, and calls fn for
+// every entry matching m. Filter returns at the end of r or with the first
+// error of reading, of fn or of testing an entry.
+func Filter(r io.Reader, m *matcher.Matcher, fn func(*Entry) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<24)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		e := &Entry{Raw: append(json.RawMessage(nil), line...)}
+		if err := json.Unmarshal(line, &e.fields); err != nil {
+			return err
+		}
+		c := e.Context()
+		ok, err := m.Test(&c)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
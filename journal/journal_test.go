@@ -0,0 +1,50 @@
+package journal_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/journal"
+	"github.com/stretchr/testify/assert"
+)
+
+const entries = `{"__REALTIME_TIMESTAMP":"1714564800000000","PRIORITY":"6","_SYSTEMD_UNIT":"nginx.service","MESSAGE":"started"}
+{"__REALTIME_TIMESTAMP":"1714564801000000","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","MESSAGE":"upstream timed out","_PID":"812"}
+
+{"__REALTIME_TIMESTAMP":"1714564802000000","PRIORITY":"2","_SYSTEMD_UNIT":"sshd.service","MESSAGE":[98,97,100,0]}
+{"__REALTIME_TIMESTAMP":"1714564803000000","PRIORITY":"4","_SYSTEMD_UNIT":"cron.service","MESSAGE":["first","second"]}
+`
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`PRIORITY <= 3 or _SYSTEMD_UNIT = "cron.service"`)
+	assert.NoError(err)
+
+	var got []*journal.Entry
+	err = journal.Filter(strings.NewReader(entries), m, func(e *journal.Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Len(got, 3)
+	assert.Equal(matcher.Context{
+		"__REALTIME_TIMESTAMP": "1714564801000000",
+		"PRIORITY":             "3",
+		"_SYSTEMD_UNIT":        "nginx.service",
+		"MESSAGE":              "upstream timed out",
+		"_PID":                 "812",
+	}, got[0].Context())
+	assert.Equal("bad\x00", got[1].Field("MESSAGE"))
+	assert.Equal("first", got[2].Field("MESSAGE"))
+	assert.Equal(time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC), got[0].Time().UTC())
+	assert.Equal("", got[0].Field("SYSLOG_IDENTIFIER"))
+}
+
+func TestFilterInvalid(t *testing.T) {
+	m, err := matcher.NewMatcher(`PRIORITY <= 3`)
+	assert.NoError(t, err)
+	err = journal.Filter(strings.NewReader("{\n"), m, func(*journal.Entry) error { return nil })
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package matcher
+
+import "sort"
+
+// Resolver looks up the values of context keys. Context and Layers are
+// resolvers; others can serve values from any source.
+type Resolver interface {
+	// Lookup returns the value of key and whether the key exists.
+	Lookup(key string) (value interface{}, ok bool)
+}
+
+// Lookup implements Resolver.
+func (c Context) Lookup(key string) (interface{}, bool) {
+	v, ok := c[key]
+	return v, ok
+}
+
+// Layers is a chain of contexts resolved from the last to the first, so
+// later layers take precedence. It combines, say, static environment facts,
+// tenant defaults and per-event data without copying them into one map. A
+// key that is null in a later layer is null, not taken from an earlier one.
+type Layers []Context
+
+// Layer returns overlay on top of base.
+func Layer(base, overlay Context) Layers {
+	return Layers{base, overlay}
+}
+
+// Lookup implements Resolver.
+func (l Layers) Lookup(key string) (interface{}, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if v, ok := l[i][key]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// TestResolver reports whether the values r resolves match the query. Only
+// the keys the query refers to are looked up.
+func (m *Matcher) TestResolver(r Resolver) (bool, error) {
+	c := AcquireContext()
+	defer ReleaseContext(c)
+	m.resolve(r, c)
+	return m.test(&c)
+}
+
+// resolve stores the values r resolves for the keys the query refers to in
+// c, together with the automatic variables of WithAutoVariables.
+func (m *Matcher) resolve(r Resolver, c Context) {
+	var auto *autoVars
+	if m.now != nil {
+		auto = &autoVars{now: m.now}
+	}
+	for _, k := range m.symbols() {
+		if v, ok := r.Lookup(k); ok {
+			c[k] = v
+		} else if auto != nil {
+			if v, ok := auto.Lookup(k); ok {
+				c[k] = v
+			}
+		}
+	}
+}
+
+// symbols returns the sorted keys the query refers to.
+func (m *Matcher) symbols() []string {
+	m.symsOnce.Do(func() {
+		for k := range m.Expression.symbols() {
+			m.syms = append(m.syms, k)
+		}
+		sort.Strings(m.syms)
+	})
+	return m.syms
+}
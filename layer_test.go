@@ -0,0 +1,47 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayers(t *testing.T) {
+	assert := assert.New(t)
+	env := matcher.Context{"region": "eu", "tier": "free", "debug": true}
+	tenant := matcher.Context{"tier": "pro", "debug": nil}
+	event := matcher.Context{"status": 503}
+	l := matcher.Layers{env, tenant, event}
+
+	v, ok := l.Lookup("tier")
+	assert.True(ok)
+	assert.Equal("pro", v)
+	v, ok = l.Lookup("debug")
+	assert.True(ok)
+	assert.Nil(v)
+	_, ok = l.Lookup("missing")
+	assert.False(ok)
+
+	m, err := matcher.NewMatcher(`region = "eu" and tier = "pro" and status >= 500 and debug = NULL`)
+	assert.NoError(err)
+	matched, err := m.TestResolver(l)
+	assert.NoError(err)
+	assert.True(matched)
+
+	matched, err = m.TestResolver(matcher.Layer(tenant, env))
+	assert.NoError(err)
+	assert.False(matched)
+	assert.Equal(matcher.Context{"region": "eu", "tier": "free", "debug": true}, env)
+}
+
+func TestTestResolverFunctions(t *testing.T) {
+	m, err := matcher.NewMatcher(`EXISTS(user) and MISSING(admin)`, matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	matched, err := m.TestResolver(matcher.Layer(matcher.Context{"user": "bob"}, matcher.Context{"x": 1}))
+	assert.NoError(t, err)
+	assert.True(t, matched)
+	matched, err = m.TestResolver(matcher.Context{"user": "bob", "admin": true})
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
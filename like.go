@@ -0,0 +1,169 @@
+package matcher
+
+import (
+	"regexp"
+	"strings"
+)
+
+// isLike reports whether op is LIKE or NOT LIKE.
+func isLike(op string) bool {
+	return op == "LIKE" || op == "NOT LIKE"
+}
+
+// likeMatcher returns a function reporting whether a string matches the SQL
+// LIKE pattern p, anchored at both ends: % matches any run of characters, _
+// any single character, and a backslash, written \\ in a query, makes the
+// next character literal. Matching is case sensitive. Patterns that are a
+// literal with % at either or both ends are matched without a regular
+// expression.
+func likeMatcher(p string) func(string) bool {
+	var (
+		parts   []string // literal runs between % wildcards
+		b       strings.Builder
+		single  bool // the pattern has _ wildcards
+		escaped bool
+	)
+	for _, r := range p {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			parts = append(parts, b.String())
+			b.Reset()
+		case r == '_':
+			single = true
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	parts = append(parts, b.String())
+
+	if !single {
+		switch {
+		case len(parts) == 1:
+			lit := parts[0]
+			return func(s string) bool { return s == lit }
+		case len(parts) == 2 && parts[1] == "":
+			lit := parts[0]
+			return func(s string) bool { return strings.HasPrefix(s, lit) }
+		case len(parts) == 2 && parts[0] == "":
+			lit := parts[1]
+			return func(s string) bool { return strings.HasSuffix(s, lit) }
+		case len(parts) == 3 && parts[0] == "" && parts[2] == "":
+			lit := parts[1]
+			return func(s string) bool { return strings.Contains(s, lit) }
+		}
+	}
+	return likeRegexp(p).MatchString
+}
+
+// likeRegexp translates a LIKE pattern into an anchored regular expression.
+func likeRegexp(p string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	escaped := false
+	for _, r := range p {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(`.*`)
+		case r == '_':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		b.WriteString(`\\`)
+	}
+	b.WriteString(`$`)
+	return regexp.MustCompile(b.String())
+}
+
+// compileLike prepares a LIKE or NOT LIKE condition. NULL and missing
+// values match neither; values other than strings are mismatches.
+func (x *Condition) compileLike(o *options) {
+	lit := *x.Compare.Value.String
+	if o.normalize {
+		lit = o.form.String(lit)
+	}
+	match := likeMatcher(lit)
+	not := x.Compare.Operator == "NOT LIKE"
+	x.test = func(ctxVal interface{}) (bool, error) {
+		s, ok := ctxVal.(string)
+		if !ok {
+			if ctxVal == nil {
+				return false, nil
+			}
+			return x.mismatch(ctxVal)
+		}
+		if o.normalize && !o.form.IsNormalString(s) {
+			s = o.form.String(s)
+		}
+		return match(s) != not, nil
+	}
+}
+
+// likeLiterals returns the runs of literal text between the wildcards of the
+// LIKE pattern p, which every string matching p contains.
+func likeLiterals(p string) []string {
+	var (
+		lits    []string
+		b       strings.Builder
+		escaped bool
+	)
+	for _, r := range p {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%', r == '_':
+			lits = append(lits, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	return append(lits, b.String())
+}
+
+// likeExample returns a string matching the LIKE pattern p, with the
+// wildcards replaced by the shortest text they match.
+func likeExample(p string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range p {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+		case r == '_':
+			b.WriteRune('x')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLike(t *testing.T) {
+	tests := []struct {
+		query string
+		value interface{}
+		want  bool
+	}{
+		{`name LIKE "Jo%n"`, "John", true},
+		{`name LIKE "Jo%n"`, "Jon", true},
+		{`name LIKE "Jo%n"`, "Johnny", false},
+		{`name LIKE "Jo%"`, "Johnny", true},
+		{`name LIKE "%ny"`, "Johnny", true},
+		{`name LIKE "%hn%"`, "Johnny", true},
+		{`name LIKE "J_n"`, "Jon", true},
+		{`name LIKE "J_n"`, "Joan", false},
+		{`name LIKE "J_n"`, "Jön", true},
+		{`name LIKE "a.c"`, "abc", false},
+		{`name LIKE "John"`, "John", true},
+		{`name LIKE "john"`, "John", false},
+		{`name LIKE "100\\%"`, "100%", true},
+		{`name LIKE "100\\%"`, "1000", false},
+		{`name LIKE "%"`, "", true},
+		{`name like "j%"`, "joe", true},
+		{`name NOT LIKE "Jo%"`, "John", false},
+		{`name not like "Jo%"`, "Mary", true},
+		{`name LIKE "1%"`, 10.0, false},
+		{`name NOT LIKE "1%"`, 10.0, true},
+		{`name LIKE "%"`, nil, false},
+		{`name NOT LIKE "%"`, nil, false},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&matcher.Context{"name": tt.value})
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %v", tt.query, tt.value)
+	}
+}
+
+func TestLikeInvalid(t *testing.T) {
+	assert := assert.New(t)
+	_, err := matcher.NewMatcher(`name LIKE 1`, matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+
+	var perr *matcher.ParseError
+	_, err = matcher.NewMatcher(`name LIKE "J%"`, matcher.WithSyntaxVersion(1))
+	assert.ErrorAs(err, &perr)
+
+	m, err := matcher.NewMatcher(`name not  like "J%"`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`name NOT LIKE "J%"`, m.Expression.String())
+	assert.Equal(1, m.Expression.Complexity().Regexes)
+}
+
+func TestLikeExamples(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`name LIKE "J_n%" AND tag NOT LIKE "%x"`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	match, miss, err := matcher.ExampleContexts(m.Expression)
+	assert.NoError(err)
+	ok, _ := m.Test(&match)
+	assert.True(ok)
+	ok, _ = m.Test(&miss)
+	assert.False(ok)
+
+	m, err = matcher.NewMatcher(`name LIKE "John"`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Len(m.Warnings(), 1)
+}
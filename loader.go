@@ -0,0 +1,145 @@
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Loader keeps a RuleSet in sync with rule files, for long-running
+// processes whose rules change without a restart.
+//
+// Files ending in .yaml or .yml hold a list of rules:
+//
+//	# rules.yaml
+//	- name: big-orders
+//	  query: total > 100
+//	  priority: 10
+//
+// Other files hold one query per line, named after the file and line like
+// "rules.txt:3"; blank lines and lines starting with # are skipped.
+//
+// A Loader is safe for concurrent use.
+type Loader struct {
+	// OnReload, if not nil, is called with every newly loaded RuleSet.
+	OnReload func(*RuleSet)
+	// OnError, if not nil, is called when the files fail to load. The
+	// previous RuleSet stays in use.
+	OnError func(error)
+
+	paths []string
+	opts  []Option
+	rules atomic.Value // *RuleSet
+
+	mu  sync.Mutex // serializes loads
+	sum [sha256.Size]byte
+}
+
+// NewLoader loads the rules of paths, compiling them with opts.
+func NewLoader(paths []string, opts ...Option) (*Loader, error) {
+	l := &Loader{paths: paths, opts: opts}
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// RuleSet returns the rules last loaded successfully.
+func (l *Loader) RuleSet() *RuleSet {
+	return l.rules.Load().(*RuleSet)
+}
+
+// Reload reads the files and, if they changed, compiles and swaps in their
+// rules. It reports whether the rules were replaced. On error, the previous
+// rules stay in use and OnError is not called.
+func (l *Loader) Reload() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := sha256.New()
+	var rules []Rule
+	for _, p := range l.paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return false, err
+		}
+		h.Write([]byte(p))
+		h.Write(b)
+		r, err := parseRuleFile(p, b)
+		if err != nil {
+			return false, err
+		}
+		rules = append(rules, r...)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	if l.rules.Load() != nil && sum == l.sum {
+		return false, nil
+	}
+
+	s, err := NewRuleSet(rules, l.opts...)
+	if err != nil {
+		return false, err
+	}
+	l.rules.Store(s)
+	l.sum = sum
+	if l.OnReload != nil {
+		l.OnReload(s)
+	}
+	return true, nil
+}
+
+// Run reloads the files every interval until ctx is done, and returns the
+// error of ctx.
+func (l *Loader) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if _, err := l.Reload(); err != nil && l.OnError != nil {
+				l.OnError(err)
+			}
+		}
+	}
+}
+
+// parseRuleFile returns the rules of a file.
+func parseRuleFile(path string, b []byte) ([]Rule, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var rules []Rule
+		if err := yaml.Unmarshal(b, &rules); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i, r := range rules {
+			if r.Name == "" {
+				rules[i].Name = fmt.Sprintf("%s[%d]", path, i)
+			}
+		}
+		return rules, nil
+	}
+
+	var rules []Rule
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		q := strings.TrimSpace(sc.Text())
+		if q == "" || strings.HasPrefix(q, "#") {
+			continue
+		}
+		rules = append(rules, Rule{Name: fmt.Sprintf("%s:%d", path, n), Query: q})
+	}
+	return rules, sc.Err()
+}
@@ -0,0 +1,78 @@
+package matcher_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	lines := filepath.Join(dir, "rules.txt")
+	yml := filepath.Join(dir, "rules.yaml")
+	assert.NoError(os.WriteFile(lines, []byte("# errors\nlevel = \"error\"\n\nstatus >= 500\n"), 0o644))
+	assert.NoError(os.WriteFile(yml, []byte("- name: vip\n  query: vip = TRUE\n  priority: 10\n  action: page\n"), 0o644))
+
+	l, err := matcher.NewLoader([]string{lines, yml})
+	assert.NoError(err)
+	var names []string
+	for _, r := range l.RuleSet().Rules() {
+		names = append(names, r.Name)
+	}
+	assert.Equal([]string{"vip", lines + ":2", lines + ":4"}, names)
+	assert.Equal("page", l.RuleSet().Rules()[0].Action)
+
+	reloaded, err := l.Reload()
+	assert.NoError(err)
+	assert.False(reloaded)
+
+	// A broken file keeps the previous rules.
+	first := l.RuleSet()
+	assert.NoError(os.WriteFile(lines, []byte("level =\n"), 0o644))
+	reloaded, err = l.Reload()
+	assert.ErrorContains(err, lines+":1")
+	assert.False(reloaded)
+	assert.Same(first, l.RuleSet())
+
+	assert.NoError(os.WriteFile(lines, []byte("level = \"warn\"\n"), 0o644))
+	reloaded, err = l.Reload()
+	assert.NoError(err)
+	assert.True(reloaded)
+	assert.Len(l.RuleSet().Rules(), 2)
+
+	_, err = matcher.NewLoader([]string{filepath.Join(dir, "missing.txt")})
+	assert.Error(err)
+}
+
+func TestLoaderRun(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	assert.NoError(os.WriteFile(path, []byte("a = 1\n"), 0o644))
+	l, err := matcher.NewLoader([]string{path})
+	assert.NoError(err)
+
+	reloads := make(chan *matcher.RuleSet, 1)
+	errs := make(chan error, 1)
+	l.OnReload = func(s *matcher.RuleSet) { reloads <- s }
+	l.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Run(ctx, time.Millisecond)
+
+	assert.NoError(os.WriteFile(path, []byte("a = \n"), 0o644))
+	assert.Error(<-errs)
+	assert.NoError(os.WriteFile(path, []byte("a = 2\n"), 0o644))
+	s := <-reloads
+	assert.Equal("a = 2", s.Rules()[0].Query)
+}
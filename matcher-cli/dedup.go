@@ -0,0 +1,39 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+)
+
+// deduper remembers the key values already emitted. With a positive size
+// only the most recently seen keys are kept.
+type deduper struct {
+	size  int
+	order *list.List
+	seen  map[string]*list.Element
+}
+
+func newDeduper(size int) *deduper {
+	return &deduper{size: size, order: list.New(), seen: make(map[string]*list.Element)}
+}
+
+// first reports whether v has not been seen before (or was evicted), and
+// marks it as seen.
+func (d *deduper) first(v interface{}) bool {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return true
+	}
+	key := string(b)
+	if e, ok := d.seen[key]; ok {
+		d.order.MoveToFront(e)
+		return false
+	}
+	d.seen[key] = d.order.PushFront(key)
+	if d.size > 0 && d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.seen, oldest.Value.(string))
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/dockerevents"
+)
+
+// dockerCmd applies a query to the Docker event stream.
+type dockerCmd struct {
+	QUERY string `arg:"" help:"QUERY to test the events against."`
+	Exec  string `placeholder:"COMMAND" help:"Run COMMAND with sh -c for every matching event, with the event JSON on stdin, instead of printing the event."`
+	Host  string `help:"Docker daemon address; defaults to DOCKER_HOST or unix:///var/run/docker.sock."`
+}
+
+// Run watches events until interrupted.
+func (d *dockerCmd) Run() error {
+	m, err := matcher.NewMatcher(d.QUERY)
+	if err != nil {
+		fail(exitUsageError, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = (&dockerevents.Client{Host: d.Host}).Watch(ctx, m, func(ev *dockerevents.Event) error {
+		if d.Exec == "" {
+			fmt.Println(string(ev.Raw))
+			return nil
+		}
+		cmd := exec.Command("sh", "-c", d.Exec)
+		cmd.Stdin = bytes.NewReader(ev.Raw)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Printf("%s: %v", d.Exec, err)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fail(exitInputError, err)
+	}
+	return nil
+}
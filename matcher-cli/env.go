@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expandEnv replaces ${VAR} references in the query with values from lookup.
+//
+// References are only expanded inside quoted string literals, and a value
+// containing the literal's quote character is rejected, so a variable can
+// never end the literal and inject query syntax. Backslashes in values are
+// escaped, as the parser unescapes string literals.
+func expandEnv(q string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	var quote byte // the open literal's quote character, 0 outside literals
+	for i := 0; i < len(q); i++ {
+		c := q[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case c == '$' && i+1 < len(q) && q[i+1] == '{':
+			end := strings.IndexByte(q[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference at offset %d", i)
+			}
+			name := q[i+2 : i+end]
+			if quote == 0 {
+				return "", fmt.Errorf("${%s} must be used inside a quoted string", name)
+			}
+			v, ok := lookup(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			if strings.IndexByte(v, quote) >= 0 {
+				return "", fmt.Errorf("environment variable %s contains a %c quote", name, quote)
+			}
+			b.WriteString(strings.ReplaceAll(v, `\`, `\\`))
+			i += end
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
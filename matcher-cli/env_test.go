@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"TENANT": "acme",
+		"EMPTY":  "",
+		"PATH":   `C:\new\tab`,
+		"DQUOTE": `say "hi"`,
+		"SQUOTE": `it's`,
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cases := []struct {
+		query string
+		field string // the field the query matches when it holds want
+		want  string
+	}{
+		{`tenant = "${TENANT}"`, "tenant", "acme"},
+		{`tenant = 'id-${TENANT}-${TENANT}'`, "tenant", "id-acme-acme"},
+		{`tenant = "${EMPTY}"`, "tenant", ""},
+		{`path = "${PATH}"`, "path", `C:\new\tab`},
+		{`path = '${PATH}'`, "path", `C:\new\tab`},
+		{`msg = '${DQUOTE}'`, "msg", `say "hi"`},
+		{`msg = "${SQUOTE}"`, "msg", `it's`},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			q, err := expandEnv(c.query, lookup)
+			assert.NoError(t, err)
+			m, err := matcher.NewMatcher(q)
+			assert.NoError(t, err)
+			ok, err := m.Test(&matcher.Context{c.field: c.want})
+			assert.NoError(t, err)
+			assert.True(t, ok, q)
+		})
+	}
+
+	for _, q := range []string{
+		`msg = "${DQUOTE}"`,
+		`msg = '${SQUOTE}'`,
+		`tenant = ${TENANT}`,
+		`tenant = "${UNSET}"`,
+		`tenant = "${TENANT"`,
+	} {
+		_, err := expandEnv(q, lookup)
+		assert.Error(t, err, q)
+	}
+}
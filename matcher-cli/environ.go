@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+
+	"github.com/kuwa72/matcher"
+)
+
+// envCmd tests the environment against a query.
+type envCmd struct {
+	QUERY  string `arg:"" help:"QUERY to test the environment variables against."`
+	Prefix string `help:"Only use variables starting with PREFIX, with PREFIX removed from their names."`
+	Lower  bool   `help:"Map variable names to lower case."`
+}
+
+// Run exits with exitMatched if the environment matches.
+func (e *envCmd) Run() error {
+	m, err := matcher.NewMatcher(e.QUERY)
+	if err != nil {
+		fail(exitUsageError, err)
+	}
+	opts := []matcher.EnvironOption{matcher.WithEnvPrefix(e.Prefix)}
+	if e.Lower {
+		opts = append(opts, matcher.WithEnvLowerCase())
+	}
+	c := matcher.ContextFromEnviron(opts...)
+	ok, err := m.Test(&c)
+	if err != nil {
+		fail(exitEvalError, err)
+	}
+	if !ok {
+		os.Exit(exitUnmatched)
+	}
+	return nil
+}
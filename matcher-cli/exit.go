@@ -0,0 +1,21 @@
+package main
+
+// Exit codes of matcher-cli.
+const (
+	exitMatched    = 0 // at least one input document matched
+	exitUnmatched  = 1 // no input document matched
+	exitUsageError = 2 // invalid flags, query or template
+	exitEvalError  = 3 // the query failed to evaluate against a document
+	exitInputError = 4 // an input could not be read or decoded
+	exitServeError = 5 // route, syslog: the server failed
+)
+
+// exitError carries the exit code an error should terminate with.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/journal"
+)
+
+// journalCmd applies a query to systemd journal entries.
+type journalCmd struct {
+	QUERY  string   `arg:"" help:"QUERY to test the entries against."`
+	Files  []string `arg:"" optional:"" type:"path" help:"Files written by journalctl --output=json, - for stdin; journalctl is run when omitted."`
+	Follow bool     `short:"f" help:"Keep printing new entries (without files)."`
+	Since  string   `help:"Only read entries since this time, in any format journalctl accepts (without files)."`
+	JSON   bool     `help:"Print the entries as JSON instead of short lines."`
+}
+
+// Run prints the matching entries and exits with the outcome.
+func (j *journalCmd) Run() error {
+	m, err := matcher.NewMatcher(j.QUERY)
+	if err != nil {
+		fail(exitUsageError, err)
+	}
+
+	matched := false
+	print := func(e *journal.Entry) error {
+		matched = true
+		if j.JSON {
+			fmt.Println(string(e.Raw))
+			return nil
+		}
+		ident := e.Field("SYSLOG_IDENTIFIER")
+		if pid := e.Field("_PID"); pid != "" {
+			ident += "[" + pid + "]"
+		}
+		fmt.Printf("%s %s %s: %s\n", e.Time().Format(time.RFC3339), e.Field("_HOSTNAME"), ident, e.Field("MESSAGE"))
+		return nil
+	}
+
+	if len(j.Files) == 0 {
+		args := []string{"--output=json"}
+		if j.Follow {
+			args = append(args, "--follow")
+		}
+		if j.Since != "" {
+			args = append(args, "--since="+j.Since)
+		}
+		cmd := exec.Command("journalctl", args...)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			fail(exitInputError, err)
+		}
+		if err := cmd.Start(); err != nil {
+			fail(exitInputError, err)
+		}
+		j.filter(m, out, print)
+		if err := cmd.Wait(); err != nil {
+			fail(exitInputError, err)
+		}
+	}
+	for _, name := range j.Files {
+		if name == "-" {
+			j.filter(m, os.Stdin, print)
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			fail(exitInputError, err)
+		}
+		j.filter(m, f, print)
+		f.Close()
+	}
+	if !matched {
+		os.Exit(exitUnmatched)
+	}
+	return nil
+}
+
+// filter prints the matching entries of r.
+func (j *journalCmd) filter(m *matcher.Matcher, r io.Reader, print func(*journal.Entry) error) {
+	if err := journal.Filter(r, m, print); err != nil {
+		fail(exitInputError, err)
+	}
+}
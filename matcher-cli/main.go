@@ -1,48 +1,359 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
 
 	"github.com/alecthomas/kong"
+	"github.com/klauspost/compress/zstd"
 
 	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/plugin"
 )
 
+var cli struct {
+	Filter  filterCmd  `cmd:"" default:"withargs" help:"Test JSON documents against QUERY (the default command)."`
+	Route   routeCmd   `cmd:"" help:"Serve webhooks, forwarding them to the destinations of the matching routes."`
+	Docker  dockerCmd  `cmd:"" name:"docker-events" help:"Print or act on the Docker events matching QUERY."`
+	MQTT    mqttCmd    `cmd:"" name:"mqtt" help:"Print or republish the MQTT messages matching QUERY."`
+	Journal journalCmd `cmd:"" help:"Print the systemd journal entries matching QUERY."`
+	Syslog  syslogCmd  `cmd:"" help:"Receive syslog messages and print or forward those matching QUERY."`
+	Env     envCmd     `cmd:"" help:"Exit with 0 if the environment variables match QUERY."`
+}
+
+// filterCmd tests input documents against a query.
+type filterCmd struct {
+	QUERY               string   `arg:"" required:"" help:"QUERY to parse."`
+	Input               []string `arg:"" optional:"" help:"Inputs to read: files, http(s):// URLs or s3://bucket/key objects. Defaults to stdin (-)."`
+	Template            string   `short:"t" help:"Go text/template rendered with the matched JSON instead of the default report, e.g. '{{.name}} <{{.email}}>'."`
+	Context             []string `sep:"none" placeholder:"FILE" help:"JSON document merged under every input document; repeatable, later files win over earlier ones and input documents win over all."`
+	Compress            string   `enum:"auto,none,gzip,zstd" default:"auto" help:"Input compression (auto,none,gzip,zstd). auto detects gzip and zstd by their magic bytes."`
+	ExpandEnv           bool     `help:"Expand $${VAR} references inside quoted strings of QUERY from the environment."`
+	ExitZeroOnUnmatched bool     `help:"Exit with 0 when nothing matched; errors still exit non-zero."`
+	DedupKey            string   `placeholder:"FIELD" help:"Emit only the first matching document for each value of FIELD."`
+	DedupSize           int      `placeholder:"N" help:"Remember at most N recent --dedup-key values (0 is unbounded)."`
+	Summary             bool     `help:"Print a table of matched counts at the end instead of per-document output."`
+	GroupBy             string   `placeholder:"FIELD" help:"Group the --summary counts by the value of FIELD."`
+	Quiet               bool     `short:"q" help:"Print nothing; report only through the exit code (and --json)."`
+	JSON                bool     `name:"json" help:"Write a JSON summary of the run to stdout instead of any other output."`
+	Retries             int      `default:"3" help:"Retries for failed URL and S3 downloads."`
+	Plugin              []string `sep:"none" placeholder:"PATH" help:"Executable providing query functions over the JSON plugin protocol; repeatable."`
+	FloatNumbers        bool     `help:"Decode JSON numbers as float64, losing the precision of integers beyond 2^53, instead of keeping them exact."`
+	SyntaxVersion       int      `default:"1" placeholder:"N" help:"Grammar version of QUERY; 2 adds functions, LIKE, NOT, parentheses and paths."`
+}
+
 var (
-	cli struct {
-		QUERY string `arg:"" required:"" help:"QUERY to parse."`
-	}
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
 )
 
-func main() {
-	ctx := kong.Parse(&cli)
-	m, err := matcher.NewMatcher(cli.QUERY)
-	ctx.FatalIfErrorf(err)
+// decompress wraps r with a streaming decoder for the given compression.
+func decompress(r io.Reader, compress string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if compress == "auto" {
+		compress = "none"
+		if head, _ := br.Peek(len(zstdMagic)); bytes.HasPrefix(head, zstdMagic) {
+			compress = "zstd"
+		} else if bytes.HasPrefix(head, gzipMagic) {
+			compress = "gzip"
+		}
+	}
+
+	switch compress {
+	case "gzip":
+		return gzip.NewReader(br)
+	case "zstd":
+		d, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// stats counts the documents seen and matched across all inputs.
+type stats struct {
+	Documents int            `json:"documents"`
+	Matches   int            `json:"matches"`
+	Groups    map[string]int `json:"groups,omitempty"`
+}
+
+// count records a matched document, grouped by --group-by.
+func (st *stats) count(c matcher.Context) {
+	st.Matches++
+	if cli.Filter.GroupBy == "" {
+		return
+	}
+	key := "(missing)"
+	if v, ok := c[cli.Filter.GroupBy]; ok {
+		key = fmt.Sprint(v)
+	}
+	if st.Groups == nil {
+		st.Groups = make(map[string]int)
+	}
+	st.Groups[key]++
+}
+
+// printSummary writes the matched counts as a table, largest group first.
+func printSummary(w io.Writer, st stats) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if cli.Filter.GroupBy != "" {
+		keys := make([]string, 0, len(st.Groups))
+		for k := range st.Groups {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if st.Groups[keys[i]] != st.Groups[keys[j]] {
+				return st.Groups[keys[i]] > st.Groups[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		fmt.Fprintf(tw, "%s\tMATCHED\n", strings.ToUpper(cli.Filter.GroupBy))
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%s\t%d\n", k, st.Groups[k])
+		}
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d/%d\n", st.Matches, st.Documents)
+	tw.Flush()
+}
 
-	j, err := ioutil.ReadAll(os.Stdin)
+// filterInput tests every JSON document of the named input and prints the
+// results.
+func filterInput(m *matcher.Matcher, tmpl *template.Template, base matcher.Context, dedup *deduper, name string, st *stats) error {
+	r, err := openInput(name, cli.Filter.Retries)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return err
+	}
+	defer r.Close()
+
+	in, err := decompress(r, cli.Filter.Compress)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Input is a stream of JSON documents, so NDJSON works as well as a
+	// single object.
+	dec := newDecoder(in)
+	for {
+		err := filterDocument(m, tmpl, base, dedup, dec, name, st)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// filterDocument decodes the next document of the named input over base and
+// reports it, returning io.EOF at the end of the input.
+func filterDocument(m *matcher.Matcher, tmpl *template.Template, base matcher.Context, dedup *deduper, dec *json.Decoder, name string, st *stats) error {
+	c := matcher.AcquireContext()
+	defer matcher.ReleaseContext(c)
+	for k, v := range base {
+		c[k] = v
+	}
+	if err := dec.Decode(&c); errors.Is(err, io.EOF) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
 	}
-	c := matcher.Context(make(map[string]interface{}))
-	json.Unmarshal([]byte(j), &c)
+	return report(m, tmpl, dedup, c, st)
+}
+
+// report tests one document and prints the result.
+func report(m *matcher.Matcher, tmpl *template.Template, dedup *deduper, c matcher.Context, st *stats) error {
+	st.Documents++
 
 	b, err := m.Test(&c)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return &exitError{code: exitEvalError, err: err}
+	}
+	if b && dedup != nil {
+		if v, ok := c[cli.Filter.DedupKey]; ok && !dedup.first(v) {
+			return nil
+		}
+	}
+	if b {
+		st.count(c)
 	}
-	fmt.Printf("QUERY: %#v\n", cli.QUERY)
-	fmt.Printf("JSON structure: %#v\n", c)
+
 	switch {
-	case b:
-		fmt.Println("matched")
-		os.Exit(0)
+	case cli.Filter.Quiet, cli.Filter.Summary, cli.Filter.JSON:
+		// stdout is for the --json summary alone.
+	case tmpl != nil:
+		if b {
+			if err := tmpl.Execute(os.Stdout, c); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
 	default:
-		fmt.Println("Unmatched")
-		os.Exit(1)
+		fmt.Printf("QUERY: %#v\n", cli.Filter.QUERY)
+		fmt.Printf("JSON structure: %#v\n", c)
+		switch {
+		case b:
+			fmt.Println("matched")
+		default:
+			fmt.Println("Unmatched")
+		}
 	}
+	return nil
+}
+
+// loadContexts merges the top-level keys of the named JSON documents, later
+// documents overriding earlier ones.
+func loadContexts(names []string) (matcher.Context, error) {
+	base := matcher.Context{}
+	for _, name := range names {
+		if err := loadContext(name, base); err != nil {
+			return nil, err
+		}
+	}
+	return base, nil
+}
+
+func loadContext(name string, into matcher.Context) error {
+	r, err := openInput(name, cli.Filter.Retries)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	in, err := decompress(r, cli.Filter.Compress)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := newDecoder(in).Decode(&into); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// newDecoder returns a decoder of input documents, which keeps numbers as
+// json.Number unless --float-numbers is given.
+func newDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	if !cli.Filter.FloatNumbers {
+		dec.UseNumber()
+	}
+	return dec
+}
+
+// finish reports err unless quiet and returns code. With --json a summary
+// object is written to stdout instead.
+func finish(code int, st stats, err error) int {
+	if cli.Filter.JSON {
+		result := struct {
+			Matched  bool   `json:"matched"`
+			ExitCode int    `json:"exit_code"`
+			Error    string `json:"error,omitempty"`
+			stats
+		}{Matched: st.Matches > 0, ExitCode: code, stats: st}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		json.NewEncoder(os.Stdout).Encode(result)
+	} else if err != nil && !cli.Filter.Quiet {
+		fmt.Println(err)
+	}
+	return code
+}
+
+// Run filters the inputs and exits with the outcome.
+func (f *filterCmd) Run() error {
+	os.Exit(f.run())
+	return nil
+}
+
+// run filters the inputs and returns the exit code, once plugins are closed.
+func (*filterCmd) run() int {
+	var st stats
+	if cli.Filter.ExpandEnv {
+		q, err := expandEnv(cli.Filter.QUERY, os.LookupEnv)
+		if err != nil {
+			return finish(exitUsageError, st, err)
+		}
+		cli.Filter.QUERY = q
+	}
+	opts := []matcher.Option{matcher.WithSyntaxVersion(cli.Filter.SyntaxVersion)}
+	for _, path := range cli.Filter.Plugin {
+		p, err := plugin.Start(path)
+		if err != nil {
+			return finish(exitUsageError, st, err)
+		}
+		defer p.Close()
+		opts = append(opts, p.Options()...)
+	}
+	m, err := matcher.NewMatcher(cli.Filter.QUERY, opts...)
+	if err != nil {
+		return finish(exitUsageError, st, err)
+	}
+
+	var tmpl *template.Template
+	if cli.Filter.Template != "" {
+		if tmpl, err = template.New("output").Parse(cli.Filter.Template); err != nil {
+			return finish(exitUsageError, st, err)
+		}
+	}
+
+	base, err := loadContexts(cli.Filter.Context)
+	if err != nil {
+		return finish(exitInputError, st, err)
+	}
+
+	var dedup *deduper
+	if cli.Filter.DedupKey != "" {
+		dedup = newDeduper(cli.Filter.DedupSize)
+	}
+
+	inputs := cli.Filter.Input
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	for _, name := range inputs {
+		if err := filterInput(m, tmpl, base, dedup, name, &st); err != nil {
+			code := exitInputError
+			var ee *exitError
+			if errors.As(err, &ee) {
+				code = ee.code
+			}
+			return finish(code, st, err)
+		}
+	}
+
+	if cli.Filter.Summary && !cli.Filter.Quiet && !cli.Filter.JSON {
+		printSummary(os.Stdout, st)
+	}
+	if st.Matches == 0 && !cli.Filter.ExitZeroOnUnmatched {
+		return finish(exitUnmatched, st, nil)
+	}
+	return finish(exitMatched, st, nil)
+}
+
+func main() {
+	ctx := kong.Parse(&cli,
+		kong.Description("Exit codes: 0 matched, 1 unmatched, 2 usage or query error, 3 evaluation error, 4 input error, 5 server error."),
+		kong.Exit(func(code int) {
+			if code != 0 {
+				code = exitUsageError
+			}
+			os.Exit(code)
+		}))
+	ctx.FatalIfErrorf(ctx.Run())
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/mqtt"
+)
+
+// mqttCmd applies a query to the messages of MQTT topics.
+type mqttCmd struct {
+	QUERY     string   `arg:"" help:"QUERY to test the messages against."`
+	Broker    string   `default:"localhost:1883" help:"Broker address."`
+	Topic     []string `required:"" help:"Topic filter to subscribe to, wildcards allowed (repeatable)."`
+	Republish string   `placeholder:"PREFIX" help:"Republish matching messages under PREFIX/<topic> instead of printing them."`
+	ClientID  string   `default:"matcher-cli" help:"MQTT client identifier."`
+	Username  string   `help:"Broker user name."`
+	Password  string   `env:"MQTT_PASSWORD" help:"Broker password."`
+}
+
+// Run filters messages until interrupted.
+func (q *mqttCmd) Run() error {
+	m, err := matcher.NewMatcher(q.QUERY)
+	if err != nil {
+		fail(exitUsageError, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := &mqtt.Client{Addr: q.Broker, ClientID: q.ClientID, Username: q.Username, Password: q.Password}
+	err = c.Watch(ctx, q.Topic, m, func(msg *mqtt.Message) error {
+		if q.Republish != "" {
+			return c.Publish(q.Republish+"/"+msg.Topic, msg.Payload)
+		}
+		fmt.Printf("%s %s\n", msg.Topic, msg.Payload)
+		return nil
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fail(exitInputError, err)
+	}
+	return nil
+}
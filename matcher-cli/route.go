@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/kuwa72/matcher/router"
+)
+
+// routeCmd serves webhooks with the routes of a config file.
+type routeCmd struct {
+	Config  string   `arg:"" help:"JSON file with the routes: {\"routes\": [{\"name\": ..., \"query\": ..., \"priority\": ..., \"destinations\": [URL...]}]}."`
+	Listen  string   `default:":8080" help:"Address to listen on."`
+	Retries int      `default:"3" help:"Retries for failed forwards."`
+	Header  []string `sep:"none" placeholder:"NAME" help:"Request header forwarded besides Content-Type; repeatable."`
+}
+
+// Run serves webhooks until the server fails.
+func (r *routeCmd) Run() error {
+	var cfg struct {
+		Routes []router.Route `json:"routes"`
+	}
+	f, err := os.Open(r.Config)
+	if err != nil {
+		fail(exitInputError, err)
+	}
+	err = json.NewDecoder(f).Decode(&cfg)
+	f.Close()
+	if err != nil {
+		fail(exitInputError, fmt.Errorf("%s: %w", r.Config, err))
+	}
+
+	rt, err := router.New(cfg.Routes)
+	if err != nil {
+		fail(exitUsageError, err)
+	}
+	rt.Retries = r.Retries
+	rt.Headers = r.Header
+
+	log.Printf("routing %d routes on %s", len(cfg.Routes), r.Listen)
+	fail(exitServeError, http.ListenAndServe(r.Listen, rt))
+	return nil
+}
+
+// fail prints err and exits with code.
+func fail(code int, err error) {
+	fmt.Println(err)
+	os.Exit(code)
+}
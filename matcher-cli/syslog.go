@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/syslog"
+)
+
+// syslogCmd receives syslog messages and passes on those matching a query.
+type syslogCmd struct {
+	QUERY   string `arg:"" help:"QUERY to test the messages against."`
+	Listen  string `default:"udp://:5514" help:"Address to receive RFC 5424 messages on, udp://host:port or tcp://host:port."`
+	Forward string `placeholder:"ADDR" help:"Forward matching messages to ADDR, udp://host:port or tcp://host:port, instead of printing them."`
+}
+
+// Run serves until interrupted.
+func (s *syslogCmd) Run() error {
+	m, err := matcher.NewMatcher(s.QUERY)
+	if err != nil {
+		fail(exitUsageError, err)
+	}
+
+	if s.Forward != "" && !strings.HasPrefix(s.Forward, "udp://") && !strings.HasPrefix(s.Forward, "tcp://") {
+		fail(exitUsageError, fmt.Errorf("--forward %s: not udp://host:port or tcp://host:port", s.Forward))
+	}
+	fwd := &syslog.Forwarder{Addr: s.Forward}
+	defer fwd.Close()
+	srv := &syslog.Server{Matcher: m, Handle: func(msg *syslog.Message) {
+		if s.Forward == "" {
+			fmt.Println(string(msg.Raw))
+			return
+		}
+		if err := fwd.Forward(msg); err != nil {
+			log.Printf("forward: %v", err)
+		}
+	}}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := srv.ListenAndServe(ctx, s.Listen); err != nil && !errors.Is(err, context.Canceled) {
+		fail(exitServeError, err)
+	}
+	return nil
+}
@@ -1,28 +1,156 @@
 package matcher
 
 import (
+	"errors"
+	"sync"
+	"time"
+
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/repr"
 )
 
+// Matcher tests contexts against a compiled query.
+//
+// A Matcher is safe for concurrent use by multiple goroutines: Test and the
+// other methods only read the compiled query and never modify the tested
+// context. The exported fields must not be changed while the Matcher is in
+// use.
 type Matcher struct {
+	// Parser is the shared parser the query was parsed with. It is not
+	// used for evaluation.
 	Parser     *participle.Parser
 	Expression *Expression
-	Debug      bool
+	// Debug prints the parsed query to stdout on every Test. Services
+	// should use Trace instead.
+	Debug bool
+
+	adaptive *adaptive
+	budget   *evalBudget
+	audit    *Auditor
+	ruleID   string
+	warnings []Warning
+	results  *resultCache
+	now      func() time.Time // set with WithAutoVariables
+	symsOnce sync.Once
+	syms     []string // keys the query refers to, for TestResolver
 }
 
-func NewMatcher(q string) (*Matcher, error) {
+// NewMatcher parses and compiles the query q.
+func NewMatcher(q string, opts ...Option) (*Matcher, error) {
 	e := &Expression{}
-	parser := NewParser()
-	err := parser.ParseString("", q, e)
-	return &Matcher{Parser: parser,
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	parser, err := parserFor(o.syntaxVersion())
+	if err != nil {
+		return &Matcher{Parser: sharedParser(), Expression: e}, err
+	}
+	err = parser.ParseString("", q, e)
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		err = newParseError(q, perr)
+	}
+	if err != nil {
+		return &Matcher{Parser: parser, Expression: e}, err
+	}
+	return newMatcher(e, opts)
+}
+
+// newMatcher validates and compiles a parsed expression.
+func newMatcher(e *Expression, opts []Option) (*Matcher, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	parser, err := parserFor(o.syntaxVersion())
+	if err != nil {
+		return &Matcher{Parser: sharedParser(), Expression: e}, err
+	}
+	m := &Matcher{Parser: parser,
 		Expression: e,
-		Debug:      false}, err
+		Debug:      false}
+	err = e.checkSyntax(o.syntaxVersion())
+	if err == nil {
+		err = e.validate()
+	}
+	if err == nil {
+		err = e.compile(&o)
+	}
+	m.budget, m.audit, m.ruleID = o.budget, o.audit, o.ruleID
+	if err == nil {
+		m.warnings = e.warnings()
+	}
+	if err == nil && o.adaptiveEvery > 0 && !e.hasCalls() && o.budget == nil && o.audit == nil {
+		m.adaptive = newAdaptive(e, o.adaptiveEvery)
+	}
+	if o.auto {
+		m.now = o.clock()
+	}
+	if o.resultCache > 0 && e.pure() {
+		m.results = newResultCache(o.resultCache, o.resultTTL, o.clock())
+	}
+	return m, err
 }
 
-func (m Matcher) Test(c *Context) (bool, error) {
+// Test reports whether the context matches the query. Test never modifies
+// the context, so a context can be tested by several matchers concurrently
+// as long as nobody writes to it; see Frozen.
+func (m *Matcher) Test(c *Context) (bool, error) {
+	if m.now != nil {
+		return m.testAuto(*c)
+	}
+	return m.test(c)
+}
+
+// testAuto tests c with the automatic variables the query refers to layered
+// under it, like Layer(auto, c), so functions still see every key of c.
+func (m *Matcher) testAuto(c Context) (bool, error) {
+	l := AcquireContext()
+	defer ReleaseContext(l)
+	auto := &autoVars{now: m.now}
+	for _, k := range m.symbols() {
+		if _, ok := c[k]; !ok && autoVariables[k] {
+			l[k], _ = auto.Lookup(k)
+		}
+	}
+	for k, v := range c {
+		l[k] = v
+	}
+	return m.test(&l)
+}
+
+// test evaluates the query against a context holding every key it refers to.
+func (m *Matcher) test(c *Context) (bool, error) {
 	if m.Debug {
 		repr.Println(m.Expression, repr.Indent("  "), repr.OmitEmpty(true))
 	}
-	return m.Expression.Eval(*c)
+	if m.results != nil {
+		return m.results.test(m, *c)
+	}
+	return m.eval(*c)
+}
+
+// eval evaluates the query as configured by the options.
+func (m *Matcher) eval(ctx Context) (bool, error) {
+	if m.audit != nil {
+		return m.audit.eval(m, ctx)
+	}
+	if m.budget != nil {
+		return m.Expression.walk(ctx, m.budget.check(), nil)
+	}
+	if m.adaptive != nil {
+		return m.adaptive.eval(ctx)
+	}
+	return m.Expression.Eval(ctx)
+}
+
+// EvaluationOrder returns the query in the order conditions are currently
+// evaluated. It differs from the query only with WithAdaptiveOrdering.
+func (m *Matcher) EvaluationOrder() string {
+	if m.adaptive != nil {
+		return m.adaptive.String()
+	}
+	return m.Expression.String()
 }
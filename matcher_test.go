@@ -0,0 +1,133 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMatcherSharesParser(t *testing.T) {
+	assert := assert.New(t)
+
+	var wg sync.WaitGroup
+	matchers := make([]*matcher.Matcher, 8)
+	for i := range matchers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := matcher.NewMatcher("a = 1 and b = \"foo\"")
+			assert.NoError(err)
+			matchers[i] = m
+		}(i)
+	}
+	wg.Wait()
+
+	for _, m := range matchers[1:] {
+		assert.Same(matchers[0].Parser, m.Parser)
+		ok, err := m.Test(&matcher.Context{"a": 1.0, "b": "foo"})
+		assert.NoError(err)
+		assert.True(ok)
+	}
+}
+
+func TestNonMatchingTestDoesNotAllocate(t *testing.T) {
+	cases := []struct {
+		query string
+		ctx   matcher.Context
+	}{
+		{"a = 1", matcher.Context{"a": 2.0}},
+		{"a > 1", matcher.Context{"a": "0.5"}},
+		{"a = 1", matcher.Context{"a": "one"}},
+		{"a < 1", matcher.Context{"a": "one"}},
+		{"a = \"foo\"", matcher.Context{"a": "bar"}},
+		{"a = TRUE", matcher.Context{"a": false}},
+		{"a = 1 and b = 2", matcher.Context{"a": 1.0, "b": 3.0}},
+		{"a = 1", matcher.Context{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			m, err := matcher.NewMatcher(c.query)
+			assert.NoError(t, err)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				ok, err := m.Test(&c.ctx)
+				if ok || err != nil {
+					t.Fatalf("unexpected result %v, %v", ok, err)
+				}
+			})
+			assert.Zero(t, allocs)
+		})
+	}
+}
+
+func TestConcurrentTest(t *testing.T) {
+	m, err := matcher.NewMatcher("a > 1 and b = \"x\" or c = 5")
+	assert.NoError(t, err)
+	shared := matcher.Context{"a": 2.0, "b": "x"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ok, err := m.Test(&shared)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+
+				own := matcher.Context{"c": float64(j % 10)}
+				ok, err = m.Test(&own)
+				assert.NoError(t, err)
+				assert.Equal(t, j%10 == 5, ok)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestIntegerContextValues(t *testing.T) {
+	values := []interface{}{
+		int(3), int8(3), int16(3), int32(3), int64(3),
+		uint(3), uint8(3), uint16(3), uint32(3), uint64(3),
+		float32(3), float64(3), json.Number("3"),
+	}
+	cases := []struct {
+		query string
+		match bool
+	}{
+		{"a = 3", true},
+		{"a != 3", false},
+		{"a > 2", true},
+		{"a >= 4", false},
+		{"a < 3.5", true},
+		{"a <= 2", false},
+		{"a = TRUE", true},
+		{"a != TRUE", false},
+	}
+
+	for _, v := range values {
+		for _, c := range cases {
+			t.Run(fmt.Sprintf("%T/%s", v, c.query), func(t *testing.T) {
+				m, err := matcher.NewMatcher(c.query)
+				assert.NoError(t, err)
+
+				ok, err := m.Test(&matcher.Context{"a": v})
+				assert.NoError(t, err)
+				assert.Equal(t, c.match, ok)
+			})
+		}
+	}
+}
+
+func jsonContext(t *testing.T, s string) matcher.Context {
+	ctx := make(matcher.Context)
+	if err := json.Unmarshal([]byte(s), &ctx); err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
@@ -0,0 +1,161 @@
+package matcher
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithResultCache makes Test remember the results of up to size contexts
+// for ttl, or until evicted as the least recently used, so that contexts
+// tested again, for example on retries, are not evaluated again. Contexts
+// are told apart by the values of the keys the query refers to, so contexts
+// only differing in other keys share a result.
+//
+// Only successful results of contexts whose referenced values encode to JSON
+// are cached. Results of queries calling functions other than EXISTS and
+// MISSING are never cached, as such functions may keep state or depend on
+// more than the context. Cache hits are neither audited nor counted against
+// an evaluation budget.
+func WithResultCache(size int, ttl time.Duration) Option {
+	return func(o *options) {
+		o.resultCache, o.resultTTL = size, ttl
+	}
+}
+
+// pure reports whether the result of the expression only depends on the
+// context.
+func (e *Expression) pure() bool {
+	for _, x := range e.Or {
+		if !pureBranch(x) {
+			return false
+		}
+	}
+	return true
+}
+
+type resultEntry struct {
+	key     [sha256.Size]byte
+	result  bool
+	expires time.Time
+}
+
+// resultCache is a least recently used cache of results keyed by the
+// fingerprint of the referenced values.
+type resultCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	order *list.List
+	items map[[sha256.Size]byte]*list.Element
+}
+
+func newResultCache(size int, ttl time.Duration, now func() time.Time) *resultCache {
+	return &resultCache{size: size, ttl: ttl, now: now, order: list.New(),
+		items: make(map[[sha256.Size]byte]*list.Element)}
+}
+
+// test returns the cached result for ctx, or evaluates and caches it.
+func (c *resultCache) test(m *Matcher, ctx Context) (bool, error) {
+	key, ok := fingerprint(m.symbols(), ctx)
+	if !ok {
+		return m.eval(ctx)
+	}
+	now := c.now()
+	c.mu.Lock()
+	if e, ok := c.items[key]; ok {
+		r := e.Value.(*resultEntry)
+		if c.ttl <= 0 || now.Before(r.expires) {
+			c.order.MoveToFront(e)
+			c.mu.Unlock()
+			return r.result, nil
+		}
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+	c.mu.Unlock()
+
+	b, err := m.eval(ctx)
+	if err != nil {
+		return b, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.order.Remove(e)
+	}
+	c.items[key] = c.order.PushFront(&resultEntry{key: key, result: b, expires: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.items, e.Value.(*resultEntry).key)
+	}
+	return b, nil
+}
+
+// fingerprint hashes the values of keys in ctx with their dynamic types, as
+// values of different types with the same JSON form, like 5.0 and
+// time.Duration(5), may compare differently. It reports false if one of the
+// values does not encode to JSON, or is a list or an object holding values
+// other than those JSON decodes to, whose types the hash would not tell
+// apart.
+func fingerprint(keys []string, ctx Context) ([sha256.Size]byte, bool) {
+	h := sha256.New()
+	for _, k := range keys {
+		v, ok := ctx[k]
+		if !ok {
+			h.Write([]byte{0})
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil || !nativeElements(v) {
+			return [sha256.Size]byte{}, false
+		}
+		h.Write([]byte{1})
+		fmt.Fprintf(h, "%T", v)
+		h.Write([]byte{0})
+		h.Write(b)
+		h.Write([]byte{0})
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, true
+}
+
+// nativeElements reports whether the elements of a list or an object, if v
+// is one, are all of the types JSON decodes to.
+func nativeElements(v interface{}) bool {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, e := range x {
+			if !isNative(e) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for _, e := range x {
+			if !isNative(e) {
+				return false
+			}
+		}
+	case Context:
+		return nativeElements(map[string]interface{}(x))
+	}
+	return true
+}
+
+// isNative reports whether v is of a type JSON decodes to.
+func isNative(v interface{}) bool {
+	switch v.(type) {
+	case nil, bool, float64, string, json.Number:
+		return true
+	case []interface{}, map[string]interface{}:
+		return nativeElements(v)
+	}
+	return false
+}
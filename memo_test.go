@@ -0,0 +1,88 @@
+package matcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCache(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	evals := 0
+	total := func(c matcher.Context) interface{} {
+		evals++
+		return c["price"].(float64) * c["qty"].(float64)
+	}
+	m, err := matcher.NewMatcher("total > 100", matcher.WithClock(clock.now), matcher.WithResultCache(2, time.Minute),
+		matcher.WithDerivedField("total", total, "price", "qty"))
+	assert.NoError(err)
+
+	test := func(c matcher.Context) bool {
+		ok, err := m.Test(&c)
+		assert.NoError(err)
+		return ok
+	}
+	assert.True(test(matcher.Context{"price": 60.0, "qty": 2.0}))
+	// Keys the query does not refer to are ignored.
+	assert.True(test(matcher.Context{"price": 60.0, "qty": 2.0, "id": "retry"}))
+	assert.Equal(1, evals)
+
+	assert.False(test(matcher.Context{"price": 10.0, "qty": 2.0}))
+	assert.False(test(matcher.Context{"price": 10.0, "qty": 3.0}))
+	assert.Equal(3, evals)
+	// The first context was evicted.
+	assert.True(test(matcher.Context{"price": 60.0, "qty": 2.0}))
+	assert.Equal(4, evals)
+
+	clock.advance(time.Minute)
+	assert.True(test(matcher.Context{"price": 60.0, "qty": 2.0}))
+	assert.Equal(5, evals)
+	assert.True(test(matcher.Context{"price": 60.0, "qty": 2.0}))
+	assert.Equal(5, evals)
+}
+
+func TestResultCacheStateful(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("COUNT_OVER(1m) > 1", matcher.WithResultCache(10, time.Minute), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	var got []bool
+	for i := 0; i < 3; i++ {
+		ok, err := m.Test(&matcher.Context{})
+		assert.NoError(err)
+		got = append(got, ok)
+	}
+	assert.Equal([]bool{false, true, true}, got)
+}
+
+func TestResultCacheTypes(t *testing.T) {
+	assert := assert.New(t)
+	mismatchFalse := matcher.WithMismatchPolicy(matcher.MismatchFalse)
+	m, err := matcher.NewMatcher("t = 5", matcher.WithResultCache(10, time.Minute), mismatchFalse)
+	assert.NoError(err)
+	for _, c := range []struct {
+		value interface{}
+		want  bool
+	}{
+		{5.0, true},
+		{time.Duration(5), false},
+		{5.0, true},
+		{[]interface{}{5.0}, false},
+		{[]interface{}{time.Duration(5)}, false},
+	} {
+		ok, err := m.Test(&matcher.Context{"t": c.value})
+		assert.NoError(err)
+		assert.Equal(c.want, ok, "%T %v", c.value, c.value)
+	}
+
+	m, err = matcher.NewMatcher("ANY(t) = 5", matcher.WithResultCache(10, time.Minute), mismatchFalse, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	ok, err := m.Test(&matcher.Context{"t": []interface{}{5.0}})
+	assert.NoError(err)
+	assert.True(ok)
+	ok, err = m.Test(&matcher.Context{"t": []interface{}{time.Duration(5)}})
+	assert.NoError(err)
+	assert.False(ok)
+}
@@ -0,0 +1,131 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrTypeMismatch is returned when a context value can not be compared with
+// the literal under MismatchError or WithStrictTypes.
+var ErrTypeMismatch = errors.New("matcher: type mismatch")
+
+// MismatchPolicy decides how a comparison behaves when the context value has
+// a type the literal can not be compared with: a string literal against a
+// number, a number literal ordered against a boolean, a boolean literal
+// against a string that is not a boolean, or any literal against an object
+// or array.
+//
+// Null values and missing keys are not mismatches; see NullPolicy.
+//
+// Without a policy, comparisons fail with ErrTypeMismatch like under
+// MismatchError, except that a string literal is unequal to any value that
+// is not a string, as MismatchFalse has it.
+type MismatchPolicy int
+
+const (
+	// mismatchDefault is the policy when none is set.
+	mismatchDefault MismatchPolicy = iota
+	// MismatchFalse treats values of different types as unequal and
+	// unordered: =, LIKE and the ordering operators are false, <>, != and
+	// NOT LIKE are true.
+	MismatchFalse
+	// MismatchUnknown treats the comparison as UNKNOWN, which does not
+	// match for any operator, including <> and !=.
+	MismatchUnknown
+	// MismatchError makes the comparison fail with ErrTypeMismatch.
+	MismatchError
+)
+
+// WithMismatchPolicy sets how comparisons between incompatible types behave.
+func WithMismatchPolicy(p MismatchPolicy) Option {
+	return func(o *options) {
+		o.mismatch = p
+	}
+}
+
+// WithStrictTypes makes comparisons between values of different types fail
+// with ErrTypeMismatch instead of converting one to the other: by default a
+// number literal equals a numeric string and a boolean literal equals 0 and
+// 1, while with this option they only compare with numbers and booleans.
+// Timestamp and duration literals still compare with the strings holding
+// them, as JSON has no such types, but other strings fail. Every other
+// mismatch fails too, whatever the MismatchPolicy.
+func WithStrictTypes() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// compileStrict wraps the comparison so that context values of another type
+// than the literal fail.
+func (x *Condition) compileStrict() {
+	next := x.test
+	if next == nil {
+		next = x.compare
+	}
+	v := x.Compare.Value
+	x.test = func(ctxVal interface{}) (bool, error) {
+		if ctxVal != nil && !v.Null && !v.accepts(ctxVal) {
+			return false, fmt.Errorf("%w: %s: %T value", ErrTypeMismatch, x, ctxVal)
+		}
+		return next(ctxVal)
+	}
+}
+
+// accepts reports whether a context value has the type of the literal under
+// WithStrictTypes.
+func (v *Value) accepts(ctxVal interface{}) bool {
+	switch ctxVal.(type) {
+	case string:
+		return v.String != nil || v.Time != nil || v.Duration != nil
+	case bool:
+		return v.Boolean != nil
+	case time.Time:
+		return v.Time != nil
+	case time.Duration:
+		return v.Duration != nil
+	case *big.Rat:
+		return v.Float != nil
+	}
+	_, ok := toFloat(ctxVal)
+	return ok && v.Float != nil
+}
+
+// mismatchPolicy returns the policy for incompatible values of the
+// condition.
+func (x *Condition) mismatchPolicy() MismatchPolicy {
+	p := mismatchDefault
+	if x.opts != nil {
+		p = x.opts.mismatch
+		if x.opts.strict {
+			p = MismatchError
+		}
+	}
+	if p != mismatchDefault {
+		return p
+	}
+	if v := x.Compare.Value; v != nil && v.String != nil {
+		switch x.Compare.Operator {
+		case "=", "<>", "!=", "LIKE", "NOT LIKE":
+			return MismatchFalse
+		}
+	}
+	return MismatchError
+}
+
+// mismatch returns the result of comparing an incompatible context value.
+func (x *Condition) mismatch(ctxVal interface{}) (bool, error) {
+	switch x.mismatchPolicy() {
+	case MismatchUnknown:
+		return false, nil
+	case MismatchError:
+		return false, fmt.Errorf("%w: %s: %T value", ErrTypeMismatch, x, ctxVal)
+	}
+	switch x.Compare.Operator {
+	case "<>", "!=", "NOT LIKE":
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,152 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMismatchPolicy(t *testing.T) {
+	cases := []struct {
+		query string
+		json  string
+		// results under MismatchFalse and MismatchUnknown
+		false, unknown bool
+	}{
+		{`a = "x"`, `{"a": 1}`, false, false},
+		{`a <> "x"`, `{"a": 1}`, true, false},
+		{`a != "x"`, `{"a": true}`, true, false},
+		{`a > "x"`, `{"a": 1}`, false, false},
+		{`a <= "x"`, `{"a": {"b": 1}}`, false, false},
+		{`a > 1`, `{"a": true}`, false, false},
+		{`a = 1`, `{"a": [1]}`, false, false},
+		{`a <> 1`, `{"a": [1]}`, true, false},
+		{`a = TRUE`, `{"a": "maybe"}`, false, false},
+		{`a <> TRUE`, `{"a": "maybe"}`, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query+" "+c.json, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := jsonContext(t, c.json)
+
+			for _, p := range []struct {
+				policy matcher.MismatchPolicy
+				match  bool
+			}{{matcher.MismatchFalse, c.false}, {matcher.MismatchUnknown, c.unknown}} {
+				m, err := matcher.NewMatcher(c.query, matcher.WithMismatchPolicy(p.policy))
+				assert.NoError(err)
+				ok, err := m.Test(&ctx)
+				assert.NoError(err)
+				assert.Equal(p.match, ok)
+			}
+
+			m, err := matcher.NewMatcher(c.query, matcher.WithMismatchPolicy(matcher.MismatchError))
+			assert.NoError(err)
+			ok, err := m.Test(&ctx)
+			assert.False(ok)
+			assert.ErrorIs(err, matcher.ErrTypeMismatch)
+		})
+	}
+}
+
+func TestMismatchDefault(t *testing.T) {
+	// A string literal is unequal to other types, as in the original
+	// grammar; every other mismatch fails.
+	for _, c := range []struct {
+		query string
+		match bool
+	}{{`a = "x"`, false}, {`a <> "x"`, true}, {`a != "x"`, true}} {
+		m, err := matcher.NewMatcher(c.query)
+		assert.NoError(t, err)
+		ok, err := m.Test(&matcher.Context{"a": 2.0})
+		assert.NoError(t, err, c.query)
+		assert.Equal(t, c.match, ok, c.query)
+	}
+	for _, c := range []struct {
+		query string
+		value interface{}
+	}{{`a > "x"`, 2.0}, {`a > 1`, true}, {`a = TRUE`, "x"}, {`a = 1`, []interface{}{1.0}}} {
+		m, err := matcher.NewMatcher(c.query)
+		assert.NoError(t, err)
+		_, err = m.Test(&matcher.Context{"a": c.value})
+		assert.ErrorIs(t, err, matcher.ErrTypeMismatch, c.query)
+
+		m, err = matcher.NewMatcher(c.query, matcher.WithMismatchPolicy(matcher.MismatchFalse))
+		assert.NoError(t, err)
+		ok, err := m.Test(&matcher.Context{"a": c.value})
+		assert.NoError(t, err, c.query)
+		assert.False(t, ok, c.query)
+	}
+}
+
+func TestMismatchCompatibleTypes(t *testing.T) {
+	// Coercions between compatible types are not mismatches.
+	for _, c := range []struct{ query, json string }{
+		{`a = TRUE`, `{"a": "true"}`},
+		{`a = TRUE`, `{"a": 1}`},
+		{`a = 0`, `{"a": false}`},
+		{`a = 5`, `{"a": "5"}`},
+		{`a > 5`, `{"a": "x"}`},
+	} {
+		m, err := matcher.NewMatcher(c.query, matcher.WithMismatchPolicy(matcher.MismatchError))
+		assert.NoError(t, err)
+		ctx := jsonContext(t, c.json)
+		ok, err := m.Test(&ctx)
+		assert.NoError(t, err, c.query)
+		assert.True(t, ok, c.query)
+	}
+}
+
+func TestStrictTypes(t *testing.T) {
+	for _, c := range []struct {
+		query, json string
+		want        bool
+		fails       bool
+	}{
+		{`a = 5`, `{"a": 5}`, true, false},
+		{`a = 5`, `{"a": "5"}`, false, true},
+		{`a > 5`, `{"a": "x"}`, false, true},
+		{`a = TRUE`, `{"a": true}`, true, false},
+		{`a = TRUE`, `{"a": 1}`, false, true},
+		{`a = TRUE`, `{"a": "true"}`, false, true},
+		{`a = 0`, `{"a": false}`, false, true},
+		{`a = "x"`, `{"a": 1}`, false, true},
+		{`a != "x"`, `{"a": [1]}`, false, true},
+		{`a = NULL`, `{"a": null}`, true, false},
+		{`a != "x"`, `{"a": null}`, true, false},
+		{`a = "x"`, `{}`, false, false},
+		{`a > 2024-01-01`, `{"a": "2024-05-01T00:00:00Z"}`, true, false},
+		{`a > 2024-01-01`, `{"a": "soon"}`, false, true},
+		{`a > 1m`, `{"a": "90s"}`, true, false},
+		{`a = b`, `{"a": 1, "b": "1"}`, false, true},
+		{`ANY(a) = 1`, `{"a": [1, "1"]}`, true, false},
+		{`ALL(a) = 1`, `{"a": [1, "1"]}`, false, true},
+	} {
+		m, err := matcher.NewMatcher(c.query, matcher.WithStrictTypes(), matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, c.query) {
+			continue
+		}
+		ctx := jsonContext(t, c.json)
+		ok, err := m.Test(&ctx)
+		if c.fails {
+			assert.ErrorIs(t, err, matcher.ErrTypeMismatch, "%s with %s", c.query, c.json)
+		} else {
+			assert.NoError(t, err, "%s with %s", c.query, c.json)
+		}
+		assert.Equal(t, c.want, ok, "%s with %s", c.query, c.json)
+	}
+}
+
+func TestStrictTypesSpecialized(t *testing.T) {
+	for _, opt := range []matcher.Option{matcher.WithFloatEpsilon(0.01), matcher.WithExactDecimals()} {
+		m, err := matcher.NewMatcher(`a = 5`, matcher.WithStrictTypes(), opt)
+		assert.NoError(t, err)
+		_, err = m.Test(&matcher.Context{"a": "5"})
+		assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+		ok, err := m.Test(&matcher.Context{"a": 5})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,338 @@
+// Package mqtt applies matcher queries to MQTT messages, for content based
+// filtering beyond topic wildcards. It speaks just enough MQTT 3.1.1 to
+// subscribe with QoS 0 and to republish messages.
+//
+// A message is tested against a context with
+//
+//   - topic, the topic name, and topic_0, topic_1, ... its levels,
+//   - the top-level fields of a JSON object payload, with characters that
+//     can not appear in a query identifier replaced by underscores and
+//     numbers kept as json.Number,
+//   - any other payload as payload: a JSON value, or the text of a payload
+//     that is not JSON.
+//
+// Payload fields win over the topic keys.
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kuwa72/matcher"
+)
+
+// DefaultKeepAlive is the keep alive interval used when Client.KeepAlive is
+// zero.
+const DefaultKeepAlive = time.Minute
+
+// ErrNotConnected is returned by Publish outside of Watch.
+var ErrNotConnected = errors.New("mqtt: not connected")
+
+// Message is a received MQTT message.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Context returns the context queries are tested against.
+func (msg *Message) Context() matcher.Context {
+	c := matcher.Context{"topic": msg.Topic}
+	for i, level := range strings.Split(msg.Topic, "/") {
+		c["topic_"+strconv.Itoa(i)] = level
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(msg.Payload))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		c["payload"] = string(msg.Payload)
+		return c
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		c["payload"] = v
+		return c
+	}
+	for k, v := range obj {
+		c[key(k)] = v
+	}
+	return c
+}
+
+func key(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Client connects to an MQTT broker.
+type Client struct {
+	// Addr is the broker address, host:port or a tcp:// or mqtt:// URL.
+	Addr     string
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is the interval of keep alive pings; DefaultKeepAlive when
+	// zero.
+	KeepAlive time.Duration
+
+	mu   sync.Mutex // guards conn and writes to it
+	conn net.Conn
+}
+
+// Packet types of MQTT 3.1.1.
+const (
+	connect    = 1
+	connack    = 2
+	publish    = 3
+	puback     = 4
+	subscribe  = 8
+	suback     = 9
+	pingreq    = 12
+	disconnect = 14
+)
+
+// Watch connects to the broker, subscribes to topics, which may contain
+// wildcards, and calls fn for every message matching m until ctx is done or
+// the connection fails. Watch returns the first error of fn or of testing a
+// message.
+func (c *Client) Watch(ctx context.Context, topics []string, m *matcher.Matcher, fn func(*Message) error) error {
+	addr := c.Addr
+	for _, scheme := range []string{"tcp://", "mqtt://"} {
+		addr = strings.TrimPrefix(addr, scheme)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	keepAlive := c.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+
+	if err := writePacket(conn, connect<<4, c.connectBody(keepAlive)); err != nil {
+		return err
+	}
+	typ, body, err := readPacket(r)
+	if err != nil {
+		return err
+	}
+	if typ>>4 != connack || len(body) != 2 {
+		return fmt.Errorf("mqtt: unexpected packet %d instead of CONNACK", typ>>4)
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("mqtt: connection refused with code %d", body[1])
+	}
+
+	var sub bytes.Buffer
+	sub.Write([]byte{0, 1})
+	for _, t := range topics {
+		writeString(&sub, t)
+		sub.WriteByte(0)
+	}
+	if err := writePacket(conn, subscribe<<4|2, sub.Bytes()); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		writePacket(conn, disconnect<<4, nil)
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				c.mu.Lock()
+				writePacket(conn, pingreq<<4, nil)
+				c.mu.Unlock()
+			}
+		}
+	}()
+
+	for {
+		typ, body, err := readPacket(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		switch typ >> 4 {
+		case suback:
+			if len(body) < 2 {
+				return errors.New("mqtt: short SUBACK packet")
+			}
+			for _, code := range body[2:] {
+				if code == 0x80 {
+					return errors.New("mqtt: subscription refused")
+				}
+			}
+		case publish:
+			msg, id, err := parsePublish(typ, body)
+			if err != nil {
+				return err
+			}
+			if id != nil {
+				c.mu.Lock()
+				err = writePacket(conn, puback<<4, id)
+				c.mu.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			ctx := msg.Context()
+			ok, err := m.Test(&ctx)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if err := fn(msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Publish sends a message with QoS 0 over the connection of a running Watch.
+func (c *Client) Publish(topic string, payload []byte) error {
+	var b bytes.Buffer
+	writeString(&b, topic)
+	b.Write(payload)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return writePacket(c.conn, publish<<4, b.Bytes())
+}
+
+// connectBody returns the variable header and payload of CONNECT.
+func (c *Client) connectBody(keepAlive time.Duration) []byte {
+	var b bytes.Buffer
+	writeString(&b, "MQTT")
+	b.WriteByte(4)
+	flags := byte(0x02) // clean session
+	if c.Username != "" {
+		flags |= 0x80
+	}
+	if c.Password != "" {
+		flags |= 0x40
+	}
+	b.WriteByte(flags)
+	binary.Write(&b, binary.BigEndian, uint16(keepAlive/time.Second))
+	writeString(&b, c.ClientID)
+	if c.Username != "" {
+		writeString(&b, c.Username)
+	}
+	if c.Password != "" {
+		writeString(&b, c.Password)
+	}
+	return b.Bytes()
+}
+
+// parsePublish decodes a PUBLISH packet. id is the packet identifier to
+// acknowledge for QoS 1 messages.
+func parsePublish(typ byte, body []byte) (msg *Message, id []byte, err error) {
+	if len(body) < 2 {
+		return nil, nil, errors.New("mqtt: short PUBLISH packet")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return nil, nil, errors.New("mqtt: short PUBLISH packet")
+	}
+	msg = &Message{Topic: string(body[2 : 2+n])}
+	body = body[2+n:]
+	if qos := typ >> 1 & 3; qos > 0 {
+		if len(body) < 2 {
+			return nil, nil, errors.New("mqtt: short PUBLISH packet")
+		}
+		if qos == 1 {
+			id = body[:2]
+		}
+		body = body[2:]
+	}
+	msg.Payload = body
+	return msg, id, nil
+}
+
+// writePacket writes a packet with the fixed header byte typ.
+func writePacket(w io.Writer, typ byte, body []byte) error {
+	b := []byte{typ}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(b, body...))
+	return err
+}
+
+// readPacket reads a packet and returns its fixed header byte and body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(digit&0x7f) << shift
+		if digit&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+	}
+	body := make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	return typ, body, err
+}
+
+// writeString writes a length prefixed UTF-8 string.
+func writeString(b *bytes.Buffer, s string) {
+	binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+}
@@ -0,0 +1,116 @@
+package mqtt_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/mqtt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageContext(t *testing.T) {
+	msg := &mqtt.Message{Topic: "sensors/kitchen/temp", Payload: []byte(`{"value": 21.5, "unit-name": "C"}`)}
+	assert.Equal(t, matcher.Context{
+		"topic":     "sensors/kitchen/temp",
+		"topic_0":   "sensors",
+		"topic_1":   "kitchen",
+		"topic_2":   "temp",
+		"value":     json.Number("21.5"),
+		"unit_name": "C",
+	}, msg.Context())
+
+	msg = &mqtt.Message{Topic: "a", Payload: []byte(`42`)}
+	assert.Equal(t, json.Number("42"), msg.Context()["payload"])
+	msg = &mqtt.Message{Topic: "a", Payload: []byte(`on`)}
+	assert.Equal(t, "on", msg.Context()["payload"])
+}
+
+// readPacket reads an MQTT packet.
+func readPacket(r *bufio.Reader) (byte, []byte) {
+	typ, _ := r.ReadByte()
+	n, shift := 0, 0
+	for {
+		d, _ := r.ReadByte()
+		n |= int(d&0x7f) << shift
+		if d&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	body := make([]byte, n)
+	io.ReadFull(r, body)
+	return typ, body
+}
+
+func publishPacket(topic, payload string) []byte {
+	body := append([]byte{0, byte(len(topic))}, topic...)
+	body = append(body, payload...)
+	return append([]byte{0x30, byte(len(body))}, body...)
+}
+
+// broker accepts a single client, answers its CONNECT and SUBSCRIBE, sends
+// msgs and returns the messages the client published.
+func broker(t *testing.T, ln net.Listener, msgs [][2]string, published chan<- string) {
+	conn, err := ln.Accept()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	typ, body := readPacket(r)
+	assert.Equal(t, byte(0x10), typ)
+	assert.Equal(t, "\x00\x04MQTT\x04", string(body[:7]))
+	conn.Write([]byte{0x20, 2, 0, 0})
+	typ, body = readPacket(r)
+	assert.Equal(t, byte(0x82), typ)
+	assert.Equal(t, "\x00\x01\x00\x09sensors/#\x00", string(body))
+	conn.Write([]byte{0x90, 3, 0, 1, 0})
+	for _, m := range msgs {
+		conn.Write(publishPacket(m[0], m[1]))
+	}
+	for {
+		typ, body = readPacket(r)
+		if typ>>4 != 3 {
+			continue
+		}
+		published <- string(body[2+int(body[1]):])
+		return
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	published := make(chan string, 1)
+	go broker(t, ln, [][2]string{
+		{"sensors/kitchen/temp", `{"value": 19}`},
+		{"sensors/kitchen/temp", `{"value": 31}`},
+		{"sensors/attic/temp", `{"value": 40}`},
+	}, published)
+
+	m, err := matcher.NewMatcher(`topic_2 = "temp" and value > 30`)
+	assert.NoError(t, err)
+	c := &mqtt.Client{Addr: "tcp://" + ln.Addr().String(), ClientID: "test"}
+	assert.ErrorIs(t, c.Publish("x", nil), mqtt.ErrNotConnected)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	err = c.Watch(ctx, []string{"sensors/#"}, m, func(msg *mqtt.Message) error {
+		got = append(got, msg.Topic)
+		if len(got) == 2 {
+			assert.NoError(t, c.Publish("alerts/"+msg.Topic, msg.Payload))
+			assert.Equal(t, `{"value": 40}`, <-published)
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"sensors/kitchen/temp", "sensors/attic/temp"}, got)
+}
@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNaN is returned when a NaN takes part in a comparison under NaNError.
+var ErrNaN = errors.New("matcher: NaN in comparison")
+
+// NaNPolicy decides how number comparisons involving NaN behave, whether the
+// NaN comes from the context or from a NaN literal.
+//
+// Infinities are ordinary numbers under every policy: -Inf is less and +Inf
+// greater than any other number, and each equals itself.
+type NaNPolicy int
+
+const (
+	// NaNIEEE follows IEEE 754, which is what float64 comparison does: NaN
+	// is neither equal to, less than nor greater than any number, itself
+	// included, so only != holds. This is the default.
+	NaNIEEE NaNPolicy = iota
+	// NaNNeverMatches makes every comparison involving NaN false, != too,
+	// like an SQL comparison with an unknown value.
+	NaNNeverMatches
+	// NaNError makes every comparison involving NaN fail with ErrNaN.
+	NaNError
+)
+
+// WithNaNPolicy sets how comparisons involving NaN behave.
+func WithNaNPolicy(p NaNPolicy) Option {
+	return func(o *options) {
+		o.nan = p
+	}
+}
+
+// nanTest wraps the test of a number literal comparison to apply policy to
+// NaN literals and NaN context values.
+func nanTest(policy NaNPolicy, f float64, test func(interface{}) (bool, error)) func(interface{}) (bool, error) {
+	result := func() (bool, error) {
+		if policy == NaNError {
+			return false, ErrNaN
+		}
+		return false, nil
+	}
+	if math.IsNaN(f) {
+		return func(interface{}) (bool, error) {
+			return result()
+		}
+	}
+	return func(ctxVal interface{}) (bool, error) {
+		switch n := ctxVal.(type) {
+		case float64:
+			if math.IsNaN(n) {
+				return result()
+			}
+		case float32:
+			if math.IsNaN(float64(n)) {
+				return result()
+			}
+		}
+		return test(ctxVal)
+	}
+}
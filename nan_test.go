@@ -0,0 +1,112 @@
+package matcher_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNaNPolicies(t *testing.T) {
+	nan := math.NaN()
+	cases := []struct {
+		query  string
+		value  interface{}
+		ieee   bool
+		never  bool
+		errors bool
+	}{
+		{"a = 1", nan, false, false, true},
+		{"a != 1", nan, true, false, true},
+		{"a > 1", nan, false, false, true},
+		{"a <= 1", float32(nan), false, false, true},
+		{"a = NaN", nan, false, false, true},
+		{"a != nan", 1.0, true, false, true},
+		{"a < NAN", 1.0, false, false, true},
+		{"a = 1", 1.0, true, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := matcher.Context{"a": c.value}
+
+			m, err := matcher.NewMatcher(c.query, matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+			ok, err := m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.ieee, ok, "IEEE")
+
+			m, err = matcher.NewMatcher(c.query, matcher.WithNaNPolicy(matcher.NaNNeverMatches), matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+			ok, err = m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.never, ok, "never matches")
+
+			m, err = matcher.NewMatcher(c.query, matcher.WithNaNPolicy(matcher.NaNError), matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+			_, err = m.Test(&ctx)
+			if c.errors {
+				assert.ErrorIs(err, matcher.ErrNaN)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestInfinity(t *testing.T) {
+	cases := []struct {
+		query string
+		value interface{}
+		match bool
+	}{
+		{"a > 1e308", math.Inf(1), true},
+		{"a < -1e308", math.Inf(-1), true},
+		{"a = Inf", math.Inf(1), true},
+		{"a = +inf", math.Inf(1), true},
+		{"a = -Inf", math.Inf(-1), true},
+		{"a < Inf", 1.0, true},
+		{"a > -INF", -1e308, true},
+		{"info = 1", 1.0, false}, // identifiers starting with inf are not literals
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			m, err := matcher.NewMatcher(c.query, matcher.WithNaNPolicy(matcher.NaNError), matcher.WithSyntaxVersion(2))
+			assert.NoError(t, err)
+
+			ok, err := m.Test(&matcher.Context{"a": c.value})
+			assert.NoError(t, err)
+			assert.Equal(t, c.match, ok)
+		})
+	}
+}
+
+func TestNaNInfFieldNames(t *testing.T) {
+	cases := []struct {
+		query string
+		match bool
+	}{
+		{"nan = 1", true},
+		{"inf = 2", true},
+		{"NaN = 1 AND Inf = 2", true},
+		{"EXISTS(nan)", true},
+		{"a.inf = 3", true},
+		{"a = inf", true},
+		{"nan = Inf", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			m, err := matcher.NewMatcher(c.query, matcher.WithSyntaxVersion(2))
+			assert.NoError(t, err)
+
+			ok, err := m.Test(&matcher.Context{"nan": 1.0, "inf": 2.0, "NaN": 1.0, "Inf": 2.0,
+				"a": math.Inf(1), "a.inf": 3.0})
+			assert.NoError(t, err)
+			assert.Equal(t, c.match, ok)
+		})
+	}
+}
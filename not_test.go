@@ -0,0 +1,86 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNot(t *testing.T) {
+	tests := []struct {
+		query string
+		ctx   matcher.Context
+		want  bool
+	}{
+		{`NOT a = 1`, matcher.Context{"a": 1.0}, false},
+		{`NOT a = 1`, matcher.Context{"a": 2.0}, true},
+		{`NOT a = 1`, matcher.Context{}, true},
+		{`not a = 1 and b = 2`, matcher.Context{"a": 2.0, "b": 2.0}, true},
+		{`NOT (a = 1 AND b = 2)`, matcher.Context{"a": 1.0, "b": 2.0}, false},
+		{`NOT (a = 1 AND b = 2)`, matcher.Context{"a": 1.0, "b": 3.0}, true},
+		{`NOT (a = 1 OR b = 2) AND c = 3`, matcher.Context{"a": 0.0, "b": 0.0, "c": 3.0}, true},
+		{`NOT (a = 1 OR b = 2) AND c = 3`, matcher.Context{"a": 0.0, "b": 2.0, "c": 3.0}, false},
+		{`(a = 1 OR b = 2) AND c = 3`, matcher.Context{"b": 2.0, "c": 3.0}, true},
+		{`(a = 1 OR b = 2) AND c = 3`, matcher.Context{"a": 1.0}, false},
+		{`NOT (NOT a = 1)`, matcher.Context{"a": 1.0}, true},
+		{`NOT EXISTS(a)`, matcher.Context{}, true},
+		{`NOT name LIKE "J%"`, matcher.Context{"name": "Mary"}, true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&tt.ctx)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %v", tt.query, tt.ctx)
+	}
+}
+
+func TestNotString(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`not (a = 1 and (b = 2 or c = 3)) or d = 4`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`NOT (a = 1 AND (b = 2 OR c = 3)) OR d = 4`, m.Expression.String())
+
+	c := m.Expression.Complexity()
+	assert.Equal(4, c.Predicates)
+	assert.Equal(4, c.Depth)
+
+	rows := m.Expression.Rows()
+	assert.Equal([]matcher.Row{
+		{Group: 0, Not: true, Query: `a = 1 AND (b = 2 OR c = 3)`},
+		{Group: 1, Field: "d", Operator: "=", Type: "number", Value: 4.0},
+	}, rows)
+	e, err := matcher.FromRows(rows)
+	assert.NoError(err)
+	assert.Equal(m.Expression.String(), e.String())
+}
+
+func TestNotInvalid(t *testing.T) {
+	assert := assert.New(t)
+	_, err := matcher.NewMatcher(`(a = 1) = 2`)
+	assert.Error(err)
+	_, err = matcher.NewMatcher(`NOT (a = 1`)
+	assert.Error(err)
+	_, err = matcher.NewMatcher(`(a = 1 OR b = 2)`, matcher.WithSyntaxVersion(1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+	_, err = matcher.NewMatcher(`(a > TRUE)`, matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+}
+
+func TestNotBudget(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`NOT (a = 1 OR b = 2 OR c = 3)`, matcher.WithEvalBudget(2, 0), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	_, err = m.Test(&matcher.Context{})
+	assert.ErrorIs(err, matcher.ErrBudgetExceeded)
+
+	m, err = matcher.NewMatcher(`NOT (a = 1 OR b = 2 OR c = 3)`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	reports := m.WhyNot(&matcher.Context{"b": 2.0})
+	if assert.Len(reports, 1) {
+		assert.Equal(`NOT (a = 1 OR b = 2 OR c = 3)`, reports[0].Failures[0].Predicate)
+	}
+}
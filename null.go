@@ -0,0 +1,30 @@
+package matcher
+
+import "errors"
+
+// ErrNullOrdering is returned when NULL is ordered under NullError.
+var ErrNullOrdering = errors.New("matcher: NULL can not be ordered")
+
+// NullPolicy decides how ordering comparisons (>, >=, <, <=) behave when the
+// context value is null or the literal is NULL.
+//
+// Equality is not affected: a null value equals NULL and is unequal to every
+// other literal. A key that is missing from the context is different from a
+// null value; conditions on missing keys are always false.
+type NullPolicy int
+
+const (
+	// NullUnknown treats ordering comparisons with NULL as UNKNOWN, which
+	// does not match. This is the default.
+	NullUnknown NullPolicy = iota
+	// NullError makes ordering comparisons with NULL fail with
+	// ErrNullOrdering.
+	NullError
+)
+
+// WithNullPolicy sets how ordering comparisons with NULL behave.
+func WithNullPolicy(p NullPolicy) Option {
+	return func(o *options) {
+		o.null = p
+	}
+}
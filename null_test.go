@@ -0,0 +1,81 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNull(t *testing.T) {
+	cases := []struct {
+		query string
+		json  string
+		match bool
+	}{
+		{"a = NULL", `{"a": null}`, true},
+		{"a = null", `{"a": null}`, true},
+		{"a = NULL", `{"a": 0}`, false},
+		{"a = NULL", `{}`, false},
+		{"a != NULL", `{"a": null}`, false},
+		{"a != NULL", `{"a": ""}`, true},
+		{"a <> NULL", `{"a": false}`, true},
+		{"a != NULL", `{}`, false},
+
+		{"a = 1", `{"a": null}`, false},
+		{"a = \"x\"", `{"a": null}`, false},
+		{"a = TRUE", `{"a": null}`, false},
+		{"a != 1", `{"a": null}`, true},
+		{"a != \"x\"", `{"a": null}`, true},
+		{"a != FALSE", `{"a": null}`, true},
+		{"a > 1", `{"a": null}`, false},
+		{"a <= \"x\"", `{"a": null}`, false},
+		{"a > NULL", `{"a": 1}`, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query+" "+c.json, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := matcher.NewMatcher(c.query, matcher.WithSyntaxVersion(2))
+			assert.NoError(err)
+
+			ctx := jsonContext(t, c.json)
+			ok, err := m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.match, ok)
+		})
+	}
+}
+
+func TestNullErrorPolicy(t *testing.T) {
+	for _, q := range []string{"a > 1", "a <= \"x\"", "a >= NULL"} {
+		m, err := matcher.NewMatcher(q, matcher.WithNullPolicy(matcher.NullError))
+		assert.NoError(t, err)
+
+		_, err = m.Test(&matcher.Context{"a": nil})
+		assert.ErrorIs(t, err, matcher.ErrNullOrdering, q)
+	}
+
+	m, err := matcher.NewMatcher("a = NULL", matcher.WithNullPolicy(matcher.NullError))
+	assert.NoError(t, err)
+	ok, err := m.Test(&matcher.Context{"a": nil})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestKeywordPrefixedIdentifiers(t *testing.T) {
+	m, err := matcher.NewMatcher("order_id = 1 and android = TRUE or nullable = FALSE and true_count = 2", matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+
+	ok, err := m.Test(&matcher.Context{"order_id": 1.0, "android": true})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = m.Test(&matcher.Context{"nullable": false, "true_count": 2.0})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = m.Test(&matcher.Context{"nullable": true, "true_count": 2.0})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,119 @@
+package matcher
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// toFloat converts a numeric context value to float64. It is the one place
+// context numbers are normalized: every Go integer and float type, types
+// defined on them like `type Score int`, and json.Number are numbers, while
+// time.Duration and anything else is not. It never panics.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case nil, string, bool, time.Duration:
+		return 0, false
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// maxExact is the magnitude from which float64 no longer holds every
+// integer.
+const maxExact = 1 << 53
+
+// source returns the text a number literal was written as, or "" if it was
+// not parsed from a query.
+func (v *Value) source() string {
+	if v.Float == nil || len(v.Tokens) != 1 {
+		return ""
+	}
+	return v.Tokens[0].Value
+}
+
+// integer returns the value of an integer literal too large for float64 to
+// hold exactly, or nil.
+func (v *Value) integer() *big.Int {
+	n, ok := new(big.Int).SetString(v.source(), 10)
+	if !ok || n.IsInt64() && n.Int64() > -maxExact && n.Int64() < maxExact {
+		return nil
+	}
+	return n
+}
+
+// compileInteger wraps the comparison with a large integer literal so that
+// integer context values, including json.Number values decoded with
+// json.Decoder.UseNumber, compare exactly: `id = 9007199254740993` does not
+// match 9007199254740992, which is the same float64.
+func (x *Condition) compileInteger() {
+	lit := x.Compare.Value.integer()
+	if lit == nil {
+		return
+	}
+	next := x.test
+	if next == nil {
+		next = x.compare
+	}
+	op := x.Compare.Operator
+	x.test = func(ctxVal interface{}) (bool, error) {
+		n, ok := toInteger(ctxVal)
+		if !ok {
+			return next(ctxVal)
+		}
+		return holds(op, n.Cmp(lit)), nil
+	}
+}
+
+// toInteger converts the context values that may hold integers float64 can
+// not, int64, uint64 and integer json.Number values, to big.Int.
+func toInteger(v interface{}) (*big.Int, bool) {
+	switch n := v.(type) {
+	case int:
+		return big.NewInt(int64(n)), true
+	case int64:
+		return big.NewInt(n), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint64:
+		return new(big.Int).SetUint64(n), true
+	case json.Number:
+		return new(big.Int).SetString(string(n), 10)
+	}
+	return nil, false
+}
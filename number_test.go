@@ -0,0 +1,107 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+type score int
+
+type ratio float32
+
+func TestNumberTypes(t *testing.T) {
+	tests := []struct {
+		query string
+		value interface{}
+		want  bool
+	}{
+		{`a = 5`, 5, true},
+		{`a = 5`, int8(5), true},
+		{`a = 5`, int64(5), true},
+		{`a = 5`, uint16(5), true},
+		{`a > 4`, uint64(5), true},
+		{`a = 5`, float32(5), true},
+		{`a = 5`, json.Number("5"), true},
+		{`a = 5`, json.Number("5.0"), true},
+		{`a < 0`, int64(-9), true},
+		{`a = 5`, score(5), true},
+		{`a >= 0.5`, ratio(0.5), true},
+		{`a = 5`, uintptr(5), true},
+		{`a = TRUE`, int32(1), true},
+		{`a = TRUE`, score(0), false},
+		{`a != FALSE`, json.Number("2"), true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query)
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&matcher.Context{"a": tt.value})
+		assert.NoError(t, err, "%s with %T", tt.query, tt.value)
+		assert.Equal(t, tt.want, ok, "%s with %T %v", tt.query, tt.value, tt.value)
+	}
+
+	// Other values are not numbers.
+	m, err := matcher.NewMatcher(`a = 5`)
+	assert.NoError(t, err)
+	for _, v := range []interface{}{json.Number("x"), 5 * time.Nanosecond, []int{5}, &struct{}{}} {
+		_, err := m.Test(&matcher.Context{"a": v})
+		assert.ErrorIs(t, err, matcher.ErrTypeMismatch, "%T", v)
+	}
+}
+
+func TestNumberTypesDataset(t *testing.T) {
+	records := []matcher.Context{{"a": 1}, {"a": int64(2)}, {"a": score(3)}, {"a": json.Number("4")}, {"a": 5.0}}
+	d := matcher.NewDataset(records)
+	d.Index("a")
+	for _, q := range []string{`a = 2`, `a > 2`, `a <= 3`, `a = 4`} {
+		m, err := matcher.NewMatcher(q)
+		assert.NoError(t, err)
+		got, err := d.Filter(m)
+		assert.NoError(t, err)
+		want, err := matcher.NewDataset(records).Filter(m)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, q)
+		assert.NotEmpty(t, got, q)
+	}
+}
+
+func TestLargeIntegers(t *testing.T) {
+	tests := []struct {
+		query string
+		value interface{}
+		want  bool
+	}{
+		{`id = 9007199254740993`, json.Number("9007199254740993"), true},
+		{`id = 9007199254740993`, json.Number("9007199254740992"), false},
+		{`id = 9007199254740993`, 9007199254740992.0, true},
+		{`id > 9007199254740992`, int64(9007199254740993), true},
+		{`id != 18446744073709551615`, uint64(18446744073709551614), true},
+		{`id < -9007199254740993`, json.Number("-9007199254740994"), true},
+		{`id = 9007199254740993`, "9007199254740993", true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query)
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&matcher.Context{"id": tt.value})
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %T %v", tt.query, tt.value, tt.value)
+	}
+
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`id = 9007199254740993 AND n = 1.5`)
+	assert.NoError(err)
+	assert.Equal(`id = 9007199254740993 AND n = 1.5`, m.Expression.String())
+
+	m, err = matcher.NewMatcher(`amount = 0.1000000000000000001`, matcher.WithExactDecimals())
+	assert.NoError(err)
+	ok, err := m.Test(&matcher.Context{"amount": json.Number("0.1")})
+	assert.NoError(err)
+	assert.False(ok)
+}
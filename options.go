@@ -0,0 +1,38 @@
+package matcher
+
+import (
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Option configures a Matcher created by NewMatcher.
+type Option func(*options)
+
+// options holds the settings applied by Option values.
+type options struct {
+	adaptiveEvery int
+	nan           NaNPolicy
+	null          NullPolicy
+	mismatch      MismatchPolicy
+	strict        bool
+	epsilon       float64
+	normalize     bool
+	form          norm.Form
+	collator      *collatorPool
+	funcs         map[string]factory
+	salt          string
+	now           func() time.Time
+	store         StateStore
+	auto          bool
+	derived       map[string]derived
+	exact         bool
+	budget        *evalBudget
+	audit         *Auditor
+	ruleID        string
+	matcher       uint64 // numbers the matcher for state keys
+	calls         int    // function calls compiled so far
+	syntax        int
+	resultCache   int
+	resultTTL     time.Duration
+}
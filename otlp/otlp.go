@@ -0,0 +1,228 @@
+// Package otlp filters OpenTelemetry data in the OTLP/JSON encoding with
+// matcher queries, like the filter processor of the OpenTelemetry collector.
+//
+// Every span, log record and metric is tested against a context built from
+// the item itself, its instrumentation scope and its resource:
+//
+//   - item attributes under their own name,
+//   - resource attributes prefixed with resource_ and scope attributes with
+//     scope_,
+//   - scope_name and scope_version,
+//   - the fields listed for Traces, Logs and Metrics.
+//
+// Characters of attribute names that can not appear in a query identifier,
+// such as the dots of service.name, are replaced by underscores, so the
+// service name is queried as resource_service_name. The fields win over
+// attributes with the same name.
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kuwa72/matcher"
+)
+
+// Filter keeps the items matching its query, or drops them if Drop is set.
+// Resources and scopes left without items are removed. Unknown fields of the
+// input are kept as they are.
+type Filter struct {
+	Matcher *matcher.Matcher
+	Drop    bool
+}
+
+// Traces filters the spans of an ExportTraceServiceRequest. Besides the
+// attributes, a span has the fields name, kind, trace_id, span_id,
+// parent_span_id, duration_ms, status_code and status_message.
+func (f *Filter) Traces(body []byte) ([]byte, error) {
+	return f.filter(body, "resourceSpans", "scopeSpans", "spans", spanFields)
+}
+
+// Logs filters the log records of an ExportLogsServiceRequest. Besides the
+// attributes, a log record has the fields body, severity_number,
+// severity_text, trace_id and span_id.
+func (f *Filter) Logs(body []byte) ([]byte, error) {
+	return f.filter(body, "resourceLogs", "scopeLogs", "logRecords", logFields)
+}
+
+// Metrics filters the metrics of an ExportMetricsServiceRequest. A metric
+// has the fields name, description and unit; data points are not looked at.
+func (f *Filter) Metrics(body []byte) ([]byte, error) {
+	return f.filter(body, "resourceMetrics", "scopeMetrics", "metrics", metricFields)
+}
+
+type object = map[string]interface{}
+
+func (f *Filter) filter(body []byte, resourcesKey, scopesKey, itemsKey string, fields func(object, matcher.Context)) ([]byte, error) {
+	var req object
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		return nil, err
+	}
+
+	resources := []interface{}{}
+	for _, r := range list(req[resourcesKey]) {
+		resource, _ := r.(object)
+		base := matcher.Context{}
+		if res, ok := resource["resource"].(object); ok {
+			addAttributes(base, "resource_", res["attributes"])
+		}
+
+		scopes := []interface{}{}
+		for _, s := range list(resource[scopesKey]) {
+			scope, _ := s.(object)
+			scopeBase := matcher.Context{}
+			for k, v := range base {
+				scopeBase[k] = v
+			}
+			if sc, ok := scope["scope"].(object); ok {
+				addAttributes(scopeBase, "scope_", sc["attributes"])
+				set(scopeBase, "scope_name", sc["name"])
+				set(scopeBase, "scope_version", sc["version"])
+			}
+
+			items := []interface{}{}
+			for _, i := range list(scope[itemsKey]) {
+				item, _ := i.(object)
+				c := matcher.Context{}
+				for k, v := range scopeBase {
+					c[k] = v
+				}
+				addAttributes(c, "", item["attributes"])
+				fields(item, c)
+
+				ok, err := f.Matcher.Test(&c)
+				if err != nil {
+					return nil, err
+				}
+				if ok != f.Drop {
+					items = append(items, item)
+				}
+			}
+			if len(items) > 0 {
+				scope[itemsKey] = items
+				scopes = append(scopes, scope)
+			}
+		}
+		if len(scopes) > 0 {
+			resource[scopesKey] = scopes
+			resources = append(resources, resource)
+		}
+	}
+	req[resourcesKey] = resources
+	return json.Marshal(req)
+}
+
+func spanFields(span object, c matcher.Context) {
+	set(c, "name", span["name"])
+	set(c, "kind", span["kind"])
+	set(c, "trace_id", span["traceId"])
+	set(c, "span_id", span["spanId"])
+	set(c, "parent_span_id", span["parentSpanId"])
+	start, okStart := nanos(span["startTimeUnixNano"])
+	end, okEnd := nanos(span["endTimeUnixNano"])
+	if okStart && okEnd {
+		set(c, "duration_ms", float64(end-start)/1e6)
+	}
+	if status, ok := span["status"].(object); ok {
+		set(c, "status_code", status["code"])
+		set(c, "status_message", status["message"])
+	}
+}
+
+func logFields(record object, c matcher.Context) {
+	set(c, "body", anyValue(record["body"]))
+	set(c, "severity_number", record["severityNumber"])
+	set(c, "severity_text", record["severityText"])
+	set(c, "trace_id", record["traceId"])
+	set(c, "span_id", record["spanId"])
+}
+
+func metricFields(metric object, c matcher.Context) {
+	set(c, "name", metric["name"])
+	set(c, "description", metric["description"])
+	set(c, "unit", metric["unit"])
+}
+
+// addAttributes adds a list of OTLP KeyValues to c.
+func addAttributes(c matcher.Context, prefix string, attrs interface{}) {
+	for _, a := range list(attrs) {
+		kv, _ := a.(object)
+		key, _ := kv["key"].(string)
+		if key == "" {
+			continue
+		}
+		c[prefix+Key(key)] = anyValue(kv["value"])
+	}
+}
+
+// Key returns the context key of an attribute name, replacing characters
+// that can not appear in a query identifier with underscores.
+func Key(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// anyValue converts an OTLP AnyValue to a context value. 64-bit integers,
+// which OTLP/JSON encodes as strings, become json.Number.
+func anyValue(v interface{}) interface{} {
+	av, ok := v.(object)
+	if !ok {
+		return nil
+	}
+	switch {
+	case av["stringValue"] != nil:
+		return av["stringValue"]
+	case av["boolValue"] != nil:
+		return av["boolValue"]
+	case av["intValue"] != nil:
+		return json.Number(fmt.Sprint(av["intValue"]))
+	case av["doubleValue"] != nil:
+		return av["doubleValue"]
+	case av["bytesValue"] != nil:
+		return av["bytesValue"]
+	case av["arrayValue"] != nil:
+		arr, _ := av["arrayValue"].(object)
+		var out []interface{}
+		for _, e := range list(arr["values"]) {
+			out = append(out, anyValue(e))
+		}
+		return out
+	case av["kvlistValue"] != nil:
+		kvs, _ := av["kvlistValue"].(object)
+		out := map[string]interface{}{}
+		for _, e := range list(kvs["values"]) {
+			kv, _ := e.(object)
+			key, _ := kv["key"].(string)
+			out[key] = anyValue(kv["value"])
+		}
+		return out
+	}
+	return nil
+}
+
+// nanos parses a fixed64 timestamp, which OTLP/JSON encodes as a string.
+func nanos(v interface{}) (int64, bool) {
+	n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+	return n, err == nil
+}
+
+// set adds v to c unless the field was absent.
+func set(c matcher.Context, key string, v interface{}) {
+	if v != nil {
+		c[key] = v
+	}
+}
+
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
@@ -0,0 +1,118 @@
+package otlp_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/otlp"
+	"github.com/stretchr/testify/assert"
+)
+
+const traces = `{"resourceSpans": [
+  {"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}]},
+   "scopeSpans": [{"scope": {"name": "http", "version": "1.0"}, "spans": [
+     {"traceId": "t1", "spanId": "s1", "name": "GET /", "kind": 2,
+      "startTimeUnixNano": "1000000000", "endTimeUnixNano": "1250000000",
+      "attributes": [{"key": "http.status_code", "value": {"intValue": "500"}}],
+      "status": {"code": 2}},
+     {"traceId": "t2", "spanId": "s2", "name": "GET /health", "kind": 2,
+      "startTimeUnixNano": "1000000000", "endTimeUnixNano": "1001000000",
+      "attributes": [{"key": "http.status_code", "value": {"intValue": "200"}}]}
+   ]}]},
+  {"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "search"}}]},
+   "scopeSpans": [{"scope": {"name": "http"}, "spans": [
+     {"traceId": "t3", "spanId": "s3", "name": "GET /q",
+      "attributes": [{"key": "http.status_code", "value": {"intValue": "503"}}]}
+   ]}]}
+]}`
+
+// spanIDs returns the span ids of an ExportTraceServiceRequest.
+func spanIDs(t *testing.T, body []byte) []string {
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					SpanID string `json:"spanId"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &req))
+	ids := []string{}
+	for _, r := range req.ResourceSpans {
+		for _, s := range r.ScopeSpans {
+			for _, span := range s.Spans {
+				ids = append(ids, span.SpanID)
+			}
+		}
+	}
+	return ids
+}
+
+func TestTraces(t *testing.T) {
+	cases := []struct {
+		query string
+		drop  bool
+		ids   []string
+	}{
+		{`http_status_code >= 500`, false, []string{"s1", "s3"}},
+		{`resource_service_name = "checkout" and duration_ms > 100`, false, []string{"s1"}},
+		{`scope_name = "http" and scope_version = "1.0"`, false, []string{"s1", "s2"}},
+		{`status_code = 2 or name = "GET /q"`, false, []string{"s1", "s3"}},
+		{`name = "GET /health"`, true, []string{"s1", "s3"}},
+		{`kind = 1`, false, []string{}},
+	}
+	for _, c := range cases {
+		m, err := matcher.NewMatcher(c.query)
+		assert.NoError(t, err)
+		f := &otlp.Filter{Matcher: m, Drop: c.drop}
+		out, err := f.Traces([]byte(traces))
+		assert.NoError(t, err, c.query)
+		assert.Equal(t, c.ids, spanIDs(t, out), c.query)
+	}
+}
+
+func TestTracesDropEmptyResources(t *testing.T) {
+	m, err := matcher.NewMatcher(`resource_service_name = "search"`)
+	assert.NoError(t, err)
+	out, err := (&otlp.Filter{Matcher: m}).Traces([]byte(traces))
+	assert.NoError(t, err)
+
+	var req map[string][]json.RawMessage
+	assert.NoError(t, json.Unmarshal(out, &req))
+	assert.Len(t, req["resourceSpans"], 1)
+}
+
+func TestLogs(t *testing.T) {
+	logs := `{"resourceLogs": [{"resource": {}, "scopeLogs": [{"logRecords": [
+	  {"severityText": "ERROR", "severityNumber": 17, "body": {"stringValue": "boom"},
+	   "attributes": [{"key": "user.id", "value": {"intValue": "42"}}]},
+	  {"severityText": "INFO", "severityNumber": 9, "body": {"stringValue": "ok"}}
+	]}]}]}`
+	m, err := matcher.NewMatcher(`severity_number >= 17 and body = "boom" and user_id = 42`)
+	assert.NoError(t, err)
+	out, err := (&otlp.Filter{Matcher: m}).Logs([]byte(logs))
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `"boom"`)
+	assert.NotContains(t, string(out), `"ok"`)
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [
+	  {"name": "http.server.duration", "unit": "ms", "histogram": {"dataPoints": [{"count": "3"}]}},
+	  {"name": "runtime.gc.count", "sum": {"dataPoints": [{"asInt": "1"}]}}
+	]}]}]}`
+	m, err := matcher.NewMatcher(`unit = "ms"`)
+	assert.NoError(t, err)
+	out, err := (&otlp.Filter{Matcher: m}).Metrics([]byte(metrics))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [
+	  {"name": "http.server.duration", "unit": "ms", "histogram": {"dataPoints": [{"count": "3"}]}}
+	]}]}]}`, string(out))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "http_status_code", otlp.Key("http.status_code"))
+	assert.Equal(t, "k8s_pod_name", otlp.Key("k8s.pod-name"))
+}
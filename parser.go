@@ -3,6 +3,9 @@ package matcher
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
@@ -13,12 +16,12 @@ type Boolean bool
 type Context map[string]interface{}
 
 func (b *Boolean) Capture(values []string) error {
-	*b = values[0] == "TRUE"
+	*b = Boolean(strings.EqualFold(values[0], "TRUE"))
 	return nil
 }
 
 type Expression struct {
-	Or []*OrCondition `@@ ( "OR" @@ )*`
+	Or []*OrCondition `parser:"@@ ( 'OR' @@ )*"`
 }
 
 func (e *Expression) Eval(ctx Context) (bool, error) {
@@ -32,8 +35,36 @@ func (e *Expression) Eval(ctx Context) (bool, error) {
 	return false, nil
 }
 
+// compile prepares every condition of the expression for evaluation.
+func (e *Expression) compile(o *options) error {
+	for _, x := range e.Or {
+		for _, c := range x.And {
+			if err := c.compile(o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// leaves returns the conditions of the expression that are not groups,
+// those of groups included, in query order.
+func (e *Expression) leaves() []*Condition {
+	var out []*Condition
+	for _, x := range e.Or {
+		for _, c := range x.And {
+			if c.Group != nil {
+				out = append(out, c.Group.leaves()...)
+			} else {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
 type OrCondition struct {
-	And []*Condition `@@ ( "AND" @@ )*`
+	And []*Condition `parser:"@@ ( 'AND' @@ )*"`
 }
 
 func (e *OrCondition) Eval(ctx Context) (bool, error) {
@@ -48,15 +79,254 @@ func (e *OrCondition) Eval(ctx Context) (bool, error) {
 }
 
 type Condition struct {
-	Symbol  string   `@Ident`
-	Compare *Compare `@@`
+	// Not negates the result of the condition.
+	Not bool `parser:"@'NOT':Keyword?"`
+	// Group is a parenthesized expression, which has no Call, Symbol or
+	// Compare.
+	Group   *Expression `parser:"( '(' @@ ')'"`
+	Call    *Call       `parser:"| ( @@"`
+	Symbol  string      `parser:"    | @( (Ident | NonFinite) ( '.' (Ident | NonFinite) )* ) )"`
+	Compare *Compare    `parser:"  @@? )"`
+
+	// fn evaluates Call, or computes the derived field Symbol if the
+	// context does not have it, set by compile.
+	fn Func
+	// inputs are the keys the derived field Symbol is computed from.
+	inputs []string
+	// path is Symbol split at its dots, if it has any, set by compile.
+	path []string
+	// quantified is set by compile for ANY and ALL, whose comparison
+	// applies to the elements of a list.
+	quantified bool
+	// test is the comparison specialized for the operator and literal type,
+	// set by compile.
+	test func(ctxVal interface{}) (bool, error)
+	// opts are the options the condition was compiled with, nil for the
+	// defaults.
+	opts *options
 }
 
+// Eval evaluates the condition against ctx. A panic during the evaluation is
+// recovered and returned as an *InternalError.
 func (x *Condition) Eval(ctx Context) (bool, error) {
-	sym := x.Symbol
-	ctxVal, ok := ctx[sym]
-	if !ok {
-		return false, nil
+	_, _, b, err := x.eval(ctx)
+	return b, err
+}
+
+// eval evaluates the condition like Eval and also returns the value it
+// tested, the context value or the result of the call, and whether there was
+// one. A group tests no value.
+func (x *Condition) eval(ctx Context) (ctxVal interface{}, found bool, b bool, err error) {
+	if x.Group != nil {
+		b, err = x.Group.Eval(ctx)
+	} else {
+		ctxVal, found, b, err = x.evalValue(ctx)
+	}
+	if x.Not && err == nil {
+		b = !b
+	}
+	return ctxVal, found, b, err
+}
+
+// evalValue evaluates a condition that is not a group, ignoring Not.
+func (x *Condition) evalValue(ctx Context) (ctxVal interface{}, found bool, b bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, err = false, &InternalError{Predicate: x.String(), Panic: r}
+		}
+	}()
+
+	if x.Call != nil {
+		if ctxVal, err = x.fn(ctx); err != nil {
+			return nil, false, false, err
+		}
+		if ctxVal == absent {
+			return nil, false, false, nil
+		}
+		if x.Compare == nil {
+			b, err = x.predicate(ctxVal)
+			return ctxVal, true, b, err
+		}
+	} else if ctxVal, found = x.value(ctx); !found {
+		if x.fn == nil {
+			return nil, false, false, nil
+		}
+		if ctxVal, err = x.fn(ctx); err != nil {
+			return nil, false, false, err
+		}
+	}
+	c := x
+	if x.Compare.Field != "" {
+		if c, b, err = x.against(ctx); c == nil {
+			return ctxVal, true, b, err
+		}
+	}
+	if x.quantified {
+		b, err = c.quantify(ctxVal)
+		return ctxVal, ctxVal != nil, b, err
+	}
+	if ctxVal, err = x.decodeRaw(ctxVal); err != nil {
+		return nil, true, false, err
+	}
+	if c.test != nil {
+		b, err = c.test(ctxVal)
+	} else {
+		b, err = c.compare(ctxVal)
+	}
+	return ctxVal, true, b, err
+}
+
+// compile prepares the condition for evaluation with the given options.
+func (x *Condition) compile(o *options) error {
+	if x.Group != nil {
+		return x.Group.compile(o)
+	}
+	x.Symbol = intern(x.Symbol)
+	if strings.IndexByte(x.Symbol, '.') >= 0 {
+		x.path = strings.Split(x.Symbol, ".")
+	}
+	x.opts = o
+	if x.Call != nil {
+		fn, err := x.Call.compile(o)
+		if err != nil {
+			return err
+		}
+		x.fn = fn
+		x.quantified = isQuantifier(x.Call, o)
+		if x.Compare == nil {
+			if x.quantified || isScalar(x.Call, o) {
+				return fmt.Errorf("%w: %s: want a comparison", ErrInvalidQuery, x)
+			}
+			return nil
+		}
+	} else if d, ok := o.derived[x.Symbol]; ok {
+		x.fn, x.inputs = d.fn, d.inputs
+	}
+	if x.Compare.Field == "" {
+		x.compileLiteral(o)
+	}
+	return nil
+}
+
+// compileLiteral specializes the comparison for the operator and the type
+// of the literal.
+func (x *Condition) compileLiteral(o *options) {
+	x.specialize(o)
+	if o.strict {
+		x.compileStrict()
+	}
+}
+
+// specialize sets test to a comparison specialized for the literal.
+func (x *Condition) specialize(o *options) {
+	v := x.Compare.Value
+	if isLike(x.Compare.Operator) {
+		if v.String != nil {
+			x.compileLike(o)
+		}
+		return
+	}
+	if v.String != nil && (o.normalize || o.collator != nil) {
+		x.compileString(o)
+	}
+	if v.Float == nil {
+		return
+	}
+	if o.epsilon > 0 {
+		x.compileEpsilon(*v.Float, o.epsilon)
+	} else {
+		x.compileNumber(*v.Float)
+	}
+	if o.nan != NaNIEEE {
+		x.test = nanTest(o.nan, *v.Float, x.test)
+	}
+	x.compileDecimal(*v.Float, o.exact)
+	x.compileInteger()
+}
+
+// compileNumber specializes the comparison of number literals: float64
+// context values, which is what JSON decoding produces, are compared directly
+// and anything else falls back to compare.
+func (x *Condition) compileNumber(f float64) {
+	v := x.Compare.Value
+	v.text = strconv.FormatFloat(f, 'f', -1, 64)
+	switch x.Compare.Operator {
+	case "=":
+		x.test = func(ctxVal interface{}) (bool, error) {
+			if n, ok := ctxVal.(float64); ok {
+				return n == f, nil
+			}
+			return x.compare(ctxVal)
+		}
+	case "<>", "!=":
+		x.test = func(ctxVal interface{}) (bool, error) {
+			if n, ok := ctxVal.(float64); ok {
+				return n != f, nil
+			}
+			return x.compare(ctxVal)
+		}
+	case ">":
+		x.test = func(ctxVal interface{}) (bool, error) {
+			if n, ok := ctxVal.(float64); ok {
+				return n > f, nil
+			}
+			return x.compare(ctxVal)
+		}
+	case ">=":
+		x.test = func(ctxVal interface{}) (bool, error) {
+			if n, ok := ctxVal.(float64); ok {
+				return n >= f, nil
+			}
+			return x.compare(ctxVal)
+		}
+	case "<":
+		x.test = func(ctxVal interface{}) (bool, error) {
+			if n, ok := ctxVal.(float64); ok {
+				return n < f, nil
+			}
+			return x.compare(ctxVal)
+		}
+	case "<=":
+		x.test = func(ctxVal interface{}) (bool, error) {
+			if n, ok := ctxVal.(float64); ok {
+				return n <= f, nil
+			}
+			return x.compare(ctxVal)
+		}
+	}
+}
+
+var symbols = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// intern returns the canonical copy of a symbol name, so the matchers of a
+// process share one string per field name instead of each holding its own.
+func intern(s string) string {
+	symbols.Lock()
+	defer symbols.Unlock()
+	if c, ok := symbols.m[s]; ok {
+		return c
+	}
+	symbols.m[s] = s
+	return s
+}
+
+// compare evaluates the condition against a context value, switching on the
+// operator and the literal type.
+func (x *Condition) compare(ctxVal interface{}) (bool, error) {
+	if x.Compare.Value.Null || ctxVal == nil {
+		return x.compareNull(ctxVal)
+	}
+	if t, ok := ctxVal.(time.Time); ok {
+		return x.compareTime(t)
+	}
+	if _, ok := ctxVal.(time.Duration); ok || x.Compare.Value.Duration != nil {
+		return x.compareDuration(ctxVal)
+	}
+	if x.Compare.Value.Time != nil {
+		return x.compareTimestamp(ctxVal)
 	}
 
 	switch o := x.Compare.Operator; o {
@@ -65,29 +335,31 @@ func (x *Condition) Eval(ctx Context) (bool, error) {
 		switch {
 		case v.Float != nil:
 			switch x := ctxVal.(type) {
-			case float32, float64:
-				return x.(float64) == *v.Float, nil
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-				return (float64)(x.(int)) == *v.Float, nil
 			case string:
-				return x == fmt.Sprintf("%f", *v.Float), nil
+				return compareNumberString(x, v) == 0, nil
 			case bool:
 				return x && *v.Float != 0 || !x && *v.Float == 0, nil // 0 is false, otherwise true
+			default:
+				if n, ok := toFloat(x); ok {
+					return n == *v.Float, nil
+				}
 			}
 		case v.String != nil:
-			return ctxVal == *v.String, nil
+			if s, ok := ctxVal.(string); ok {
+				return s == *v.String, nil
+			}
 		case v.Boolean != nil:
 			switch x := ctxVal.(type) {
-			case int:
-				return x == 0 && !(*v.Boolean) || x != 0 && (*v.Boolean), nil // 0 is false, otherwise true
 			case bool:
-				return x == *v.Boolean, nil
+				return x == bool(*v.Boolean), nil
 			case string:
-				b, err := strconv.ParseBool(x)
-				if err != nil {
-					return false, fmt.Errorf("is not bool value:%s, %w", x, err)
+				if b, err := strconv.ParseBool(x); err == nil {
+					return b == bool(*v.Boolean), nil
+				}
+			default:
+				if n, ok := toFloat(x); ok {
+					return (n != 0) == bool(*v.Boolean), nil // 0 is false, otherwise true
 				}
-				return b == *v.Boolean, nil
 			}
 		default:
 			return false, fmt.Errorf("unknown value type: %#v", v)
@@ -97,29 +369,31 @@ func (x *Condition) Eval(ctx Context) (bool, error) {
 		switch {
 		case v.Float != nil:
 			switch x := ctxVal.(type) {
-			case float32, float64:
-				return x.(float64) != *v.Float, nil
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-				return (float64)(x.(int)) != *v.Float, nil
 			case string:
-				return x != fmt.Sprintf("%f", *v.Float), nil
+				return compareNumberString(x, v) != 0, nil
 			case bool:
 				return !(x && *v.Float != 0 || !x && *v.Float == 0), nil // 0 is false, otherwise true
+			default:
+				if n, ok := toFloat(x); ok {
+					return n != *v.Float, nil
+				}
 			}
 		case v.String != nil:
-			return ctxVal != *v.String, nil
+			if s, ok := ctxVal.(string); ok {
+				return s != *v.String, nil
+			}
 		case v.Boolean != nil:
 			switch x := ctxVal.(type) {
-			case int:
-				return !(x == 0 && !(*v.Boolean) || x != 0 && (*v.Boolean)), nil // 0 is false, otherwise true
 			case bool:
-				return x != *v.Boolean, nil
+				return x != bool(*v.Boolean), nil
 			case string:
-				b, err := strconv.ParseBool(x)
-				if err != nil {
-					return false, fmt.Errorf("is not bool value:%s, %w", x, err)
+				if b, err := strconv.ParseBool(x); err == nil {
+					return b != bool(*v.Boolean), nil
+				}
+			default:
+				if n, ok := toFloat(x); ok {
+					return (n != 0) != bool(*v.Boolean), nil // 0 is false, otherwise true
 				}
-				return b != *v.Boolean, nil
 			}
 		default:
 			return false, fmt.Errorf("unknown value type: %#v", v)
@@ -130,18 +404,17 @@ func (x *Condition) Eval(ctx Context) (bool, error) {
 		switch {
 		case v.Float != nil:
 			switch x := ctxVal.(type) {
-			case float32, float64:
-				return x.(float64) > *v.Float, nil
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-				i := x.(int64)
-				return float64(i) > *v.Float, nil
 			case string:
-				return string(x) > fmt.Sprintf("%f", *v.Float), nil
-			case bool:
-				return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
+				return compareNumberString(x, v) > 0, nil
+			default:
+				if n, ok := toFloat(x); ok {
+					return n > *v.Float, nil
+				}
 			}
 		case v.String != nil:
-			return ctxVal.(string) > *v.String, nil
+			if s, ok := ctxVal.(string); ok {
+				return s > *v.String, nil
+			}
 		case v.Boolean != nil:
 			return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
 		default:
@@ -153,18 +426,17 @@ func (x *Condition) Eval(ctx Context) (bool, error) {
 		switch {
 		case v.Float != nil:
 			switch x := ctxVal.(type) {
-			case float32, float64:
-				return x.(float64) >= *v.Float, nil
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-				i := x.(int64)
-				return float64(i) >= *v.Float, nil
 			case string:
-				return string(x) >= fmt.Sprintf("%f", *v.Float), nil
-			case bool:
-				return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
+				return compareNumberString(x, v) >= 0, nil
+			default:
+				if n, ok := toFloat(x); ok {
+					return n >= *v.Float, nil
+				}
 			}
 		case v.String != nil:
-			return ctxVal.(string) >= *v.String, nil
+			if s, ok := ctxVal.(string); ok {
+				return s >= *v.String, nil
+			}
 		case v.Boolean != nil:
 			return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
 		default:
@@ -176,18 +448,17 @@ func (x *Condition) Eval(ctx Context) (bool, error) {
 		switch {
 		case v.Float != nil:
 			switch x := ctxVal.(type) {
-			case float32, float64:
-				return x.(float64) < *v.Float, nil
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-				i := x.(int64)
-				return float64(i) < *v.Float, nil
 			case string:
-				return string(x) < fmt.Sprintf("%f", *v.Float), nil
-			case bool:
-				return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
+				return compareNumberString(x, v) < 0, nil
+			default:
+				if n, ok := toFloat(x); ok {
+					return n < *v.Float, nil
+				}
 			}
 		case v.String != nil:
-			return ctxVal.(string) < *v.String, nil
+			if s, ok := ctxVal.(string); ok {
+				return s < *v.String, nil
+			}
 		case v.Boolean != nil:
 			return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
 		default:
@@ -199,50 +470,177 @@ func (x *Condition) Eval(ctx Context) (bool, error) {
 		switch {
 		case v.Float != nil:
 			switch x := ctxVal.(type) {
-			case float32, float64:
-				return x.(float64) <= *v.Float, nil
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-				i := x.(int64)
-				return float64(i) <= *v.Float, nil
 			case string:
-				return string(x) <= fmt.Sprintf("%f", *v.Float), nil
-			case bool:
-				return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
+				return compareNumberString(x, v) <= 0, nil
+			default:
+				if n, ok := toFloat(x); ok {
+					return n <= *v.Float, nil
+				}
 			}
 		case v.String != nil:
-			return ctxVal.(string) <= *v.String, nil
+			if s, ok := ctxVal.(string); ok {
+				return s <= *v.String, nil
+			}
 		case v.Boolean != nil:
 			return false, fmt.Errorf("boolean did not compare by greater/less then: %#v", v)
 		default:
 			return false, fmt.Errorf("unknown value type: %#v", v)
 		}
 
+	case "LIKE", "NOT LIKE":
+		if s, ok := ctxVal.(string); ok && x.Compare.Value.String != nil {
+			return likeMatcher(*x.Compare.Value.String)(s) == (o == "LIKE"), nil
+		}
+
 	default:
 		return false, fmt.Errorf("unknown operator: %s", o)
 	}
-	return false, fmt.Errorf("failed to complation, type: %T: %#v", ctxVal, ctxVal)
+	return x.mismatch(ctxVal)
+}
+
+// compareNull evaluates comparisons with a NULL literal or a null context
+// value. NULL equals only NULL, and is unequal to everything else. Ordering
+// comparisons are UNKNOWN, which is false, unless the NullError policy
+// applies.
+func (x *Condition) compareNull(ctxVal interface{}) (bool, error) {
+	bothNull := x.Compare.Value.Null && ctxVal == nil
+	switch x.Compare.Operator {
+	case "=":
+		return bothNull, nil
+	case "<>", "!=":
+		return !bothNull, nil
+	}
+	if x.opts != nil && x.opts.null == NullError {
+		return false, fmt.Errorf("%w: %s", ErrNullOrdering, x)
+	}
+	return false, nil
+}
+
+// compareNumberString orders a string context value against a number literal.
+// Strings holding a number compare numerically, so "5" equals 5; any other
+// string compares lexically with the literal's shortest decimal form.
+func compareNumberString(s string, v *Value) int {
+	f := *v.Float
+	if n, ok := parseNumber(s); ok {
+		switch {
+		case n < f:
+			return -1
+		case n > f:
+			return 1
+		case n == f:
+			return 0
+		}
+	}
+	return strings.Compare(s, v.floatText())
+}
+
+// parseNumber parses a decimal number. Unlike strconv.ParseFloat it does not
+// allocate an error for strings that are not numbers.
+func parseNumber(s string) (float64, bool) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exp := 0
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			exp++
+		}
+		if exp == 0 {
+			return 0, false
+		}
+	}
+	if i != len(s) {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
 }
 
 type Compare struct {
-	Operator string `@( "<>" | "<=" | ">=" | "=" | "<" | ">" | "!=" )`
-	Value    *Value `@@`
+	Operator string `parser:"@( '<>' | '<=' | '>=' | '=' | '<' | '>' | '!=' | 'LIKE':Keyword | 'NOT':Keyword 'LIKE':Keyword )"`
+	Value    *Value `parser:"( @@"`
+	// Field is the field compared with in a comparison of two fields, such
+	// as `updated_at > created_at`, which has no Value.
+	Field string `parser:"| @( (Ident | NonFinite) ( '.' (Ident | NonFinite) )* ) )"`
 }
 
 type Value struct {
-	Float   *float64 `( @Float `
-	String  *string  ` | @String`
-	Boolean *bool    ` | @("TRUE" | "FALSE")`
-	Null    bool     ` | @"NULL" )`
+	Float    *float64   `parser:"( @(Float | NonFinite)"`
+	String   *string    `parser:"| @String"`
+	Boolean  *Boolean   `parser:"| @('TRUE' | 'FALSE')"`
+	Duration *Duration  `parser:"| @Duration"`
+	Time     *Timestamp `parser:"| @Timestamp"`
+	Null     bool       `parser:"| @'NULL' )"`
+
+	// Tokens are the tokens of a parsed literal, which keep the digits of
+	// numbers that float64 can not hold exactly.
+	Tokens []lexer.Token
+
+	// text caches the shortest decimal form of Float, set by compile.
+	text string
 }
 
+// floatText returns the shortest decimal form of the number literal.
+func (v *Value) floatText() string {
+	if v.text != "" {
+		return v.text
+	}
+	return strconv.FormatFloat(*v.Float, 'f', -1, 64)
+}
+
+// sharedParser returns the process wide parser of the latest grammar.
+func sharedParser() *participle.Parser {
+	p, _ := parserFor(SyntaxVersion)
+	return p
+}
+
+// NewParser returns a parser of the latest grammar.
 func NewParser() *participle.Parser {
+	return newParser(SyntaxVersion)
+}
+
+// newParser returns a parser of version v of the grammar.
+func newParser(v int) *participle.Parser {
+	keyword := `(?i)\b(` + strings.Join(keywords[v], "|") + `)\b`
+	float := `[-+]?\d*\.?\d+([eE][-+]?\d+)?|[-+](?i:inf|nan)\b`
+	// Unsigned NaN and Inf are numbers where a value is expected and field
+	// names elsewhere.
+	nonFinite := `(?i)\b(inf|nan)\b`
+	if v == 1 {
+		// Version 1 lexes keywords and numbers exactly like the original
+		// grammar: keywords need no word boundary, and NULL, NaN and Inf
+		// are identifiers. Durations and timestamps are text it rejected.
+		keyword = `(?i)` + strings.Join(keywords[v], "|")
+		float = `[-+]?\d*\.?\d+([eE][-+]?\d+)?`
+		nonFinite = `[^\s\S]`
+	}
 	qLexer := lexer.MustSimple([]lexer.SimpleRule{
-		{`Keyword`, `(?i)TRUE|FALSE|AND|OR`},
-		{`Ident`, `[a-zA-Z_][a-zA-Z0-9_]*`},
-		{`Float`, `[-+]?\d*\.?\d+([eE][-+]?\d+)?`},
-		{`String`, `'[^']*'|"[^"]*"`},
-		{`Operators`, `<>|!=|<=|>=|[-+*/%,.()=<>]`},
-		{"whitespace", `\s+`},
+		{Name: `Keyword`, Pattern: keyword},
+		{Name: `Timestamp`, Pattern: `\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[-+]\d{2}:\d{2})?)?\b`},
+		{Name: `Duration`, Pattern: `(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+\b`},
+		{Name: `Float`, Pattern: float},
+		{Name: `NonFinite`, Pattern: nonFinite},
+		{Name: `Ident`, Pattern: `[a-zA-Z_][a-zA-Z0-9_]*`},
+		{Name: `String`, Pattern: `'[^']*'|"[^"]*"`},
+		{Name: `Operators`, Pattern: `<>|!=|<=|>=|[-+*/%,.()=<>]`},
+		{Name: "whitespace", Pattern: `\s+`},
 	})
 	return participle.MustBuild(
 		&Expression{},
@@ -250,7 +648,7 @@ func NewParser() *participle.Parser {
 		participle.Unquote("String"),
 		participle.CaseInsensitive("Keyword"),
 		// participle.Elide("Comment"),
-		// Need to solve left recursion detection first, if possible.
-		// participle.UseLookahead(),
+		// A function call and a condition both start with an identifier.
+		participle.UseLookahead(2),
 	)
 }
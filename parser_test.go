@@ -40,6 +40,17 @@ func TestSimpleMatcher(t *testing.T) {
 		{"a<=2", "{\"a\":3}", false},
 		{"a<=2", "{\"a\":2}", true},
 		{"a<=2", "{\"a\":1}", true},
+
+		// numeric strings compare as numbers
+		{"a=5", "{\"a\":\"5\"}", true},
+		{"a=5", "{\"a\":\"5.0\"}", true},
+		{"a!=5", "{\"a\":\"5\"}", false},
+		{"a>10", "{\"a\":\"9\"}", false},
+		{"a<=1.5", "{\"a\":\"1.25\"}", true},
+
+		// other strings compare with the literal's text
+		{"a=5", "{\"a\":\"five\"}", false},
+		{"a>5", "{\"a\":\"x\"}", true},
 	}
 
 	for _, c := range cases {
@@ -96,6 +107,27 @@ func BenchmarkComplexMatcher(b *testing.B) {
 
 	json.Unmarshal([]byte(content), &ctx)
 
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Test(&ctx)
+	}
+}
+
+func BenchmarkNumericMatcher(b *testing.B) {
+	m, _ := matcher.NewMatcher("age > 30 and score >= 0.5 and retries < 3 and id != 7")
+	ctx := matcher.Context{"age": 40.0, "score": 0.75, "retries": 1.0, "id": 8.0}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Test(&ctx)
+	}
+}
+
+func BenchmarkNumericStringMatcher(b *testing.B) {
+	m, _ := matcher.NewMatcher("age > 30 and score >= 0.5 and retries < 3 and id != 7")
+	ctx := matcher.Context{"age": "40", "score": "0.75", "retries": "1", "id": "8"}
+
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		m.Test(&ctx)
 	}
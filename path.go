@@ -0,0 +1,71 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// lookup returns the value of key in ctx. A key with dots that ctx does not
+// hold is a path into nested objects: "address.city" is the city member of
+// the address object. Objects may be maps or undecoded json.RawMessage
+// values.
+func lookup(ctx Context, key string) (interface{}, bool) {
+	v, ok := ctx[key]
+	if ok || strings.IndexByte(key, '.') < 0 {
+		return v, ok
+	}
+	return lookupPath(ctx, strings.Split(key, "."))
+}
+
+// lookupPath returns the value at path in ctx.
+func lookupPath(ctx Context, path []string) (interface{}, bool) {
+	var v interface{} = ctx
+	for _, name := range path {
+		if raw, ok := v.(json.RawMessage); ok {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			if err := dec.Decode(&v); err != nil {
+				return nil, false
+			}
+		}
+		var ok bool
+		switch m := v.(type) {
+		case Context:
+			v, ok = m[name]
+		case map[string]interface{}:
+			v, ok = m[name]
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// value returns the context value of the field of the condition.
+func (x *Condition) value(ctx Context) (interface{}, bool) {
+	v, ok := ctx[x.Symbol]
+	if ok || x.path == nil {
+		return v, ok
+	}
+	return lookupPath(ctx, x.path)
+}
+
+// root returns the top-level key of a path.
+func root(key string) string {
+	if i := strings.IndexByte(key, '.'); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// isPath reports whether s can be written as a field or a path in a query.
+func isPath(s string) bool {
+	for _, name := range strings.Split(s, ".") {
+		if !isIdentifier(name) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		query string
+		ctx   matcher.Context
+		want  bool
+	}{
+		{`address.city = "Tokyo"`, matcher.Context{"address": map[string]interface{}{"city": "Tokyo"}}, true},
+		{`address.city = "Tokyo"`, matcher.Context{"address": matcher.Context{"city": "Osaka"}}, false},
+		{`a.b.c > 1`, matcher.Context{"a": map[string]interface{}{"b": map[string]interface{}{"c": 2.0}}}, true},
+		{`address.city = "Tokyo"`, matcher.Context{"address": json.RawMessage(`{"city":"Tokyo"}`)}, true},
+		{`address.city = "Tokyo"`, matcher.Context{"address.city": "Tokyo"}, true},
+		{`address.city = "Tokyo"`, matcher.Context{"address": "Tokyo"}, false},
+		{`address.city = NULL`, matcher.Context{"address": map[string]interface{}{}}, false},
+		{`address.city = NULL`, matcher.Context{"address": map[string]interface{}{"city": nil}}, true},
+		{`EXISTS(address.city)`, matcher.Context{"address": map[string]interface{}{"city": nil}}, true},
+		{`MISSING(address.zip)`, matcher.Context{"address": map[string]interface{}{"city": nil}}, true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&tt.ctx)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %v", tt.query, tt.ctx)
+	}
+}
+
+func TestPathResolvers(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`user.address.city = "Tokyo" AND plan = "pro"`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`user.address.city = "Tokyo" AND plan = "pro"`, m.Expression.String())
+
+	ok, err := m.TestReader(strings.NewReader(`{"plan":"pro","other":1,"user":{"address":{"city":"Tokyo"}}}`))
+	assert.NoError(err)
+	assert.True(ok)
+
+	ok, err = m.TestResolver(matcher.Layers{
+		{"plan": "pro"},
+		{"user": map[string]interface{}{"address": map[string]interface{}{"city": "Tokyo"}}},
+	})
+	assert.NoError(err)
+	assert.True(ok)
+
+	e, err := matcher.FromRows(m.Expression.Rows())
+	assert.NoError(err)
+	assert.Equal(m.Expression.String(), e.String())
+
+	_, err = matcher.NewMatcher(`EXISTS(a.b)`, matcher.WithSyntaxVersion(1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+}
@@ -0,0 +1,283 @@
+// Package plugin lets separate programs provide query functions, so that the
+// rule language can be extended without recompiling every program embedding
+// the matcher.
+//
+// A plugin is an executable speaking newline-delimited JSON on its standard
+// input and output. The host first sends
+//
+//	{"id":0,"method":"functions"}
+//
+// and the plugin answers with the names of its functions:
+//
+//	{"id":0,"result":["GEO_COUNTRY"]}
+//
+// Every evaluation of a call then sends the arguments, with fields replaced
+// by their context values (null when missing) and durations by their
+// nanoseconds:
+//
+//	{"id":1,"method":"call","params":{"function":"GEO_COUNTRY","args":["10.0.0.1"]}}
+//
+// and the plugin answers with the result, or an error message:
+//
+//	{"id":1,"result":"JP"}
+//	{"id":2,"error":"bad address"}
+//
+// Plugins written in Go implement this with Serve.
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kuwa72/matcher"
+)
+
+// ErrClosed is returned by calls to a plugin that exited or was closed.
+var ErrClosed = errors.New("plugin: closed")
+
+// request is a message from the host.
+type request struct {
+	ID     int64   `json:"id"`
+	Method string  `json:"method"`
+	Params *params `json:"params,omitempty"`
+}
+
+type params struct {
+	Function string        `json:"function"`
+	Args     []interface{} `json:"args"`
+}
+
+// response is a message from the plugin.
+type response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Plugin is a running plugin process. It is safe for concurrent use; calls
+// are sent one at a time.
+type Plugin struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	out   *bufio.Reader
+	funcs []string
+
+	mu     sync.Mutex
+	nextID int64
+	err    error
+}
+
+// Start runs the plugin executable name with args and asks it for its
+// functions. The plugin inherits the standard error of the process.
+func Start(name string, args ...string) (*Plugin, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Plugin{cmd: cmd, stdin: stdin, out: bufio.NewReader(stdout)}
+	res, err := p.roundTrip("functions", nil)
+	if err == nil {
+		err = json.Unmarshal(res, &p.funcs)
+	}
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Functions returns the names of the functions of the plugin.
+func (p *Plugin) Functions() []string {
+	return p.funcs
+}
+
+// Options returns the options making the functions of the plugin available
+// to a query.
+func (p *Plugin) Options() []matcher.Option {
+	opts := make([]matcher.Option, len(p.funcs))
+	for i, name := range p.funcs {
+		opts[i] = matcher.WithFunction(name, p.factory(name))
+	}
+	return opts
+}
+
+// Close stops the plugin and waits for it to exit.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == ErrClosed {
+		return nil
+	}
+	p.err = ErrClosed
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// factory returns the FuncFactory of the plugin function name.
+func (p *Plugin) factory(name string) matcher.FuncFactory {
+	name = strings.ToUpper(name)
+	return func(args []*matcher.Arg) (matcher.Func, error) {
+		return func(ctx matcher.Context) (interface{}, error) {
+			values := make([]interface{}, len(args))
+			for i, a := range args {
+				values[i] = argValue(a, ctx)
+			}
+			res, err := p.roundTrip("call", &params{Function: name, Args: values})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			var v interface{}
+			if err := json.Unmarshal(res, &v); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			return v, nil
+		}, nil
+	}
+}
+
+// argValue returns the value sent for an argument.
+func argValue(a *matcher.Arg, ctx matcher.Context) interface{} {
+	switch {
+	case a.Duration != nil:
+		return time.Duration(*a.Duration).Nanoseconds()
+	case a.Symbol != "":
+		return ctx[a.Symbol]
+	}
+	v := a.Value
+	switch {
+	case v.Float != nil:
+		return *v.Float
+	case v.String != nil:
+		return *v.String
+	case v.Boolean != nil:
+		return bool(*v.Boolean)
+	case v.Duration != nil:
+		return time.Duration(*v.Duration).Nanoseconds()
+	}
+	return nil
+}
+
+// roundTrip sends a request and reads its response. A failure to talk to the
+// plugin closes it.
+func (p *Plugin) roundTrip(method string, params *params) (json.RawMessage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	id := p.nextID
+	p.nextID++
+	b, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	var res response
+	if _, err = p.stdin.Write(append(b, '\n')); err == nil {
+		var line []byte
+		if line, err = p.out.ReadBytes('\n'); err == nil {
+			err = json.Unmarshal(line, &res)
+		}
+	}
+	if err == nil && res.ID != id {
+		err = fmt.Errorf("response %d to request %d", res.ID, id)
+	}
+	if err != nil {
+		p.err = fmt.Errorf("%w: %v", ErrClosed, err)
+		return nil, p.err
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+	return res.Result, nil
+}
+
+// Func is a plugin function. Its arguments are the decoded JSON values sent
+// by the host.
+type Func func(args []interface{}) (interface{}, error)
+
+// Serve answers the requests of the host on standard input and output with
+// funcs until standard input is closed. Function names are case-insensitive.
+func Serve(funcs map[string]Func) error {
+	return ServeIO(os.Stdin, os.Stdout, funcs)
+}
+
+// ServeIO is like Serve, reading requests from r and writing responses to
+// w.
+func ServeIO(r io.Reader, w io.Writer, funcs map[string]Func) error {
+	byName := make(map[string]Func, len(funcs))
+	names := make([]string, 0, len(funcs))
+	for name, f := range funcs {
+		byName[strings.ToUpper(name)] = f
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	in := bufio.NewReader(r)
+	enc := json.NewEncoder(w)
+	for {
+		line, err := in.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			return fmt.Errorf("plugin: invalid request: %w", err)
+		}
+
+		var result interface{}
+		var callErr error
+		switch req.Method {
+		case "functions":
+			result = names
+		case "call":
+			if req.Params == nil {
+				callErr = errors.New("missing params")
+			} else if f, ok := byName[strings.ToUpper(req.Params.Function)]; !ok {
+				callErr = fmt.Errorf("unknown function %s", req.Params.Function)
+			} else {
+				result, callErr = f(req.Params.Args)
+			}
+		default:
+			callErr = fmt.Errorf("unknown method %s", req.Method)
+		}
+
+		res := response{ID: req.ID}
+		if callErr != nil {
+			res.Error = callErr.Error()
+		} else if res.Result, err = json.Marshal(result); err != nil {
+			res.Error = err.Error()
+		}
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+	}
+}
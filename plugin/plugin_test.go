@@ -0,0 +1,74 @@
+package plugin_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain runs the test binary as a plugin when PLUGIN_TEST is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("PLUGIN_TEST") != "" {
+		err := plugin.Serve(map[string]plugin.Func{
+			"domain": func(args []interface{}) (interface{}, error) {
+				s, _ := args[0].(string)
+				i := strings.LastIndex(s, "@")
+				if i < 0 {
+					return nil, errors.New("not an address")
+				}
+				return s[i+1:], nil
+			},
+			"describe": func(args []interface{}) (interface{}, error) {
+				return fmt.Sprint(args), nil
+			},
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPlugin(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("PLUGIN_TEST", "1")
+	p, err := plugin.Start(os.Args[0])
+	assert.NoError(err)
+	defer p.Close()
+	assert.Equal([]string{"describe", "domain"}, p.Functions())
+
+	m, err := matcher.NewMatcher(`DOMAIN(email) = "example.com" and DESCRIBE(5m, 1, "a", TRUE, missing) = "[3e+11 1 a true <nil>]"`,
+		append(p.Options(), matcher.WithSyntaxVersion(2))...)
+	assert.NoError(err)
+	ok, err := m.Test(&matcher.Context{"email": "kim@example.com"})
+	assert.NoError(err)
+	assert.True(ok)
+
+	_, err = m.Test(&matcher.Context{"email": "kim"})
+	assert.EqualError(err, "DOMAIN: not an address")
+
+	assert.NoError(p.Close())
+	_, err = m.Test(&matcher.Context{"email": "kim@example.com"})
+	assert.ErrorIs(err, plugin.ErrClosed)
+}
+
+func TestServeIO(t *testing.T) {
+	var out strings.Builder
+	err := plugin.ServeIO(strings.NewReader(
+		`{"id":0,"method":"functions"}`+"\n"+
+			`{"id":1,"method":"call","params":{"function":"nope","args":[]}}`+"\n"+
+			`{"id":2,"method":"stop"}`),
+		&out, map[string]plugin.Func{"f": nil})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":0,"result":["f"]}
+{"id":1,"error":"unknown function nope"}
+{"id":2,"error":"unknown method stop"}
+`, out.String())
+}
@@ -0,0 +1,78 @@
+package matcher
+
+// Effect is the outcome of a policy decision.
+type Effect int
+
+const (
+	// Deny rejects the request. It is the zero Effect.
+	Deny Effect = iota
+	// Allow accepts the request.
+	Allow
+)
+
+func (e Effect) String() string {
+	if e == Allow {
+		return "allow"
+	}
+	return "deny"
+}
+
+// Statement allows or denies the contexts matching its query.
+type Statement struct {
+	Name   string
+	Effect Effect
+	Query  string
+}
+
+// Decision is the outcome of Policy.Decide.
+type Decision struct {
+	Effect Effect
+	// Statement is the statement that decided, nil when the policy default
+	// applied.
+	Statement *Statement
+}
+
+// Allowed reports whether the decision is Allow.
+func (d Decision) Allowed() bool {
+	return d.Effect == Allow
+}
+
+// Policy decides between allow and deny with an ordered list of statements:
+// the first statement whose query matches decides, and Default applies when
+// none does.
+//
+// A Policy is safe for concurrent use by multiple goroutines.
+type Policy struct {
+	Default Effect
+
+	rules *RuleSet
+}
+
+// NewPolicy compiles the queries of statements with opts.
+func NewPolicy(def Effect, statements []Statement, opts ...Option) (*Policy, error) {
+	rules := make([]Rule, len(statements))
+	for i := range statements {
+		s := statements[i]
+		rules[i] = Rule{Name: s.Name, Query: s.Query, Action: &s}
+	}
+	s, err := NewRuleSet(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{Default: def, rules: s}, nil
+}
+
+// Decide returns the decision for the context. When a query fails to
+// evaluate, the error is returned with a Deny decision, so callers ignoring
+// the error fail closed.
+func (p *Policy) Decide(c *Context) (Decision, error) {
+	r, err := p.rules.EvaluateFirst(c)
+	if err != nil {
+		return Decision{Effect: Deny}, err
+	}
+	if r == nil {
+		return Decision{Effect: p.Default}, nil
+	}
+	s := r.Action.(*Statement)
+	return Decision{Effect: s.Effect, Statement: s}, nil
+}
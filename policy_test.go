@@ -0,0 +1,60 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	assert := assert.New(t)
+	p, err := matcher.NewPolicy(matcher.Deny, []matcher.Statement{
+		{Name: "blocked", Effect: matcher.Deny, Query: `user = "mallory"`},
+		{Name: "admins", Effect: matcher.Allow, Query: `role = "admin"`},
+		{Name: "readers", Effect: matcher.Allow, Query: `method = "GET" and path <> "/admin"`},
+	})
+	assert.NoError(err)
+
+	cases := []struct {
+		ctx       matcher.Context
+		effect    matcher.Effect
+		statement string
+	}{
+		{matcher.Context{"user": "mallory", "role": "admin"}, matcher.Deny, "blocked"},
+		{matcher.Context{"user": "alice", "role": "admin", "method": "POST"}, matcher.Allow, "admins"},
+		{matcher.Context{"user": "bob", "method": "GET", "path": "/"}, matcher.Allow, "readers"},
+		{matcher.Context{"user": "bob", "method": "POST", "path": "/"}, matcher.Deny, ""},
+	}
+	for _, c := range cases {
+		d, err := p.Decide(&c.ctx)
+		assert.NoError(err)
+		assert.Equal(c.effect, d.Effect, c.ctx)
+		assert.Equal(c.effect == matcher.Allow, d.Allowed())
+		if c.statement == "" {
+			assert.Nil(d.Statement)
+		} else {
+			assert.Equal(c.statement, d.Statement.Name)
+		}
+	}
+
+	p.Default = matcher.Allow
+	d, err := p.Decide(&matcher.Context{"user": "bob"})
+	assert.NoError(err)
+	assert.True(d.Allowed())
+	assert.Equal("allow", d.Effect.String())
+}
+
+func TestPolicyFailsClosed(t *testing.T) {
+	p, err := matcher.NewPolicy(matcher.Allow, []matcher.Statement{
+		{Name: "strict", Effect: matcher.Allow, Query: `role = "admin"`},
+	}, matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, err)
+
+	d, err := p.Decide(&matcher.Context{"role": 1.0})
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+	assert.False(t, d.Allowed())
+
+	_, err = matcher.NewPolicy(matcher.Deny, []matcher.Statement{{Name: "broken", Query: "role ="}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package matcher
+
+// prefilter selects the rules of a RuleSet that may match a context from
+// their equality conditions on string literals. A rule whose every OR branch
+// has such a condition, `field = "value"`, can only match a context having
+// one of these values; other rules are always candidates.
+type prefilter struct {
+	// values[field][value] lists the rules with a branch requiring the
+	// value.
+	values map[string]map[string][]int
+	// fields[field] lists the rules with a branch requiring a value of the
+	// field, candidates whenever the field holds something else than a
+	// string, such as a time.Time, that may equal a string literal.
+	fields map[string][]int
+	always []int
+}
+
+func newPrefilter(rules []*Rule) *prefilter {
+	p := &prefilter{values: make(map[string]map[string][]int), fields: make(map[string][]int)}
+	for i, r := range rules {
+		keys := r.matcher.prefilterKeys()
+		if keys == nil {
+			p.always = append(p.always, i)
+			continue
+		}
+		for _, c := range keys {
+			if p.values[c.Symbol] == nil {
+				p.values[c.Symbol] = make(map[string][]int)
+			}
+			v := *c.Compare.Value.String
+			p.values[c.Symbol][v] = append(p.values[c.Symbol][v], i)
+			p.fields[c.Symbol] = append(p.fields[c.Symbol], i)
+		}
+	}
+	return p
+}
+
+// prefilterKeys returns an indexable equality condition of every OR branch,
+// or nil if a branch has none.
+func (m *Matcher) prefilterKeys() []*Condition {
+	if m.now != nil {
+		return nil
+	}
+	keys := make([]*Condition, 0, len(m.Expression.Or))
+	for _, x := range m.Expression.Or {
+		// Conditions after a call are not used, as skipping the rule
+		// would change what stateful functions such as COUNT_OVER see.
+		var key *Condition
+		for _, c := range x.And {
+			if c.Call != nil || c.Group != nil && c.Group.hasCalls() {
+				break
+			}
+			if c.indexable() {
+				key = c
+				break
+			}
+		}
+		if key == nil {
+			return nil
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// indexable reports whether the condition only holds for a context holding
+// the string literal, or a value that is not a string, under the field.
+func (x *Condition) indexable() bool {
+	return x.Call == nil && x.Group == nil && !x.Not && x.fn == nil && x.Compare.Operator == "=" && x.Compare.Value != nil && x.Compare.Value.String != nil &&
+		(x.opts == nil || !x.opts.normalize && x.opts.collator == nil)
+}
+
+// candidates returns which of the n rules may match c.
+func (p *prefilter) candidates(c Context, n int) []bool {
+	selected := make([]bool, n)
+	for _, i := range p.always {
+		selected[i] = true
+	}
+	for field, values := range p.values {
+		v, ok := lookup(c, field)
+		if !ok {
+			continue
+		}
+		rows := p.fields[field]
+		if s, ok := v.(string); ok {
+			rows = values[s]
+		}
+		for _, i := range rows {
+			selected[i] = true
+		}
+	}
+	return selected
+}
@@ -0,0 +1,60 @@
+package matcher_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleSetPrefilter(t *testing.T) {
+	assert := assert.New(t)
+	s, err := matcher.NewRuleSet([]matcher.Rule{
+		{Name: "order", Query: `type = "order" and total > 100`},
+		{Name: "either", Query: `type = "refund" or kind = "refund"`},
+		{Name: "any", Query: `amount > 1000`},
+		{Name: "day", Query: `day = "2024-05-01"`},
+		{Name: "counted", Query: `COUNT_OVER(1m) > 1 and type = "order"`},
+	}, matcher.WithMismatchPolicy(matcher.MismatchError), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	names := func(c matcher.Context) []string {
+		rules, err := s.EvaluateAll(&c)
+		assert.NoError(err)
+		var out []string
+		for _, r := range rules {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+	// The order rule is skipped for other types, so its failing comparison
+	// of total is not reported.
+	assert.Equal([]string{"either"}, names(matcher.Context{"type": "refund", "total": true}))
+	assert.Equal([]string{"either", "any"}, names(matcher.Context{"kind": "refund", "amount": 2000.0}))
+	assert.Equal([]string{"order", "counted"}, names(matcher.Context{"type": "order", "total": 200.0}))
+	// Values other than strings may still equal string literals.
+	assert.Equal([]string{"day"}, names(matcher.Context{"day": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}))
+
+	_, err = s.EvaluateAll(&matcher.Context{"type": "order", "total": true})
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+}
+
+func BenchmarkRuleSetPrefilter(b *testing.B) {
+	rules := make([]matcher.Rule, 1000)
+	for i := range rules {
+		rules[i] = matcher.Rule{Name: fmt.Sprint(i), Query: fmt.Sprintf(`tenant = "t%d" and level = "error"`, i)}
+	}
+	s, err := matcher.NewRuleSet(rules)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := matcher.Context{"tenant": "t500", "level": "error"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.EvaluateAll(&c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package matcher
+
+import "time"
+
+// PredicateProfile holds the statistics of one condition collected by
+// Profile.
+type PredicateProfile struct {
+	// Predicate is the condition in query syntax, e.g. `age > 30`.
+	Predicate string
+	// Branch is the index of the OR branch the condition belongs to.
+	Branch int
+	// Calls counts how often the condition was evaluated.
+	Calls int
+	// Matches counts the evaluations that held.
+	Matches int
+	// Errors counts the evaluations that failed.
+	Errors int
+	// ShortCircuits counts the evaluations after which the rest of the
+	// expression was skipped: a false condition ending its AND chain early,
+	// or a matching AND chain skipping the OR branches after it.
+	ShortCircuits int
+	// Time is the total time spent evaluating the condition.
+	Time time.Duration
+}
+
+// ShortCircuitRate returns the share of evaluations that short-circuited.
+func (p PredicateProfile) ShortCircuitRate() float64 {
+	if p.Calls == 0 {
+		return 0
+	}
+	return float64(p.ShortCircuits) / float64(p.Calls)
+}
+
+// ProfileResult is the outcome of Profile.
+type ProfileResult struct {
+	// Contexts, Matches and Errors count the tested contexts, those that
+	// matched and those that failed to evaluate.
+	Contexts int
+	Matches  int
+	Errors   int
+	// Time is the total evaluation time.
+	Time time.Duration
+	// Predicates lists the conditions in query order.
+	Predicates []PredicateProfile
+}
+
+// Profile tests m against every context and records per condition
+// statistics. Contexts failing to evaluate are counted and skipped.
+func Profile(m *Matcher, contexts []Context) *ProfileResult {
+	e := m.Expression
+	res := &ProfileResult{}
+	// offsets[i] is the index in res.Predicates of branch i's first condition.
+	offsets := make([]int, len(e.Or))
+	for i, x := range e.Or {
+		offsets[i] = len(res.Predicates)
+		for _, c := range x.And {
+			res.Predicates = append(res.Predicates, PredicateProfile{Predicate: c.String(), Branch: i})
+		}
+	}
+
+	start := time.Now()
+	for _, ctx := range contexts {
+		res.Contexts++
+		matched, err := profileEval(e, ctx, res.Predicates, offsets)
+		switch {
+		case err != nil:
+			res.Errors++
+		case matched:
+			res.Matches++
+		}
+	}
+	res.Time = time.Since(start)
+	return res
+}
+
+// profileEval evaluates e like Expression.Eval, recording statistics.
+func profileEval(e *Expression, ctx Context, preds []PredicateProfile, offsets []int) (bool, error) {
+	for i, x := range e.Or {
+		matched := true
+		for j, c := range x.And {
+			p := &preds[offsets[i]+j]
+			start := time.Now()
+			b, err := c.Eval(ctx)
+			p.Time += time.Since(start)
+			p.Calls++
+			if err != nil {
+				p.Errors++
+				return false, err
+			}
+			if !b {
+				if j < len(x.And)-1 {
+					p.ShortCircuits++
+				}
+				matched = false
+				break
+			}
+			p.Matches++
+			if j == len(x.And)-1 && i < len(e.Or)-1 {
+				p.ShortCircuits++
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,46 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("a > 1 and b = \"x\" or c = TRUE", matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(err)
+
+	res := matcher.Profile(m, []matcher.Context{
+		{"a": 2.0, "b": "x"},             // first branch matches
+		{"a": 0.0, "c": true},            // a fails, c matches
+		{"a": 2.0, "b": "y", "c": false}, // nothing matches
+		{"a": 0.0, "c": "maybe"},         // c fails to evaluate
+	})
+
+	assert.Equal(4, res.Contexts)
+	assert.Equal(2, res.Matches)
+	assert.Equal(1, res.Errors)
+	assert.Len(res.Predicates, 3)
+
+	a, b, c := res.Predicates[0], res.Predicates[1], res.Predicates[2]
+	assert.Equal("a > 1", a.Predicate)
+	assert.Equal(0, a.Branch)
+	assert.Equal(4, a.Calls)
+	assert.Equal(2, a.Matches)
+	assert.Equal(2, a.ShortCircuits)
+	assert.Equal(0.5, a.ShortCircuitRate())
+
+	assert.Equal(`b = "x"`, b.Predicate)
+	assert.Equal(2, b.Calls)
+	assert.Equal(1, b.Matches)
+	assert.Equal(1, b.ShortCircuits)
+
+	assert.Equal("c = TRUE", c.Predicate)
+	assert.Equal(1, c.Branch)
+	assert.Equal(3, c.Calls)
+	assert.Equal(1, c.Matches)
+	assert.Equal(1, c.Errors)
+	assert.Equal(0, c.ShortCircuits)
+}
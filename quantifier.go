@@ -0,0 +1,73 @@
+package matcher
+
+import (
+	"errors"
+	"reflect"
+)
+
+// quantifier compiles ANY(field) and ALL(field), which compare the elements
+// of a list instead of a single value: `ANY(tags) = "urgent"` holds when an
+// element of tags equals "urgent" and `ALL(scores) > 50` when every element
+// of scores is over 50. A value that is not a list is a list of one
+// element. A null or missing field matches neither, and an empty list only
+// matches ALL.
+func quantifier(c *Call, _ *options) (Func, error) {
+	if len(c.Args) != 1 || c.Args[0].Symbol == "" {
+		return nil, errors.New("want " + c.Name + "(field) followed by a comparison")
+	}
+	field := c.Args[0].Symbol
+	return func(ctx Context) (interface{}, error) {
+		v, _ := lookup(ctx, field)
+		return v, nil
+	}, nil
+}
+
+// isQuantifier reports whether the call is a built-in ANY or ALL.
+func isQuantifier(c *Call, o *options) bool {
+	if c.Name != "ANY" && c.Name != "ALL" {
+		return false
+	}
+	_, replaced := o.funcs[c.Name]
+	return !replaced
+}
+
+// quantify compares the elements of the list v.
+func (x *Condition) quantify(v interface{}) (bool, error) {
+	v, err := x.decodeRaw(v)
+	if err != nil || v == nil {
+		return false, err
+	}
+	all := x.Call.Name == "ALL"
+	var list []interface{}
+	switch l := v.(type) {
+	case []interface{}:
+		list = l
+	default:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			list = make([]interface{}, rv.Len())
+			for i := range list {
+				list[i] = rv.Index(i).Interface()
+			}
+		} else {
+			list = []interface{}{v}
+		}
+	}
+	for _, e := range list {
+		if e, err = x.decodeRaw(e); err != nil {
+			return false, err
+		}
+		var b bool
+		if x.test != nil {
+			b, err = x.test(e)
+		} else {
+			b, err = x.compare(e)
+		}
+		if err != nil {
+			return false, err
+		}
+		if b != all {
+			return b, nil
+		}
+	}
+	return all, nil
+}
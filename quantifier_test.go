@@ -0,0 +1,74 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuantifiers(t *testing.T) {
+	tests := []struct {
+		query string
+		value interface{}
+		want  bool
+	}{
+		{`ANY(v) = "urgent"`, []interface{}{"low", "urgent"}, true},
+		{`ANY(v) = "urgent"`, []interface{}{"low"}, false},
+		{`ANY(v) = "urgent"`, []string{"urgent"}, true},
+		{`ANY(v) = "urgent"`, "urgent", true},
+		{`ANY(v) = "urgent"`, []interface{}{}, false},
+		{`ANY(v) = "urgent"`, nil, false},
+		{`any(v) LIKE "urg%"`, []interface{}{"urgent"}, true},
+		{`ALL(v) > 50`, []interface{}{60.0, 70.0}, true},
+		{`ALL(v) > 50`, []interface{}{60.0, 40.0}, false},
+		{`ALL(v) > 50`, []int{51, 52}, true},
+		{`ALL(v) > 50`, []interface{}{}, true},
+		{`ALL(v) > 50`, nil, false},
+		{`ALL(v) > 50`, json.RawMessage(`[51, 52]`), true},
+		{`NOT ALL(v) > 50`, []interface{}{60.0, 40.0}, true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&matcher.Context{"v": tt.value})
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %v", tt.query, tt.value)
+	}
+
+	_, err := matcher.NewMatcher(`ANY(v)`, matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(t, err, matcher.ErrInvalidQuery)
+	_, err = matcher.NewMatcher(`ALL(1) = 1`, matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(t, err, matcher.ErrInvalidQuery)
+}
+
+func TestQuantifierNested(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`ANY(user.roles) = "admin"`, matcher.WithMismatchPolicy(matcher.MismatchError), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	ok, err := m.Test(&matcher.Context{"user": map[string]interface{}{"roles": []interface{}{"dev", "admin"}}})
+	assert.NoError(err)
+	assert.True(ok)
+	_, err = m.Test(&matcher.Context{"user": map[string]interface{}{"roles": []interface{}{true}}})
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+}
+
+func TestQuantifierExamples(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`ANY(tags) = "urgent" AND ALL(scores) > 50`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	match, miss, err := matcher.ExampleContexts(m.Expression)
+	assert.NoError(err)
+	ok, _ := m.Test(&match)
+	assert.True(ok)
+	ok, _ = m.Test(&miss)
+	assert.False(ok)
+
+	reports := m.WhyNot(&matcher.Context{"tags": []interface{}{"low"}, "scores": []interface{}{60.0}})
+	if assert.Len(reports, 1) && assert.Len(reports[0].Failures, 1) {
+		assert.Equal("tags", reports[0].Failures[0].Field)
+	}
+}
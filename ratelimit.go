@@ -0,0 +1,95 @@
+package matcher
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// rateLimit compiles RATE_LIMIT(n, window[, key]), which holds for at most n
+// contexts per window with a token bucket: the bucket holds n tokens, each
+// match takes one, and tokens come back at n per window. With a key field,
+// every value of the field has its own bucket.
+//
+// Used as the last conjunct, e.g. `level = "error" AND RATE_LIMIT(100, 1m)`,
+// it throttles the actions triggered by a rule.
+func rateLimit(c *Call, o *options) (Func, error) {
+	args := c.Args
+	if len(args) < 2 || len(args) > 3 || args[0].Value == nil || args[0].Value.Float == nil || args[1].Duration == nil ||
+		len(args) == 3 && args[2].Symbol == "" {
+		return nil, errors.New("want RATE_LIMIT(n, window[, key])")
+	}
+	n := *args[0].Value.Float
+	if !(n > 0) {
+		return nil, errors.New("n must be positive")
+	}
+	window := time.Duration(*args[1].Duration)
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	var field string
+	if len(args) == 3 {
+		field = args[2].Symbol
+	}
+	l := &tokenBuckets{capacity: n, rate: n / window.Seconds(), window: window, buckets: make(map[string]*tokenBucket)}
+	now := o.clock()
+	return func(ctx Context) (interface{}, error) {
+		var key string
+		if field != "" {
+			v, _ := lookup(ctx, field)
+			key = keyString(v)
+		}
+		return l.take(key, now()), nil
+	}, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBuckets is a set of token buckets by key.
+type tokenBuckets struct {
+	capacity float64
+	rate     float64 // tokens per second
+	window   time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// take takes a token from the bucket of key at t and reports whether there
+// was one.
+func (l *tokenBuckets) take(key string, t time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Buckets untouched for a whole window are full again and can go.
+	if t.Sub(l.lastSweep) > l.window {
+		for k, b := range l.buckets {
+			if t.Sub(b.last) >= l.window {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = t
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.capacity, last: t}
+		l.buckets[key] = b
+	}
+	if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * l.rate
+		if b.tokens > l.capacity {
+			b.tokens = l.capacity
+		}
+		b.last = t
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
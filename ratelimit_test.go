@@ -0,0 +1,59 @@
+package matcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`level = "error" and RATE_LIMIT(3, 1m)`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`level = "error" AND RATE_LIMIT(3, 1m)`, m.Expression.String())
+
+	matches := func(n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			ok, err := m.Test(&matcher.Context{"level": "error"})
+			assert.NoError(err)
+			if ok {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(3, matches(10))
+	clock.advance(20 * time.Second)
+	assert.Equal(1, matches(10))
+	clock.advance(10 * time.Minute)
+	assert.Equal(3, matches(10)) // the bucket holds no more than n
+}
+
+func TestRateLimitPerKey(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`RATE_LIMIT(1, 1h, user)`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	test := func(user string) bool {
+		ok, err := m.Test(&matcher.Context{"user": user})
+		assert.NoError(err)
+		return ok
+	}
+	assert.True(test("a"))
+	assert.True(test("b"))
+	assert.False(test("a"))
+	clock.advance(time.Hour)
+	assert.True(test("a"))
+}
+
+func TestRateLimitArgs(t *testing.T) {
+	for _, q := range []string{"RATE_LIMIT(1)", "RATE_LIMIT(0, 1m)", "RATE_LIMIT(1m, 1)", "RATE_LIMIT(1, 0s)", "RATE_LIMIT(1, 1m, 2)"} {
+		_, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, q)
+	}
+}
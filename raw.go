@@ -0,0 +1,25 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// decodeRaw decodes a json.RawMessage context value, so callers can leave
+// parts of a large payload undecoded and only pay for those a condition
+// refers to. Numbers are decoded as json.Number. Other values are returned
+// as they are.
+func (x *Condition) decodeRaw(v interface{}) (interface{}, error) {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		return v, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("%s: invalid JSON value: %w", x, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,43 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawMessage(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  bool
+	}{
+		{`status = 503`, true},
+		{`status >= 500 and region = "eu"`, true},
+		{`region = "us"`, false},
+		{`note = NULL`, true},
+		{`ok = TRUE`, true},
+		{`broken = 1`, false},
+	} {
+		m, err := matcher.NewMatcher(tc.query)
+		assert.NoError(t, err)
+		c := matcher.Context{
+			"status": json.RawMessage(`503`),
+			"region": json.RawMessage(`"eu"`),
+			"note":   json.RawMessage(`null`),
+			"ok":     json.RawMessage(`true`),
+			"body":   json.RawMessage(`{"large": [1, 2, 3]}`),
+		}
+		got, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, tc.query)
+		assert.Equal(t, json.RawMessage(`503`), c["status"], "context is not modified")
+	}
+
+	m, err := matcher.NewMatcher(`broken = 1`)
+	assert.NoError(t, err)
+	c := matcher.Context{"broken": json.RawMessage(`{`)}
+	_, err = m.Test(&c)
+	assert.EqualError(t, err, "broken = 1: invalid JSON value: unexpected EOF")
+}
@@ -0,0 +1,119 @@
+package matcher
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ContextFromRequest maps an HTTP request to a context with the keys
+//
+//   - method, e.g. "GET",
+//   - scheme, "http" or "https",
+//   - host, the Host header,
+//   - path, the unescaped URL path, and path_0, path_1, ... its non-empty
+//     segments,
+//   - query_<name> for the first value of every query parameter,
+//   - header_<name> for every header, all values joined with ", ",
+//   - cookie_<name> for every cookie,
+//   - client_ip, the host of RemoteAddr. Requests passing proxies carry the
+//     original client in headers such as X-Forwarded-For instead, which only
+//     a trusted proxy should be believed about.
+//
+// Names are lower case for headers, and in every name characters that can not
+// appear in a query identifier, such as dashes, are replaced by underscores:
+// X-Request-Id is queried as header_x_request_id.
+func ContextFromRequest(r *http.Request) Context {
+	c := Context{"method": r.Method, "host": r.Host, "path": r.URL.Path}
+	if r.TLS != nil {
+		c["scheme"] = "https"
+	} else {
+		c["scheme"] = "http"
+	}
+
+	i := 0
+	for _, seg := range strings.Split(r.URL.Path, "/") {
+		if seg != "" {
+			c["path_"+strconv.Itoa(i)] = seg
+			i++
+		}
+	}
+	for name, values := range r.URL.Query() {
+		if len(values) > 0 {
+			c["query_"+identifier(name)] = values[0]
+		}
+	}
+	for name, v := range ContextFromHeader(r.Header) {
+		c["header_"+name] = v
+	}
+	for _, cookie := range r.Cookies() {
+		c["cookie_"+identifier(cookie.Name)] = cookie.Value
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		c["client_ip"] = host
+	} else if r.RemoteAddr != "" {
+		c["client_ip"] = r.RemoteAddr
+	}
+	return c
+}
+
+// ContextFromHeader maps HTTP headers to a context, for proxies that only
+// look at headers. Names are lower case with dashes and other characters that
+// can not appear in a query identifier replaced by underscores, so
+// Content-Type is content_type, and the values of a repeated header are
+// joined with ", " as if they were sent in a single header.
+func ContextFromHeader(h http.Header) Context {
+	c := make(Context, len(h))
+	for name, values := range h {
+		c[identifier(strings.ToLower(name))] = strings.Join(values, ", ")
+	}
+	return c
+}
+
+// ContextFromValues maps query parameters or form values to a context. A
+// name with a single value maps to that value and a repeated name to a
+// []interface{} of its values; a trailing "[]" is removed from names, and the
+// other characters that can not appear in a query identifier are replaced by
+// underscores. Values that are numbers in their shortest form, such as "42"
+// or "-1.5", become float64, while values like "007" or "1e3" stay strings so
+// they still equal their string literal.
+func ContextFromValues(values url.Values) Context {
+	c := make(Context, len(values))
+	for name, vs := range values {
+		name = identifier(strings.TrimSuffix(name, "[]"))
+		switch len(vs) {
+		case 0:
+		case 1:
+			c[name] = formValue(vs[0])
+		default:
+			list := make([]interface{}, len(vs))
+			for i, v := range vs {
+				list[i] = formValue(v)
+			}
+			c[name] = list
+		}
+	}
+	return c
+}
+
+// formValue converts a form value to float64 if it is a number in its
+// shortest form.
+func formValue(s string) interface{} {
+	if n, ok := parseNumber(s); ok && strconv.FormatFloat(n, 'f', -1, 64) == s {
+		return n
+	}
+	return s
+}
+
+// identifier replaces the characters of name that can not appear in a query
+// identifier with underscores.
+func identifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
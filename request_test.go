@@ -0,0 +1,86 @@
+package matcher_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/users/a%20b/?page=2&page=3&sort-by=name", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+	r.AddCookie(&http.Cookie{Name: "session-id", Value: "s3"})
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	assert.Equal(t, matcher.Context{
+		"method":              "POST",
+		"scheme":              "http",
+		"host":                "api.example.com",
+		"path":                "/v1/users/a b/",
+		"path_0":              "v1",
+		"path_1":              "users",
+		"path_2":              "a b",
+		"query_page":          "2",
+		"query_sort_by":       "name",
+		"header_x_request_id": "abc",
+		"header_accept":       "text/html, application/json",
+		"header_cookie":       "session-id=s3",
+		"cookie_session_id":   "s3",
+		"client_ip":           "192.0.2.1",
+	}, matcher.ContextFromRequest(r))
+}
+
+func TestContextFromRequestQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/admin/users", nil)
+	r.TLS = &tls.ConnectionState{}
+	r.RemoteAddr = "[2001:db8::1]:443"
+
+	m, err := matcher.NewMatcher(`scheme = "https" and path_0 = "admin" and client_ip = "2001:db8::1"`)
+	assert.NoError(t, err)
+	c := matcher.ContextFromRequest(r)
+	ok, err := m.Test(&c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestContextFromValues(t *testing.T) {
+	v, err := url.ParseQuery("age=42&ratio=-1.5&zip=007&exp=1e3&tags[]=a&tags[]=b&ids=1&ids=2&user-name=bob&empty=")
+	assert.NoError(t, err)
+	assert.Equal(t, matcher.Context{
+		"age":       float64(42),
+		"ratio":     -1.5,
+		"zip":       "007",
+		"exp":       "1e3",
+		"tags":      []interface{}{"a", "b"},
+		"ids":       []interface{}{float64(1), float64(2)},
+		"user_name": "bob",
+		"empty":     "",
+	}, matcher.ContextFromValues(v))
+}
+
+func TestContextFromHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	h.Add("X-Api-Version", "2")
+	h.Add("Via", "1.1 a")
+	h.Add("Via", "1.1 b")
+	c := matcher.ContextFromHeader(h)
+	assert.Equal(t, matcher.Context{
+		"content_type":  "application/json",
+		"x_api_version": "2",
+		"via":           "1.1 a, 1.1 b",
+	}, c)
+
+	m, err := matcher.NewMatcher(`content_type = "application/json" and x_api_version >= 2`)
+	assert.NoError(t, err)
+	ok, err := m.Test(&c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,99 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// rollout compiles ROLLOUT(field, percent[, salt]), which holds for a stable
+// percent of the values of field: the value is hashed with the salt into one
+// of 10000 buckets, so the same value always gets the same answer and
+// raising percent only adds values. Contexts without the field do not match.
+//
+// The salt defaults to the flag name for a Flag and to "" otherwise.
+func rollout(c *Call, o *options) (Func, error) {
+	args := c.Args
+	if len(args) < 2 || len(args) > 3 {
+		return nil, errors.New("want ROLLOUT(field, percent[, salt])")
+	}
+	field := args[0].Symbol
+	if field == "" {
+		return nil, errors.New("first argument must be a field")
+	}
+	if args[1].Value == nil || args[1].Value.Float == nil {
+		return nil, errors.New("percent must be a number")
+	}
+	percent := *args[1].Value.Float
+	if !(percent >= 0 && percent <= 100) {
+		return nil, fmt.Errorf("percent %g is not between 0 and 100", percent)
+	}
+	salt := o.salt
+	if len(args) == 3 {
+		if args[2].Value == nil || args[2].Value.String == nil {
+			return nil, errors.New("salt must be a string")
+		}
+		salt = *args[2].Value.String
+	}
+
+	threshold := uint32(percent * 100)
+	return func(ctx Context) (interface{}, error) {
+		v, ok := lookup(ctx, field)
+		if !ok || v == nil {
+			return false, nil
+		}
+		return rolloutBucket(salt, v) < threshold, nil
+	}, nil
+}
+
+// rolloutBucket hashes a value into a bucket between 0 and 9999.
+func rolloutBucket(salt string, v interface{}) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(salt))
+	h.Write([]byte{0})
+	h.Write([]byte(keyString(v)))
+	return h.Sum32() % 10000
+}
+
+// keyString formats a context value as a key. Numbers of every type format
+// alike, so 7 and 7.0 are the same key, and integers, json.Number values
+// included, keep all their digits, so IDs beyond 2^53 do not collide.
+func keyString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if n, ok := toInteger(v); ok {
+		return n.String()
+	}
+	if f, ok := toFloat(v); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// Flag is a feature flag enabled for the contexts matching its rule, e.g.
+// `country = "JP" AND ROLLOUT(user_id, 25)`. A ROLLOUT in the rule buckets
+// values with the flag name unless it is given a salt, so two flags rolled out
+// to the same percentage reach different users.
+type Flag struct {
+	Name    string
+	Matcher *Matcher
+}
+
+// NewFlag compiles the rule of the flag name. Rules use the latest grammar
+// unless opts select another version.
+func NewFlag(name, rule string, opts ...Option) (*Flag, error) {
+	opts = append([]Option{WithSyntaxVersion(SyntaxVersion), func(o *options) { o.salt = name }}, opts...)
+	m, err := NewMatcher(rule, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("flag %s: %w", name, err)
+	}
+	return &Flag{Name: name, Matcher: m}, nil
+}
+
+// Enabled reports whether the flag is on for the context, typically
+// describing a user.
+func (f *Flag) Enabled(c Context) (bool, error) {
+	return f.Matcher.Test(&c)
+}
@@ -0,0 +1,143 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func rolloutShare(t *testing.T, m *matcher.Matcher, n int) map[int]bool {
+	on := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		ok, err := m.Test(&matcher.Context{"user_id": fmt.Sprintf("user-%d", i)})
+		assert.NoError(t, err)
+		if ok {
+			on[i] = true
+		}
+	}
+	return on
+}
+
+func TestRollout(t *testing.T) {
+	assert := assert.New(t)
+	m25, err := matcher.NewMatcher("ROLLOUT(user_id, 25)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	m50, err := matcher.NewMatcher("rollout(user_id, 50)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	on25, on50 := rolloutShare(t, m25, 10000), rolloutShare(t, m50, 10000)
+	assert.InDelta(2500, len(on25), 200)
+	assert.InDelta(5000, len(on50), 200)
+	for i := range on25 {
+		assert.True(on50[i], "raising the percentage keeps user-%d", i)
+	}
+
+	// Stable across matchers.
+	again, err := matcher.NewMatcher("ROLLOUT(user_id, 25)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(on25, rolloutShare(t, again, 10000))
+
+	ok, err := m50.Test(&matcher.Context{"other": 1.0})
+	assert.NoError(err)
+	assert.False(ok)
+
+	all, err := matcher.NewMatcher("ROLLOUT(user_id, 100)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Len(rolloutShare(t, all, 1000), 1000)
+}
+
+func TestRolloutComposes(t *testing.T) {
+	m, err := matcher.NewMatcher(`country = "JP" AND ROLLOUT(user_id, 100)`, matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+
+	ok, err := m.Test(&matcher.Context{"country": "JP", "user_id": 7.0})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = m.Test(&matcher.Context{"country": "US", "user_id": 7.0})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRolloutLargeIntegers(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("ROLLOUT(user_id, 50)", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	test := func(id interface{}) bool {
+		ok, err := m.Test(&matcher.Context{"user_id": id})
+		assert.NoError(err)
+		return ok
+	}
+
+	// 2^53+4k+1 is the same float64 as 2^53+4k, but not the same user.
+	differ := 0
+	for k := int64(0); k < 100; k++ {
+		id := int64(1)<<53 + 4*k
+		a, b := test(json.Number(strconv.FormatInt(id, 10))), test(json.Number(strconv.FormatInt(id+1, 10)))
+		if a != b {
+			differ++
+		}
+		assert.Equal(a, test(id))
+		assert.Equal(b, test(uint64(id+1)))
+	}
+	assert.InDelta(50, differ, 20)
+
+	// Numbers of every type share their buckets.
+	assert.Equal(test(7.0), test(json.Number("7")))
+	assert.Equal(test(7.0), test(json.Number("7.0")))
+	assert.Equal(test(7.0), test(7))
+}
+
+func TestRolloutArgs(t *testing.T) {
+	for _, q := range []string{
+		"ROLLOUT(user_id)",
+		"ROLLOUT(25, user_id)",
+		"ROLLOUT(user_id, 101)",
+		`ROLLOUT(user_id, "25")`,
+		"ROLLOUT(user_id, 25, 1)",
+	} {
+		_, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, q)
+	}
+}
+
+func TestFlag(t *testing.T) {
+	assert := assert.New(t)
+	a, err := matcher.NewFlag("new-checkout", `country = "JP" and ROLLOUT(user_id, 50)`)
+	assert.NoError(err)
+	b, err := matcher.NewFlag("dark-mode", `country = "JP" and ROLLOUT(user_id, 50)`)
+	assert.NoError(err)
+
+	onB, same := 0, 0
+	for i := 0; i < 1000; i++ {
+		user := matcher.Context{"country": "JP", "user_id": float64(i)}
+		x, err := a.Enabled(user)
+		assert.NoError(err)
+		y, err := b.Enabled(user)
+		assert.NoError(err)
+		if y {
+			onB++
+		}
+		if x == y {
+			same++
+		}
+	}
+	assert.InDelta(500, onB, 60)
+	// Independent buckets agree about half of the time.
+	assert.InDelta(500, same, 80)
+
+	salted, err := matcher.NewFlag("x", `ROLLOUT(user_id, 50, "dark-mode")`)
+	assert.NoError(err)
+	for i := 0; i < 100; i++ {
+		user := matcher.Context{"country": "JP", "user_id": float64(i)}
+		x, _ := salted.Enabled(user)
+		y, _ := b.Enabled(user)
+		assert.Equal(x, y)
+	}
+
+	_, err = matcher.NewFlag("broken", "ROLLOUT(")
+	assert.Error(err)
+}
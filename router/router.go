@@ -0,0 +1,230 @@
+// Package router forwards incoming JSON webhooks to the destinations of the
+// routes whose matcher query matches them.
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kuwa72/matcher"
+)
+
+// DefaultMaxBodySize is the largest webhook body accepted when
+// Router.MaxBodySize is zero.
+const DefaultMaxBodySize = 1 << 20
+
+// HeaderPrefix is prepended to request header names to form context keys:
+// X-GitHub-Event is queried as header_x_github_event.
+const HeaderPrefix = "header_"
+
+// Route forwards the webhooks matching Query to every destination URL.
+type Route struct {
+	Name         string   `json:"name"`
+	Query        string   `json:"query"`
+	Priority     int      `json:"priority"`
+	Destinations []string `json:"destinations"`
+}
+
+// Router is an http.Handler evaluating webhooks against its routes.
+//
+// A webhook body must be a JSON object. Its top-level keys and the request
+// headers, named as described for HeaderPrefix, form the context the route
+// queries are tested against; body keys win over headers. The body is
+// forwarded unchanged with a POST to the destinations of all matching routes
+// together with its Content-Type and the headers named in Headers.
+//
+// Webhooks are acknowledged before they are forwarded, so a failing
+// destination never makes the sender redeliver to the others. The response
+// is 202 with the names of the matching routes as a JSON array, 400 for a
+// body that is not a JSON object and 422 when a query fails to evaluate.
+// Forwards still failing after the retries are logged to ErrorLog.
+type Router struct {
+	// Client sends the forwarded requests; http.DefaultClient when nil.
+	Client *http.Client
+	// Headers are the request headers forwarded besides Content-Type.
+	// Credentials such as Authorization and Cookie are meant for the router
+	// and are only forwarded when listed.
+	Headers []string
+	// Retries is how often a forward failing with a network error, 429 or
+	// a 5xx response is retried.
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every
+	// further retry.
+	Backoff time.Duration
+	// MaxBodySize limits the accepted body size; DefaultMaxBodySize when
+	// zero.
+	MaxBodySize int64
+	// ErrorLog logs failed forwards; the standard logger when nil.
+	ErrorLog *log.Logger
+
+	rules    *matcher.RuleSet
+	inflight sync.WaitGroup
+}
+
+// New compiles the route queries with opts.
+func New(routes []Route, opts ...matcher.Option) (*Router, error) {
+	rules := make([]matcher.Rule, len(routes))
+	for i, r := range routes {
+		rules[i] = matcher.Rule{Name: r.Name, Query: r.Query, Priority: r.Priority, Action: r.Destinations}
+	}
+	s, err := matcher.NewRuleSet(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Router{Backoff: time.Second, rules: s}, nil
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := rt.MaxBodySize
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := requestContext(r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	matched, err := rt.rules.EvaluateAll(&c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	names := []string{}
+	for _, m := range matched {
+		names = append(names, m.Name)
+	}
+	header := rt.forwardHeader(r.Header)
+	rt.inflight.Add(1)
+	go func() {
+		defer rt.inflight.Done()
+		for _, err := range rt.forwardAll(matched, header, body) {
+			rt.logf("%v", err)
+		}
+	}()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(names)
+}
+
+// Wait blocks until the forwards of all acknowledged webhooks are done.
+func (rt *Router) Wait() {
+	rt.inflight.Wait()
+}
+
+func (rt *Router) logf(format string, args ...interface{}) {
+	if rt.ErrorLog != nil {
+		rt.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// forwardHeader returns the headers of a webhook that are forwarded.
+func (rt *Router) forwardHeader(header http.Header) http.Header {
+	h := make(http.Header)
+	for _, k := range append([]string{"Content-Type"}, rt.Headers...) {
+		if v := header.Values(k); len(v) > 0 {
+			h[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+		}
+	}
+	return h
+}
+
+// requestContext builds the context of a webhook from its headers and body.
+func requestContext(header http.Header, body []byte) (matcher.Context, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.New("body is not a JSON object")
+	}
+
+	c := make(matcher.Context, len(header)+len(doc))
+	for k, v := range header {
+		c[HeaderPrefix+strings.ToLower(strings.ReplaceAll(k, "-", "_"))] = strings.Join(v, ", ")
+	}
+	for k, v := range doc {
+		c[k] = v
+	}
+	return c, nil
+}
+
+// forwardAll forwards the body to the destinations of every matched route
+// concurrently and returns the errors of the failed forwards.
+func (rt *Router) forwardAll(matched []*matcher.Rule, header http.Header, body []byte) []error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, m := range matched {
+		for _, dest := range m.Action.([]string) {
+			wg.Add(1)
+			go func(name, dest string) {
+				defer wg.Done()
+				if err := rt.forward(dest, header, body); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("route %s: %w", name, err))
+					mu.Unlock()
+				}
+			}(m.Name, dest)
+		}
+	}
+	wg.Wait()
+	return errs
+}
+
+// forward posts body to dest, retrying network errors, 429 and 5xx
+// responses with exponential backoff.
+func (rt *Router) forward(dest string, header http.Header, body []byte) error {
+	client := rt.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	backoff := rt.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, dest, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header = header.Clone()
+
+		resp, err := client.Do(req)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("POST %s: %s", dest, resp.Status)
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+				return err
+			}
+		}
+		if attempt >= rt.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,121 @@
+package router_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kuwa72/matcher/router"
+	"github.com/stretchr/testify/assert"
+)
+
+// sink records the bodies posted to it.
+type sink struct {
+	mu     sync.Mutex
+	bodies []string
+	events []string
+	auths  []string
+}
+
+func (s *sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.bodies = append(s.bodies, string(b))
+	s.events = append(s.events, r.Header.Get("X-GitHub-Event"))
+	s.auths = append(s.auths, r.Header.Get("Authorization"))
+	s.mu.Unlock()
+}
+
+func TestRouter(t *testing.T) {
+	assert := assert.New(t)
+	var pushes, all sink
+	pushSrv, allSrv := httptest.NewServer(&pushes), httptest.NewServer(&all)
+	defer pushSrv.Close()
+	defer allSrv.Close()
+
+	rt, err := router.New([]router.Route{
+		{Name: "all", Query: `header_x_github_event <> NULL`, Destinations: []string{allSrv.URL}},
+		{Name: "push", Query: `header_x_github_event = "push" and ref = "main"`, Priority: 1, Destinations: []string{pushSrv.URL}},
+	})
+	assert.NoError(err)
+	rt.Headers = []string{"X-GitHub-Event"}
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	post := func(event, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(err)
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, strings.TrimSpace(string(b))
+	}
+
+	resp, names := post("push", `{"ref": "main"}`)
+	assert.Equal(http.StatusAccepted, resp.StatusCode)
+	assert.Equal(`["push","all"]`, names)
+
+	resp, names = post("issues", `{"ref": "main"}`)
+	assert.Equal(http.StatusAccepted, resp.StatusCode)
+	assert.Equal(`["all"]`, names)
+
+	rt.Wait()
+	assert.Equal([]string{`{"ref": "main"}`}, pushes.bodies)
+	assert.Equal([]string{"push"}, pushes.events)
+	assert.Equal([]string{""}, pushes.auths)
+	assert.Len(all.bodies, 2)
+
+	resp, _ = post("push", `[1, 2]`)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	resp, _ = post("push", `null`)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRouterRetries(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer dest.Close()
+
+	rt, err := router.New([]router.Route{{Name: "flaky", Query: `a = 1`, Destinations: []string{dest.URL}}})
+	assert.NoError(err)
+	rt.Backoff = 0
+	var logs bytes.Buffer
+	rt.ErrorLog = log.New(&logs, "", 0)
+
+	rt.Retries = 2
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	rt.Wait()
+	assert.Equal(http.StatusAccepted, w.Code)
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+	assert.Empty(logs.String())
+
+	atomic.StoreInt32(&calls, 0)
+	rt.Retries = 1
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	rt.Wait()
+	assert.Equal(http.StatusAccepted, w.Code)
+	assert.Contains(logs.String(), "route flaky: ")
+}
+
+func TestRouterMethod(t *testing.T) {
+	rt, err := router.New(nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,90 @@
+package matcher
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RouteTable maps queries to payloads, such as handlers or destinations, and
+// looks up the payloads of all queries matching a context. Queries are
+// compiled once, when they are added.
+//
+// A RouteTable is safe for concurrent use by multiple goroutines; routes can
+// be added and removed while contexts are matched. A Match running
+// concurrently with Add or Remove sees the routes from before or after the
+// change, never a mix.
+type RouteTable[T any] struct {
+	opts []Option
+
+	mu     sync.RWMutex
+	routes []*route[T] // replaced, never modified, on change
+}
+
+// route is a compiled query of a RouteTable with its payload.
+type route[T any] struct {
+	query   string
+	matcher *Matcher
+	payload T
+}
+
+// NewRouteTable returns an empty table compiling queries with opts.
+func NewRouteTable[T any](opts ...Option) *RouteTable[T] {
+	return &RouteTable[T]{opts: opts}
+}
+
+// Add compiles query and registers payload for it. A query can be added
+// several times with different payloads.
+func (t *RouteTable[T]) Add(query string, payload T) error {
+	m, err := NewMatcher(query, t.opts...)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	routes := make([]*route[T], len(t.routes), len(t.routes)+1)
+	copy(routes, t.routes)
+	t.routes = append(routes, &route[T]{query: query, matcher: m, payload: payload})
+	return nil
+}
+
+// Remove removes every route of query and returns how many were removed.
+func (t *RouteTable[T]) Remove(query string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	routes := make([]*route[T], 0, len(t.routes))
+	for _, r := range t.routes {
+		if r.query != query {
+			routes = append(routes, r)
+		}
+	}
+	n := len(t.routes) - len(routes)
+	t.routes = routes
+	return n
+}
+
+// Len returns the number of routes.
+func (t *RouteTable[T]) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.routes)
+}
+
+// Match returns the payloads of all routes matching the context, in the
+// order they were added.
+func (t *RouteTable[T]) Match(c *Context) ([]T, error) {
+	t.mu.RLock()
+	routes := t.routes
+	t.mu.RUnlock()
+
+	var out []T
+	for _, r := range routes {
+		ok, err := r.matcher.Test(c)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", r.query, err)
+		}
+		if ok {
+			out = append(out, r.payload)
+		}
+	}
+	return out, nil
+}
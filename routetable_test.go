@@ -0,0 +1,74 @@
+package matcher_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTable(t *testing.T) {
+	assert := assert.New(t)
+	rt := matcher.NewRouteTable[string]()
+	assert.NoError(rt.Add(`level = "error"`, "pager"))
+	assert.NoError(rt.Add(`service = "billing"`, "billing-team"))
+	assert.NoError(rt.Add(`level = "error"`, "archive"))
+	assert.Error(rt.Add(`level =`, "broken"))
+	assert.Equal(3, rt.Len())
+
+	c := matcher.Context{"level": "error", "service": "billing"}
+	got, err := rt.Match(&c)
+	assert.NoError(err)
+	assert.Equal([]string{"pager", "billing-team", "archive"}, got)
+
+	assert.Equal(2, rt.Remove(`level = "error"`))
+	assert.Equal(0, rt.Remove(`level = "error"`))
+	got, err = rt.Match(&c)
+	assert.NoError(err)
+	assert.Equal([]string{"billing-team"}, got)
+
+	c = matcher.Context{"service": "search"}
+	got, err = rt.Match(&c)
+	assert.NoError(err)
+	assert.Empty(got)
+}
+
+func TestRouteTableError(t *testing.T) {
+	rt := matcher.NewRouteTable[int](matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, rt.Add(`a > 1`, 1))
+	c := matcher.Context{"a": true}
+	_, err := rt.Match(&c)
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+}
+
+func TestRouteTableConcurrent(t *testing.T) {
+	rt := matcher.NewRouteTable[func() int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		i := i
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			q := fmt.Sprintf("n = %d", i)
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, rt.Add(q, func() int { return i }))
+				rt.Remove(q)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			c := matcher.Context{"n": i}
+			for j := 0; j < 50; j++ {
+				handlers, err := rt.Match(&c)
+				assert.NoError(t, err)
+				for _, h := range handlers {
+					assert.Equal(t, i, h())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 0, rt.Len())
+}
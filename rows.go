@@ -0,0 +1,217 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Row is a condition of a query in the flat form visual query builders work
+// with. Rows with the same Group are combined with AND, and groups with OR,
+// in the order of their first row.
+type Row struct {
+	Group int `json:"group"`
+	// Not negates the condition.
+	Not bool `json:"not,omitempty"`
+	// Query is a parenthesized expression in query syntax, without the
+	// parentheses, for a condition that does not fit in a row. Such rows
+	// have no Field, Function, Operator or Value.
+	Query string `json:"query,omitempty"`
+	// Field is the compared field, or empty for a function call.
+	Field string `json:"field,omitempty"`
+	// Function is a function call in query syntax, such as
+	// "ROLLOUT(user_id, 25)".
+	Function string `json:"function,omitempty"`
+	// Operator is empty for a function call used as a condition on its
+	// own.
+	Operator string `json:"operator,omitempty"`
+	// Type is the type of Value: "number", "string", "boolean", "null",
+	// "duration", "time", or "field" when comparing with another field.
+	Type string `json:"type,omitempty"`
+	// Value is a float64 for numbers, or the strings "NaN", "Inf" and
+	// "-Inf"; a string for strings, for durations, like "5m", for times,
+	// like "2024-01-01", and for fields; a bool for booleans and nil for null.
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Rows converts an expression to rows, one per condition.
+func (e *Expression) Rows() []Row {
+	var rows []Row
+	for i, x := range e.Or {
+		for _, c := range x.And {
+			r := Row{Group: i, Not: c.Not, Field: c.Symbol}
+			if c.Group != nil {
+				r.Query = c.Group.String()
+			}
+			if c.Call != nil {
+				r.Function = c.Call.String()
+			}
+			switch {
+			case c.Compare == nil:
+			case c.Compare.Field != "":
+				r.Operator, r.Type, r.Value = c.Compare.Operator, "field", c.Compare.Field
+			default:
+				r.Operator = c.Compare.Operator
+				r.Type, r.Value = rowValue(c.Compare.Value)
+			}
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+func rowValue(v *Value) (string, interface{}) {
+	switch {
+	case v.Float != nil:
+		f := *v.Float
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "number", v.literal()
+		}
+		return "number", f
+	case v.String != nil:
+		return "string", *v.String
+	case v.Boolean != nil:
+		return "boolean", bool(*v.Boolean)
+	case v.Duration != nil:
+		return "duration", v.Duration.literal()
+	case v.Time != nil:
+		return "time", string(*v.Time)
+	}
+	return "null", nil
+}
+
+// FromRows converts rows back to an expression. The expression is validated
+// but not compiled; use its String form with NewMatcher to evaluate it.
+func FromRows(rows []Row) (*Expression, error) {
+	e := &Expression{}
+	groups := map[int]*OrCondition{}
+	for i, r := range rows {
+		c, err := r.condition()
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidQuery, i, err)
+		}
+		x, ok := groups[r.Group]
+		if !ok {
+			x = &OrCondition{}
+			groups[r.Group] = x
+			e.Or = append(e.Or, x)
+		}
+		x.And = append(x.And, c)
+	}
+	if len(e.Or) == 0 {
+		return nil, fmt.Errorf("%w: no rows", ErrInvalidQuery)
+	}
+	return e, e.validate()
+}
+
+// condition converts a row to a condition.
+func (r Row) condition() (*Condition, error) {
+	c := &Condition{Not: r.Not, Symbol: r.Field}
+	if r.Query != "" {
+		c.Symbol, c.Group = "", &Expression{}
+		if err := sharedParser().ParseString("", r.Query, c.Group); err != nil {
+			return nil, fmt.Errorf("invalid query %q: %v", r.Query, err)
+		}
+		return c, nil
+	}
+	if r.Function != "" {
+		f := &Expression{}
+		if err := sharedParser().ParseString("", r.Function, f); err != nil || len(f.Or) != 1 || len(f.Or[0].And) != 1 ||
+			f.Or[0].And[0].Call == nil || f.Or[0].And[0].Compare != nil || f.Or[0].And[0].Not {
+			return nil, fmt.Errorf("invalid function call %q", r.Function)
+		}
+		c.Symbol, c.Call = "", f.Or[0].And[0].Call
+	} else if r.Field == "" || !isPath(r.Field) {
+		return nil, fmt.Errorf("invalid field %q", r.Field)
+	}
+	if r.Operator == "" && c.Call != nil {
+		return c, nil
+	}
+	switch r.Operator {
+	case "=", "<>", "!=", ">", ">=", "<", "<=", "LIKE", "NOT LIKE":
+	default:
+		return nil, fmt.Errorf("invalid operator %q", r.Operator)
+	}
+	if r.Type == "field" {
+		f, ok := r.Value.(string)
+		if !ok || !isPath(f) {
+			return nil, fmt.Errorf("invalid field %v", r.Value)
+		}
+		c.Compare = &Compare{Operator: r.Operator, Field: f}
+		return c, nil
+	}
+	v, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	c.Compare = &Compare{Operator: r.Operator, Value: v}
+	return c, nil
+}
+
+// value converts the value of a row to a literal.
+func (r Row) value() (*Value, error) {
+	switch r.Type {
+	case "number":
+		var f float64
+		var err error
+		switch n := r.Value.(type) {
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		case json.Number:
+			f, err = n.Float64()
+		case string:
+			f, err = strconv.ParseFloat(n, 64)
+		default:
+			err = fmt.Errorf("%T", n)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %v", r.Value)
+		}
+		return &Value{Float: &f}, nil
+	case "string":
+		s, ok := r.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string %v", r.Value)
+		}
+		return &Value{String: &s}, nil
+	case "boolean":
+		b, ok := r.Value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid boolean %v", r.Value)
+		}
+		v := Boolean(b)
+		return &Value{Boolean: &v}, nil
+	case "duration":
+		s, _ := r.Value.(string)
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %v", r.Value)
+		}
+		v := Duration(d)
+		return &Value{Duration: &v}, nil
+	case "time":
+		var t Timestamp
+		s, _ := r.Value.(string)
+		if err := t.Capture([]string{s}); err != nil {
+			return nil, err
+		}
+		return &Value{Time: &t}, nil
+	case "null":
+		return &Value{Null: true}, nil
+	}
+	return nil, fmt.Errorf("invalid type %q", r.Type)
+}
+
+// isIdentifier reports whether s can be written as a field in a query.
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,59 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRows(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`age >= 18 and country = "JP" or ROLLOUT(user_id, 25) and vip = TRUE or deleted = NULL and ttl < 5m or score <> NaN`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	rows := m.Expression.Rows()
+	assert.Equal([]matcher.Row{
+		{Group: 0, Field: "age", Operator: ">=", Type: "number", Value: float64(18)},
+		{Group: 0, Field: "country", Operator: "=", Type: "string", Value: "JP"},
+		{Group: 1, Function: "ROLLOUT(user_id, 25)"},
+		{Group: 1, Field: "vip", Operator: "=", Type: "boolean", Value: true},
+		{Group: 2, Field: "deleted", Operator: "=", Type: "null"},
+		{Group: 2, Field: "ttl", Operator: "<", Type: "duration", Value: "5m"},
+		{Group: 3, Field: "score", Operator: "<>", Type: "number", Value: "NaN"},
+	}, rows)
+
+	b, err := json.Marshal(rows)
+	assert.NoError(err)
+	var decoded []matcher.Row
+	assert.NoError(json.Unmarshal(b, &decoded))
+	e, err := matcher.FromRows(decoded)
+	assert.NoError(err)
+	assert.Equal(m.Expression.String(), e.String())
+}
+
+func TestFromRows(t *testing.T) {
+	e, err := matcher.FromRows([]matcher.Row{
+		{Group: 5, Field: "a", Operator: "=", Type: "number", Value: json.Number("1")},
+		{Group: 2, Field: "b", Operator: ">", Type: "number", Value: 2},
+		{Group: 5, Function: "count_over(1m)", Operator: ">", Type: "number", Value: 3.0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a = 1 AND count_over(1m) > 3 OR b > 2", e.String())
+
+	for _, rows := range [][]matcher.Row{
+		nil,
+		{{Field: "a b", Operator: "=", Type: "number", Value: 1.0}},
+		{{Field: "a", Operator: "~", Type: "number", Value: 1.0}},
+		{{Field: "a", Operator: "=", Type: "number", Value: "x"}},
+		{{Field: "a", Operator: "=", Type: "string", Value: 1.0}},
+		{{Field: "a", Operator: "=", Type: "date", Value: "2024"}},
+		{{Field: "a", Operator: "="}},
+		{{Field: "a", Operator: ">", Type: "boolean", Value: true}},
+		{{Function: "a = 1"}},
+		{{Field: "a"}},
+	} {
+		_, err := matcher.FromRows(rows)
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, "%v", rows)
+	}
+}
@@ -0,0 +1,107 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Rule is a named query with a priority and an arbitrary action, such as a
+// destination or a handler, for the caller to act on when the rule matches.
+type Rule struct {
+	Name     string
+	Query    string
+	Priority int
+	Action   interface{}
+
+	matcher *Matcher
+}
+
+// Matcher returns the compiled query of a rule taken from a RuleSet.
+func (r *Rule) Matcher() *Matcher {
+	return r.matcher
+}
+
+// RuleSet evaluates a list of rules in priority order. Rules with a higher
+// Priority come first; rules with the same priority keep the order they were
+// given in.
+//
+// Rules whose every OR branch requires a field to equal a string literal,
+// such as `type = "order" AND total > 100`, are only evaluated against
+// contexts holding one of these values, which lets large rule sets skip most
+// of their rules. Skipped rules are not audited, and errors they would have
+// raised are not reported.
+//
+// A RuleSet is safe for concurrent use by multiple goroutines.
+type RuleSet struct {
+	rules     []*Rule
+	prefilter *prefilter
+}
+
+// NewRuleSet compiles the queries of rules with opts. The rule names are
+// the rule IDs of audit records.
+func NewRuleSet(rules []Rule, opts ...Option) (*RuleSet, error) {
+	s := &RuleSet{rules: make([]*Rule, len(rules))}
+	for i := range rules {
+		r := rules[i]
+		m, err := NewMatcher(r.Query, append(opts[:len(opts):len(opts)], WithRuleID(r.Name))...)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.matcher = m
+		s.rules[i] = &r
+	}
+	sortRules(s.rules)
+	s.prefilter = newPrefilter(s.rules)
+	return s, nil
+}
+
+// sortRules sorts rules by descending priority, keeping the order of rules
+// with the same priority.
+func sortRules(rules []*Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+}
+
+// Rules returns the rules in evaluation order.
+func (s *RuleSet) Rules() []*Rule {
+	return s.rules
+}
+
+// EvaluateFirst returns the highest-priority rule matching the context, or
+// nil when no rule matches. Rules after the first match are not evaluated.
+func (s *RuleSet) EvaluateFirst(c *Context) (*Rule, error) {
+	candidates := s.prefilter.candidates(*c, len(s.rules))
+	for i, r := range s.rules {
+		if !candidates[i] {
+			continue
+		}
+		ok, err := r.matcher.Test(c)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		if ok {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// EvaluateAll returns every rule matching the context, in priority order.
+func (s *RuleSet) EvaluateAll(c *Context) ([]*Rule, error) {
+	candidates := s.prefilter.candidates(*c, len(s.rules))
+	var out []*Rule
+	for i, r := range s.rules {
+		if !candidates[i] {
+			continue
+		}
+		ok, err := r.matcher.Test(c)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		if ok {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleSet(t *testing.T) {
+	assert := assert.New(t)
+	s, err := matcher.NewRuleSet([]matcher.Rule{
+		{Name: "errors", Query: `level = "error"`, Priority: 1, Action: "pager"},
+		{Name: "all", Query: `level <> NULL`, Action: "archive"},
+		{Name: "billing", Query: `level = "error" and service = "billing"`, Priority: 10, Action: "billing-team"},
+		{Name: "also-errors", Query: `level = "error"`, Priority: 1, Action: "slack"},
+	})
+	assert.NoError(err)
+
+	var names []string
+	for _, r := range s.Rules() {
+		names = append(names, r.Name)
+	}
+	assert.Equal([]string{"billing", "errors", "also-errors", "all"}, names)
+
+	r, err := s.EvaluateFirst(&matcher.Context{"level": "error", "service": "billing"})
+	assert.NoError(err)
+	assert.Equal("billing", r.Name)
+	assert.Equal("billing-team", r.Action)
+
+	r, err = s.EvaluateFirst(&matcher.Context{"level": "error", "service": "search"})
+	assert.NoError(err)
+	assert.Equal("errors", r.Name)
+
+	all, err := s.EvaluateAll(&matcher.Context{"level": "error", "service": "search"})
+	assert.NoError(err)
+	var actions []interface{}
+	for _, r := range all {
+		actions = append(actions, r.Action)
+	}
+	assert.Equal([]interface{}{"pager", "slack", "archive"}, actions)
+
+	r, err = s.EvaluateFirst(&matcher.Context{"service": "search"})
+	assert.NoError(err)
+	assert.Nil(r)
+}
+
+func TestRuleSetErrors(t *testing.T) {
+	_, err := matcher.NewRuleSet([]matcher.Rule{{Name: "broken", Query: "a ="}})
+	var pe *matcher.ParseError
+	assert.ErrorAs(t, err, &pe)
+	assert.Contains(t, err.Error(), "rule broken: ")
+
+	s, err := matcher.NewRuleSet([]matcher.Rule{{Name: "strict", Query: `a = "x"`}},
+		matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, err)
+	_, err = s.EvaluateAll(&matcher.Context{"a": 1.0})
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+}
@@ -0,0 +1,96 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// absent is returned by a Func when its result is undefined, like LOWER of a
+// missing field. A comparison with it never matches, as for missing fields.
+var absent = new(struct{})
+
+// scalar returns the factory of a function of one field, like LOWER(name).
+// f is called with the value of the field and returns the result, or false
+// if the value has a type the function does not take. A missing or null
+// field, or one of the wrong type, gives no result, so a comparison with the
+// call never matches.
+func scalar(f func(v interface{}) (interface{}, bool)) factory {
+	return func(c *Call, _ *options) (Func, error) {
+		if len(c.Args) != 1 || c.Args[0].Symbol == "" {
+			return nil, errors.New("want " + c.Name + "(field) followed by a comparison")
+		}
+		field := c.Args[0].Symbol
+		return func(ctx Context) (interface{}, error) {
+			v, ok := lookup(ctx, field)
+			if raw, isRaw := v.(json.RawMessage); isRaw {
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.UseNumber()
+				if err := dec.Decode(&v); err != nil {
+					return nil, err
+				}
+			}
+			if !ok || v == nil {
+				return absent, nil
+			}
+			if v, ok = f(v); !ok {
+				return absent, nil
+			}
+			return v, nil
+		}, nil
+	}
+}
+
+// isScalar reports whether the call is one of the built-in scalar functions.
+func isScalar(c *Call, o *options) bool {
+	switch c.Name {
+	case "LOWER", "UPPER", "TRIM", "LEN", "ABS":
+	default:
+		return false
+	}
+	_, replaced := o.funcs[c.Name]
+	return !replaced
+}
+
+// stringFunc adapts a function of strings to scalar.
+func stringFunc(f func(string) string) func(v interface{}) (interface{}, bool) {
+	return func(v interface{}) (interface{}, bool) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		return f(s), true
+	}
+}
+
+// length is LEN: the number of characters of a string, or of elements of a
+// list or an object.
+func length(v interface{}) (interface{}, bool) {
+	if s, ok := v.(string); ok {
+		return float64(utf8.RuneCountInString(s)), true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(rv.Len()), true
+	}
+	return nil, false
+}
+
+// abs is ABS: the absolute value of a number.
+func abs(v interface{}) (interface{}, bool) {
+	f, ok := toFloat(v)
+	if !ok {
+		return nil, false
+	}
+	return math.Abs(f), true
+}
+
+var (
+	lower = scalar(stringFunc(strings.ToLower))
+	upper = scalar(stringFunc(strings.ToUpper))
+	trim  = scalar(stringFunc(strings.TrimSpace))
+)
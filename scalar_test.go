@@ -0,0 +1,62 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalar(t *testing.T) {
+	tests := []struct {
+		query string
+		ctx   matcher.Context
+		want  bool
+	}{
+		{`LOWER(name) = "john"`, matcher.Context{"name": "JoHN"}, true},
+		{`upper(name) = "JOHN"`, matcher.Context{"name": "john"}, true},
+		{`TRIM(name) = "john"`, matcher.Context{"name": "  john\n"}, true},
+		{`LEN(name) = 4`, matcher.Context{"name": "日本語だ"}, true},
+		{`LEN(tags) > 2`, matcher.Context{"tags": []interface{}{"a", "b", "c"}}, true},
+		{`LEN(tags) > 2`, matcher.Context{"tags": []string{"a", "b"}}, false},
+		{`LEN(tags) = 3`, matcher.Context{"tags": json.RawMessage(`["a","b","c"]`)}, true},
+		{`LEN(user) = 1`, matcher.Context{"user": map[string]interface{}{"id": 1}}, true},
+		{`ABS(delta) < 5`, matcher.Context{"delta": -3}, true},
+		{`ABS(delta) < 5`, matcher.Context{"delta": -7.5}, false},
+		{`LOWER(user.name) LIKE "jo%"`, matcher.Context{"user": matcher.Context{"name": "John"}}, true},
+		{`LOWER(name) != "john"`, matcher.Context{}, false},
+		{`LOWER(name) = NULL`, matcher.Context{"name": nil}, false},
+		{`LOWER(name) != "john"`, matcher.Context{"name": 1.0}, false},
+		{`ABS(name) > 0`, matcher.Context{"name": "x"}, false},
+		{`NOT LOWER(name) = "john"`, matcher.Context{}, true},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		ok, err := m.Test(&tt.ctx)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, ok, "%s with %v", tt.query, tt.ctx)
+	}
+}
+
+func TestScalarInvalid(t *testing.T) {
+	assert := assert.New(t)
+	for _, q := range []string{`LOWER(name)`, `LEN(a, b) = 1`, `ABS(1) = 1`} {
+		_, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.ErrorIs(err, matcher.ErrInvalidQuery, q)
+	}
+
+	m, err := matcher.NewMatcher(`LOWER(name) = "JOHN"`, matcher.WithFunction("lower",
+		func(args []*matcher.Arg) (matcher.Func, error) {
+			return func(ctx matcher.Context) (interface{}, error) {
+				return ctx[args[0].Symbol], nil
+			}, nil
+		}), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	ok, err := m.Test(&matcher.Context{"name": "JOHN"})
+	assert.NoError(err)
+	assert.True(ok)
+}
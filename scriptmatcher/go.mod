@@ -0,0 +1,19 @@
+module github.com/kuwa72/matcher/scriptmatcher
+
+go 1.25.0
+
+require (
+	github.com/kuwa72/matcher v0.1.0
+	github.com/stretchr/testify v1.8.1
+	go.starlark.net v0.0.0-20260908191801-89a6a09411d5
+)
+
+require (
+	github.com/alecthomas/participle/v2 v2.0.0-alpha9 // indirect
+	github.com/alecthomas/repr v0.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.42.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
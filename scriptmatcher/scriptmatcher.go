@@ -0,0 +1,180 @@
+// Package scriptmatcher provides the SCRIPT function, which evaluates a
+// Starlark expression, for the rare rules that can not be written in the
+// query language:
+//
+//	SCRIPT("any([t.startswith('beta-') for t in tags]) and len(name) < 20")
+//
+// It is a module of its own to keep Starlark out of the dependencies of the
+// matcher module, and is only available to matchers created with
+// WithScript and version 2 of the query grammar.
+//
+// The names the expression uses are bound to the context values with the
+// same keys, or None when missing. JSON numbers without a fraction become
+// Starlark ints, lists become lists and objects dicts. The result must be a
+// bool when SCRIPT is used as a condition on its own; otherwise it is
+// compared like a context value, e.g. `SCRIPT("len(tags)") > 3`. Fields only
+// used by scripts are not decoded by Matcher.TestReader.
+package scriptmatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Limits bound the resources of a script. Zero values select the defaults.
+type Limits struct {
+	// MaxSteps is the maximum number of Starlark execution steps of an
+	// evaluation, 100000 by default.
+	MaxSteps uint64
+	// Timeout is the maximum duration of an evaluation, 100ms by default.
+	Timeout time.Duration
+	// MaxLength is the maximum length of a script in bytes, 4096 by default.
+	MaxLength int
+}
+
+// WithScript makes SCRIPT available to the query, with the given limits.
+// An evaluation exceeding them fails.
+func WithScript(l Limits) matcher.Option {
+	if l.MaxSteps == 0 {
+		l.MaxSteps = 100000
+	}
+	if l.Timeout == 0 {
+		l.Timeout = 100 * time.Millisecond
+	}
+	if l.MaxLength == 0 {
+		l.MaxLength = 4096
+	}
+	return matcher.WithFunction("SCRIPT", func(args []*matcher.Arg) (matcher.Func, error) {
+		if len(args) != 1 || args[0].Value == nil || args[0].Value.String == nil {
+			return nil, errors.New(`want SCRIPT("expression")`)
+		}
+		return compile(*args[0].Value.String, l)
+	})
+}
+
+// fileOptions enables sets, which are not part of core Starlark.
+var fileOptions = &syntax.FileOptions{Set: true}
+
+// compile compiles a script into a Func.
+func compile(src string, l Limits) (matcher.Func, error) {
+	if len(src) > l.MaxLength {
+		return nil, fmt.Errorf("script longer than %d bytes", l.MaxLength)
+	}
+	expr, err := fileOptions.ParseExpr("SCRIPT", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	syntax.Walk(expr, func(n syntax.Node) bool {
+		if id, ok := n.(*syntax.Ident); ok && !seen[id.Name] && starlark.Universe[id.Name] == nil {
+			seen[id.Name] = true
+			names = append(names, id.Name)
+		}
+		return true
+	})
+
+	// The expression is compiled once, as the assignment of a global,
+	// with the names it uses as predeclared.
+	f, err := fileOptions.Parse("SCRIPT", "_result = ("+src+"\n)\n", 0)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := starlark.FileProgram(f, func(name string) bool { return seen[name] })
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx matcher.Context) (interface{}, error) {
+		env := make(starlark.StringDict, len(names))
+		for _, name := range names {
+			v, err := toStarlark(ctx[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			env[name] = v
+		}
+		thread := &starlark.Thread{Name: "SCRIPT", Print: func(*starlark.Thread, string) {}}
+		thread.SetMaxExecutionSteps(l.MaxSteps)
+		timer := time.AfterFunc(l.Timeout, func() { thread.Cancel("timeout") })
+		defer timer.Stop()
+		globals, err := prog.Init(thread, env)
+		if err != nil {
+			return nil, err
+		}
+		return fromStarlark(globals["_result"])
+	}, nil
+}
+
+// toStarlark converts a context value.
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch x := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(x), nil
+	case string:
+		return starlark.String(x), nil
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return starlark.MakeInt64(int64(x)), nil
+		}
+		return starlark.Float(x), nil
+	case int:
+		return starlark.MakeInt(x), nil
+	case int64:
+		return starlark.MakeInt64(x), nil
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return starlark.MakeInt64(n), nil
+		}
+		f, err := x.Float64()
+		return starlark.Float(f), err
+	case []interface{}:
+		l := make([]starlark.Value, len(x))
+		for i, e := range x {
+			v, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return starlark.NewList(l), nil
+	case map[string]interface{}:
+		d := starlark.NewDict(len(x))
+		for k, e := range x {
+			v, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			d.SetKey(starlark.String(k), v)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// fromStarlark converts a result to a value matcher compares.
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch x := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(x), nil
+	case starlark.String:
+		return string(x), nil
+	case starlark.Int:
+		f, _ := starlark.AsFloat(x)
+		return f, nil
+	case starlark.Float:
+		return float64(x), nil
+	}
+	return nil, fmt.Errorf("unsupported result of type %s", v.Type())
+}
@@ -0,0 +1,63 @@
+package scriptmatcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/scriptmatcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScript(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`SCRIPT("any([t.startswith('beta-') for t in tags]) and len(name) < 8") and `+
+		`SCRIPT("count * 2 + len(attrs)") = 11`,
+		scriptmatcher.WithScript(scriptmatcher.Limits{}), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	c := matcher.Context{"tags": []interface{}{"x", "beta-1"}, "name": "short", "count": 5.0,
+		"attrs": map[string]interface{}{"a": 1.0}}
+	ok, err := m.Test(&c)
+	assert.NoError(err)
+	assert.True(ok)
+
+	c["name"] = "much too long"
+	ok, err = m.Test(&c)
+	assert.NoError(err)
+	assert.False(ok)
+
+	m, err = matcher.NewMatcher(`SCRIPT("missing == None")`, scriptmatcher.WithScript(scriptmatcher.Limits{}), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	ok, err = m.Test(&matcher.Context{})
+	assert.NoError(err)
+	assert.True(ok)
+}
+
+func TestScriptErrors(t *testing.T) {
+	assert := assert.New(t)
+	opt := scriptmatcher.WithScript(scriptmatcher.Limits{MaxSteps: 1000, MaxLength: 100, Timeout: time.Second})
+	for _, q := range []string{
+		`SCRIPT("1 +")`,
+		`SCRIPT("x = 1")`,
+		`SCRIPT(x)`,
+		`SCRIPT("` + string(make([]byte, 101)) + `")`,
+	} {
+		_, err := matcher.NewMatcher(q, opt, matcher.WithSyntaxVersion(2))
+		assert.ErrorIs(err, matcher.ErrInvalidQuery, q)
+	}
+	_, err := matcher.NewMatcher(`SCRIPT("1") = 1`, matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+
+	m, err := matcher.NewMatcher(`SCRIPT("len([i for i in range(100000)]) > 0")`, opt, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	_, err = m.Test(&matcher.Context{})
+	assert.ErrorContains(err, "too many steps")
+
+	m, err = matcher.NewMatcher(`SCRIPT("n + 1")`, opt, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	_, err = m.Test(&matcher.Context{"n": 1.0})
+	assert.ErrorContains(err, "returned float64, not a boolean")
+	_, err = m.Test(&matcher.Context{"n": "x"})
+	assert.Error(err)
+}
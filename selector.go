@@ -0,0 +1,213 @@
+package matcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NewSelectorMatcher compiles a Kubernetes label selector, such as
+// `app=web,tier in (frontend,cache),!canary`, into a Matcher testing the
+// labels of a resource, given as the context.
+//
+// All requirements must hold. Like in Kubernetes, `key!=value` and
+// `key notin (...)` also hold when the label is missing, `key` holds when the
+// label exists and `!key` when it does not, and `key>n` and `key<n` compare
+// numbers. The empty selector matches everything.
+//
+// The selector is rewritten into an equivalent query, which Expression
+// holds: an AND chain with a parenthesized group for each `in`, `notin`
+// and `!=` requirement. EXISTS and MISSING express the existence checks.
+func NewSelectorMatcher(selector string, opts ...Option) (*Matcher, error) {
+	p := &selectorParser{s: selector}
+	reqs, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: selector %q: %v", ErrInvalidQuery, selector, err)
+	}
+
+	// Every requirement is a disjunction of AND chains; the expression is
+	// their conjunction, with a group for each requirement of several.
+	var and []*Condition
+	for _, req := range reqs {
+		if len(req) == 1 {
+			and = append(and, req[0]...)
+			continue
+		}
+		g := &Expression{}
+		for _, alt := range req {
+			g.Or = append(g.Or, &OrCondition{And: alt})
+		}
+		and = append(and, &Condition{Group: g})
+	}
+	e := &Expression{Or: []*OrCondition{{And: and}}}
+	return newMatcher(e, append([]Option{WithSyntaxVersion(SyntaxVersion)}, opts...))
+}
+
+// selectorParser parses a label selector into requirements, each a list of
+// alternative AND chains.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func (p *selectorParser) parse() ([][][]*Condition, error) {
+	var reqs [][][]*Condition
+	if p.skipSpace(); p.pos == len(p.s) {
+		return nil, nil
+	}
+	for {
+		req, err := p.requirement()
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+		if p.skipSpace(); p.pos == len(p.s) {
+			return reqs, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *selectorParser) requirement() ([][]*Condition, error) {
+	p.skipSpace()
+	if p.accept("!") {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		return [][]*Condition{{existence("MISSING", key)}}, nil
+	}
+	key, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	switch {
+	case p.pos == len(p.s) || p.s[p.pos] == ',':
+		return [][]*Condition{{existence("EXISTS", key)}}, nil
+	case p.accept("!="):
+		v := p.value()
+		return [][]*Condition{{existence("MISSING", key)}, {labelCondition(key, "<>", v)}}, nil
+	case p.accept("=="), p.accept("="):
+		return [][]*Condition{{labelCondition(key, "=", p.value())}}, nil
+	case p.accept(">"), p.accept("<"):
+		op := p.s[p.pos-1 : p.pos]
+		v := p.value()
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s%s%s: not a number", key, op, v)
+		}
+		return [][]*Condition{{{Symbol: key, Compare: &Compare{Operator: op, Value: &Value{Float: &f}}}}}, nil
+	case p.acceptWord("notin"):
+		values, err := p.set()
+		if err != nil {
+			return nil, err
+		}
+		and := make([]*Condition, len(values))
+		for i, v := range values {
+			and[i] = labelCondition(key, "<>", v)
+		}
+		return [][]*Condition{{existence("MISSING", key)}, and}, nil
+	case p.acceptWord("in"):
+		values, err := p.set()
+		if err != nil {
+			return nil, err
+		}
+		alts := make([][]*Condition, len(values))
+		for i, v := range values {
+			alts[i] = []*Condition{labelCondition(key, "=", v)}
+		}
+		return alts, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", p.s[p.pos:], p.pos)
+}
+
+// set parses a parenthesized, comma separated list of values.
+func (p *selectorParser) set() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		values = append(values, p.value())
+		if err := p.expect(",", ")"); err != nil {
+			return nil, err
+		}
+		if p.s[p.pos-1] == ')' {
+			return values, nil
+		}
+	}
+}
+
+func (p *selectorParser) key() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isLabelChar(p.s[p.pos], true) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("expected a label key at %d", p.pos)
+	}
+	return p.s[start:p.pos], nil
+}
+
+// value parses a label value, which may be empty.
+func (p *selectorParser) value() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isLabelChar(p.s[p.pos], false) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isLabelChar(c byte, key bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || key && c == '/'
+}
+
+func (p *selectorParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *selectorParser) accept(tok string) bool {
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// acceptWord accepts tok unless it is the start of a longer word.
+func (p *selectorParser) acceptWord(tok string) bool {
+	end := p.pos + len(tok)
+	if !strings.HasPrefix(p.s[p.pos:], tok) || end < len(p.s) && isLabelChar(p.s[end], true) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+// expect accepts one of toks after optional spaces.
+func (p *selectorParser) expect(toks ...string) error {
+	p.skipSpace()
+	for _, tok := range toks {
+		if p.accept(tok) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected %s at %d", strings.Join(toks, " or "), p.pos)
+}
+
+func labelCondition(key, op, value string) *Condition {
+	return &Condition{Symbol: key, Compare: &Compare{Operator: op, Value: &Value{String: &value}}}
+}
+
+func existence(fn, key string) *Condition {
+	return &Condition{Call: &Call{Name: fn, Args: []*Arg{{Symbol: key}}}}
+}
@@ -0,0 +1,91 @@
+package matcher_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector(t *testing.T) {
+	web := matcher.Context{"app": "web", "tier": "frontend", "app.kubernetes.io/name": "shop", "replicas": "3"}
+	canary := matcher.Context{"app": "web", "tier": "cache", "canary": "true"}
+	db := matcher.Context{"app": "db", "tier": "backend"}
+
+	cases := []struct {
+		selector string
+		matches  []bool // web, canary, db
+	}{
+		{"", []bool{true, true, true}},
+		{"app=web", []bool{true, true, false}},
+		{"app==web", []bool{true, true, false}},
+		{"app!=web", []bool{false, false, true}},
+		{"app=web,tier in (frontend,cache),!canary", []bool{true, false, false}},
+		{"tier in (frontend, cache)", []bool{true, true, false}},
+		{"tier notin (frontend,cache)", []bool{false, false, true}},
+		{"canary", []bool{false, true, false}},
+		{"canary!=true", []bool{true, false, true}},
+		{"canary notin (true)", []bool{true, false, true}},
+		{"app.kubernetes.io/name=shop", []bool{true, false, false}},
+		{"replicas>2", []bool{true, false, false}},
+		{"replicas<2", []bool{false, false, false}},
+		{"app in (web,db),tier in (cache,backend)", []bool{false, true, true}},
+	}
+	for _, c := range cases {
+		m, err := matcher.NewSelectorMatcher(c.selector)
+		assert.NoError(t, err, c.selector)
+		for i, ctx := range []matcher.Context{web, canary, db} {
+			ok, err := m.Test(&ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, c.matches[i], ok, "%s on %v", c.selector, ctx)
+		}
+	}
+}
+
+func TestSelectorQuery(t *testing.T) {
+	m, err := matcher.NewSelectorMatcher("app=web,tier in (a,b),!canary")
+	assert.NoError(t, err)
+	assert.Equal(t, `app = "web" AND (tier = "a" OR tier = "b") AND MISSING(canary)`, m.Expression.String())
+
+	m, err = matcher.NewSelectorMatcher("a in (1,2,3),b notin (x,y),c!=z,d")
+	assert.NoError(t, err)
+	assert.Equal(t, `(a = "1" OR a = "2" OR a = "3") AND (MISSING(b) OR b <> "x" AND b <> "y") AND `+
+		`(MISSING(c) OR c <> "z") AND EXISTS(d)`, m.Expression.String())
+
+	// Requirements are not multiplied out, which would take 3^40 branches.
+	var reqs []string
+	ctx := matcher.Context{}
+	for i := 0; i < 40; i++ {
+		reqs = append(reqs, fmt.Sprintf("l%d in (a,b,c)", i))
+		ctx[fmt.Sprintf("l%d", i)] = "c"
+	}
+	m, err = matcher.NewSelectorMatcher(strings.Join(reqs, ","))
+	assert.NoError(t, err)
+	assert.Len(t, m.Expression.Or, 1)
+	ok, err := m.Test(&ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSelectorErrors(t *testing.T) {
+	for _, s := range []string{"app=web,", "=web", "tier in (a,b", "tier in a", "replicas>x", "app=web tier=a", "!"} {
+		_, err := matcher.NewSelectorMatcher(s)
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, s)
+	}
+}
+
+func TestExists(t *testing.T) {
+	m, err := matcher.NewMatcher("EXISTS(a) and MISSING(b)", matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	ok, err := m.Test(&matcher.Context{"a": nil})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = m.Test(&matcher.Context{"a": 1.0, "b": nil})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = matcher.NewMatcher("EXISTS(1)", matcher.WithSyntaxVersion(2))
+	assert.ErrorIs(t, err, matcher.ErrInvalidQuery)
+}
@@ -0,0 +1,44 @@
+//go:build go1.23
+
+package matcher
+
+import "iter"
+
+// FilterSeq returns the contexts of seq that match m. Contexts failing to
+// evaluate are skipped; use FilterSeq2 to see their errors.
+func (m *Matcher) FilterSeq(seq iter.Seq[Context]) iter.Seq[Context] {
+	return func(yield func(Context) bool) {
+		for c := range seq {
+			if ok, err := m.Test(&c); err == nil && ok && !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// FilterSeq2 returns the contexts of seq that match m, and those failing to
+// evaluate with their error.
+func (m *Matcher) FilterSeq2(seq iter.Seq[Context]) iter.Seq2[Context, error] {
+	return func(yield func(Context, error) bool) {
+		for c := range seq {
+			ok, err := m.Test(&c)
+			if (ok || err != nil) && !yield(c, err) {
+				return
+			}
+		}
+	}
+}
+
+// FilterSeqOf returns the values of seq that match m, testing the context
+// adapt builds for each value, such as a map of the fields of a struct.
+// Values failing to evaluate are skipped.
+func FilterSeqOf[T any](m *Matcher, seq iter.Seq[T], adapt func(T) Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			c := adapt(v)
+			if ok, err := m.Test(&c); err == nil && ok && !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build go1.23
+
+package matcher_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSeq(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("n > 1", matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(err)
+	ctxs := []matcher.Context{{"n": 1.0}, {"n": 2.0}, {"n": true}, {"n": 3.0}}
+
+	assert.Equal([]matcher.Context{{"n": 2.0}, {"n": 3.0}}, slices.Collect(m.FilterSeq(slices.Values(ctxs))))
+
+	var errs int
+	var matched []matcher.Context
+	for c, err := range m.FilterSeq2(slices.Values(ctxs)) {
+		if err != nil {
+			errs++
+			continue
+		}
+		matched = append(matched, c)
+	}
+	assert.Equal(1, errs)
+	assert.Len(matched, 2)
+
+	// Stopping early stops reading the input.
+	for c := range m.FilterSeq(slices.Values(ctxs)) {
+		assert.Equal(2.0, c["n"])
+		break
+	}
+}
+
+func TestFilterSeqOf(t *testing.T) {
+	type order struct {
+		ID    string
+		Total float64
+	}
+	m, err := matcher.NewMatcher("total >= 100")
+	assert.NoError(t, err)
+	orders := map[string]order{"a": {"a", 50}, "b": {"b", 150}, "c": {"c", 100}}
+	big := matcher.FilterSeqOf(m, maps.Values(orders), func(o order) matcher.Context {
+		return matcher.Context{"id": o.ID, "total": o.Total}
+	})
+	got := slices.SortedFunc(big, func(a, b order) int { return int(a.Total - b.Total) })
+	assert.Equal(t, []order{{"c", 100}, {"b", 150}}, got)
+}
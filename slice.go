@@ -0,0 +1,73 @@
+package matcher
+
+// Filter returns the contexts of ctxs that match m, in order. It stops at
+// the first context failing to evaluate and returns its error.
+func Filter(m *Matcher, ctxs []Context) ([]Context, error) {
+	var out []Context
+	for i := range ctxs {
+		ok, err := m.Test(&ctxs[i])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, ctxs[i])
+		}
+	}
+	return out, nil
+}
+
+// FilterSlice returns the values of s that match m, in order, testing the
+// context adapt builds for each value. It stops at the first value failing
+// to evaluate and returns its error.
+func FilterSlice[T any](m *Matcher, s []T, adapt func(T) Context) ([]T, error) {
+	var out []T
+	for _, v := range s {
+		c := adapt(v)
+		ok, err := m.Test(&c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// Any reports whether a context of ctxs matches m, testing them in order
+// until one does or fails to evaluate.
+func Any(m *Matcher, ctxs []Context) (bool, error) {
+	for i := range ctxs {
+		if ok, err := m.Test(&ctxs[i]); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// All reports whether every context of ctxs matches m, testing them in
+// order until one does not or fails to evaluate. All of no contexts is true.
+func All(m *Matcher, ctxs []Context) (bool, error) {
+	for i := range ctxs {
+		if ok, err := m.Test(&ctxs[i]); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Count returns the number of contexts of ctxs that match m. It stops at the
+// first context failing to evaluate and returns its error.
+func Count(m *Matcher, ctxs []Context) (int, error) {
+	n := 0
+	for i := range ctxs {
+		ok, err := m.Test(&ctxs[i])
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
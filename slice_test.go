@@ -0,0 +1,81 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSliceHelpers(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`a > 1`)
+	assert.NoError(err)
+	ctxs := []matcher.Context{{"a": 1.0}, {"a": 2.0}, {"a": 3.0}}
+
+	got, err := matcher.Filter(m, ctxs)
+	assert.NoError(err)
+	assert.Equal([]matcher.Context{{"a": 2.0}, {"a": 3.0}}, got)
+
+	n, err := matcher.Count(m, ctxs)
+	assert.NoError(err)
+	assert.Equal(2, n)
+
+	ok, err := matcher.Any(m, ctxs)
+	assert.NoError(err)
+	assert.True(ok)
+	ok, err = matcher.All(m, ctxs)
+	assert.NoError(err)
+	assert.False(ok)
+	ok, err = matcher.All(m, ctxs[1:])
+	assert.NoError(err)
+	assert.True(ok)
+
+	ok, err = matcher.Any(m, nil)
+	assert.NoError(err)
+	assert.False(ok)
+	ok, err = matcher.All(m, nil)
+	assert.NoError(err)
+	assert.True(ok)
+	got, err = matcher.Filter(m, nil)
+	assert.NoError(err)
+	assert.Empty(got)
+}
+
+func TestSliceHelpersErrors(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`a > 1`, matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(err)
+	ctxs := []matcher.Context{{"a": 2.0}, {"a": true}, {"a": 3.0}}
+
+	_, err = matcher.Filter(m, ctxs)
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+	_, err = matcher.Count(m, ctxs)
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+	_, err = matcher.All(m, ctxs)
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+
+	// Any stops at the first match, before the failing context.
+	ok, err := matcher.Any(m, ctxs)
+	assert.NoError(err)
+	assert.True(ok)
+}
+
+func TestFilterSlice(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`name LIKE "J%"`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	names := []string{"John", "Mary", "Jane"}
+	got, err := matcher.FilterSlice(m, names, func(s string) matcher.Context {
+		return matcher.Context{"name": s}
+	})
+	assert.NoError(err)
+	assert.Equal([]string{"John", "Jane"}, got)
+
+	m, err = matcher.NewMatcher(`name = "John"`, matcher.WithStrictTypes())
+	assert.NoError(err)
+	_, err = matcher.FilterSlice(m, names, func(s string) matcher.Context {
+		return matcher.Context{"name": len(s)}
+	})
+	assert.ErrorIs(err, matcher.ErrTypeMismatch)
+}
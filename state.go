@@ -0,0 +1,146 @@
+package matcher
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStateSize is the number of keys kept by the in-memory StateStore a
+// Matcher uses when WithStateStore is not given.
+const DefaultStateSize = 100000
+
+// StateStore keeps the per-key state of stateful functions such as CHANGED,
+// FIRST_SEEN and DEDUP. Keys start with the rule ID set with WithRuleID, or a
+// number unique to the Matcher without one, followed by the position of the
+// call among the calls of the query and the call itself. Different rules and
+// different calls of one rule never share state, while matchers of the same
+// rule sharing a store do, e.g. across processes using a common external
+// store.
+//
+// Implementations must be safe for concurrent use. Both methods map to a
+// single Redis command: Swap to SET key value PX ttl GET and Add to
+// SET key value NX PX ttl (without PX when ttl is zero).
+type StateStore interface {
+	// Swap stores value under key for ttl, or without expiry when ttl is
+	// zero, and returns the previous value if it had not expired.
+	Swap(key, value string, ttl time.Duration) (old string, found bool, err error)
+	// Add stores value under key for ttl like Swap, but only if the key
+	// holds no unexpired value, and reports whether it did.
+	Add(key, value string, ttl time.Duration) (added bool, err error)
+}
+
+// WithStateStore sets the store of stateful functions. By default each
+// Matcher keeps its state in its own MemoryStore of DefaultStateSize keys.
+func WithStateStore(s StateStore) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// stateStore returns the configured store, creating the default one on first
+// use.
+func (o *options) stateStore() StateStore {
+	if o.store == nil {
+		s := NewMemoryStore(DefaultStateSize)
+		s.now = o.clock()
+		o.store = s
+	}
+	return o.store
+}
+
+// matchers numbers the matchers without a rule ID that keep state.
+var matchers uint64
+
+// statePrefix returns the prefix of the state keys of the stateful call c,
+// which is being compiled, as described for StateStore.
+func (o *options) statePrefix(c *Call) string {
+	scope := "rule " + o.ruleID
+	if o.ruleID == "" {
+		if o.matcher == 0 {
+			o.matcher = atomic.AddUint64(&matchers, 1)
+		}
+		scope = "matcher " + strconv.FormatUint(o.matcher, 10)
+	}
+	return scope + "\x00" + strconv.Itoa(o.calls) + "\x00" + c.String() + "\x00"
+}
+
+type memoryEntry struct {
+	key     string
+	value   string
+	expires time.Time // zero for no expiry
+}
+
+// MemoryStore is an in-memory StateStore evicting the least recently used
+// keys above its size.
+type MemoryStore struct {
+	mu    sync.Mutex
+	size  int
+	now   func() time.Time
+	order *list.List
+	items map[string]*list.Element
+}
+
+// NewMemoryStore returns a store keeping at most size keys, or any number of
+// keys when size is 0 or less.
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{size: size, now: time.Now, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Swap implements StateStore.
+func (s *MemoryStore) Swap(key, value string, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var expires time.Time
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+
+	if e, ok := s.items[key]; ok {
+		entry := e.Value.(*memoryEntry)
+		old, found := entry.value, entry.expires.IsZero() || now.Before(entry.expires)
+		entry.value, entry.expires = value, expires
+		s.order.MoveToFront(e)
+		return old, found, nil
+	}
+	s.items[key] = s.order.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	s.evict()
+	return "", false, nil
+}
+
+// Add implements StateStore.
+func (s *MemoryStore) Add(key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var expires time.Time
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+
+	if e, ok := s.items[key]; ok {
+		entry := e.Value.(*memoryEntry)
+		s.order.MoveToFront(e)
+		if entry.expires.IsZero() || now.Before(entry.expires) {
+			return false, nil
+		}
+		entry.value, entry.expires = value, expires
+		return true, nil
+	}
+	s.items[key] = s.order.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	s.evict()
+	return true, nil
+}
+
+// evict drops the least recently used entries above the size limit. The
+// caller holds s.mu.
+func (s *MemoryStore) evict() {
+	for s.size > 0 && s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryEntry).key)
+	}
+}
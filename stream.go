@@ -0,0 +1,143 @@
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TestReader tests a single JSON object read from r without decoding it into
+// a Context first. The object is read token by token: only the top-level
+// fields referenced by the query are decoded, everything else is skipped,
+// and reading stops as soon as all referenced fields were found. This keeps
+// memory use low for very large documents. Numbers are decoded as
+// json.Number, so integers beyond 2^53 compare exactly.
+//
+// If a referenced field occurs more than once, the first occurrence is used.
+func (m *Matcher) TestReader(r io.Reader) (bool, error) {
+	ctx, err := m.Expression.decodeReferenced(r)
+	if err != nil {
+		return false, err
+	}
+	return m.Test(&ctx)
+}
+
+// MatchStream tests every line of the newline-delimited JSON read from r
+// like TestReader, and calls fn with the line, without its line break, and
+// whether it matched. Blank lines are skipped.
+//
+// MatchStream returns nil at the end of r. It stops early with the error of
+// ctx when it is done, with the error fn returns, and at the first line that
+// is not a JSON object or fails to evaluate, with its error and line number.
+func (m *Matcher) MatchStream(ctx context.Context, r io.Reader, fn func(line []byte, matched bool) error) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			ok, terr := m.TestReader(bytes.NewReader(line))
+			if terr != nil {
+				return fmt.Errorf("line %d: %w", n, terr)
+			}
+			if ferr := fn(bytes.TrimRight(line, "\r\n"), ok); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// symbols returns the set of context keys the expression refers to.
+func (e *Expression) symbols() map[string]bool {
+	syms := make(map[string]bool)
+	for _, c := range e.leaves() {
+		if c.Compare != nil && c.Compare.Field != "" {
+			syms[c.Compare.Field] = true
+			syms[root(c.Compare.Field)] = true
+		}
+		if c.Call == nil {
+			syms[c.Symbol] = true
+			syms[root(c.Symbol)] = true
+			for _, k := range c.inputs {
+				syms[k] = true
+			}
+			continue
+		}
+		for _, a := range c.Call.Args {
+			if a.Symbol != "" {
+				syms[a.Symbol] = true
+				syms[root(a.Symbol)] = true
+			}
+		}
+	}
+	return syms
+}
+
+// decodeReferenced decodes the top-level fields of the JSON object in r
+// that the expression refers to, itself or through a path.
+func (e *Expression) decodeReferenced(r io.Reader) (Context, error) {
+	syms := make(map[string]bool)
+	for k := range e.symbols() {
+		syms[root(k)] = true
+	}
+	ctx := make(Context, len(syms))
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, fmt.Errorf("expected JSON object, got %v", tok)
+	}
+
+	for len(ctx) < len(syms) && dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		if _, seen := ctx[key]; syms[key] && !seen {
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			ctx[key] = v
+			continue
+		}
+		if err := skipValue(dec); err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+// skipValue reads past the next JSON value without keeping it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package matcher_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestReader(t *testing.T) {
+	doc := `{"skip": {"deep": [1, {"x": [2, 3]}, "s"]}, "a": 1, "list": [1, 2], "b": "foo", "c": null}`
+	cases := []struct {
+		query string
+		match bool
+	}{
+		{"a = 1", true},
+		{"a = 1 and b = \"foo\"", true},
+		{"a = 2 or b = \"bar\"", false},
+		{"missing = 1", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			m, err := matcher.NewMatcher(c.query)
+			assert.NoError(t, err)
+
+			ok, err := m.TestReader(strings.NewReader(doc))
+			assert.NoError(t, err)
+			assert.Equal(t, c.match, ok)
+		})
+	}
+}
+
+// failingReader returns its content and then an error instead of EOF.
+type failingReader struct{ r io.Reader }
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("read past the referenced fields")
+	}
+	return n, err
+}
+
+func TestTestReaderStopsEarly(t *testing.T) {
+	m, err := matcher.NewMatcher("a = 1")
+	assert.NoError(t, err)
+
+	r := failingReader{strings.NewReader(`{"a": 1, "b": [` + strings.Repeat(`"x",`, 10000))}
+	ok, err := m.TestReader(r)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	m, err = matcher.NewMatcher(`user.name = "kim"`, matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	r = failingReader{strings.NewReader(`{"user": {"name": "kim"}, "b": [` + strings.Repeat(`"x",`, 10000))}
+	ok, err = m.TestReader(r)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestTestReaderNumbers(t *testing.T) {
+	doc := `{"id": 9007199254740993}`
+	m, err := matcher.NewMatcher("id = 9007199254740993")
+	assert.NoError(t, err)
+	ok, err := m.TestReader(strings.NewReader(doc))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	m, err = matcher.NewMatcher("id = 9007199254740992")
+	assert.NoError(t, err)
+	ok, err = m.TestReader(strings.NewReader(doc))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestTestReaderInvalid(t *testing.T) {
+	m, err := matcher.NewMatcher("a = 1")
+	assert.NoError(t, err)
+
+	_, err = m.TestReader(strings.NewReader(`[1, 2]`))
+	assert.Error(t, err)
+	_, err = m.TestReader(strings.NewReader(`{"b": [1, `))
+	assert.Error(t, err)
+}
+
+func TestMatchStream(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`level = "error"`)
+	assert.NoError(err)
+
+	input := "{\"level\":\"error\",\"id\":1}\n\n{\"level\":\"info\"}\r\n   \n{\"level\":\"error\",\"id\":3}"
+	var lines []string
+	var matched []bool
+	err = m.MatchStream(context.Background(), strings.NewReader(input), func(line []byte, ok bool) error {
+		lines = append(lines, string(line))
+		matched = append(matched, ok)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]string{`{"level":"error","id":1}`, `{"level":"info"}`, `{"level":"error","id":3}`}, lines)
+	assert.Equal([]bool{true, false, true}, matched)
+}
+
+func TestMatchStreamStops(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`a = 1`)
+	assert.NoError(err)
+	input := "{\"a\":1}\n{\"a\":2}\n"
+
+	stop := errors.New("stop")
+	calls := 0
+	err = m.MatchStream(context.Background(), strings.NewReader(input), func([]byte, bool) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(err, stop)
+	assert.Equal(1, calls)
+
+	err = m.MatchStream(context.Background(), strings.NewReader("{\"a\":1}\n[1]\n"), func([]byte, bool) error {
+		return nil
+	})
+	assert.Error(err)
+	assert.Contains(err.Error(), "line 2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = m.MatchStream(ctx, strings.NewReader(input), func([]byte, bool) error {
+		return nil
+	})
+	assert.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,233 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TestStruct reports whether the struct v, or a pointer to one, matches the
+// query. Fields are named as encoding/json names them, honoring json tags,
+// omitempty and embedded structs, so TestStruct agrees with Test on the
+// Context v marshals to, without marshaling it: only the fields the query
+// refers to are read, by reflection. Maps with string keys inside v are
+// traversed by paths like struct fields.
+//
+// Values keep their Go types, which compare like their JSON forms. Types
+// with their own JSON encoding, other than time.Time, are not converted.
+func (m *Matcher) TestStruct(v interface{}) (bool, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return false, fmt.Errorf("TestStruct: %T is not a struct", v)
+	}
+	return m.TestResolver(structResolver{rv})
+}
+
+// structResolver resolves keys to the fields of a struct.
+type structResolver struct {
+	v reflect.Value
+}
+
+// Lookup implements Resolver. A key with dots that is not the name of a
+// field is a path into nested structs and maps.
+func (s structResolver) Lookup(key string) (interface{}, bool) {
+	if f, ok := member(s.v, key); ok {
+		return plainValue(f), true
+	}
+	if strings.IndexByte(key, '.') < 0 {
+		return nil, false
+	}
+	f := s.v
+	for _, name := range strings.Split(key, ".") {
+		var ok bool
+		if f, ok = member(f, name); !ok {
+			return nil, false
+		}
+	}
+	return plainValue(f), true
+}
+
+// member returns the member name of a struct or of a map with string keys,
+// following pointers and interfaces.
+func member(v reflect.Value, name string) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok := fieldsOf(v.Type())[name]
+		if !ok {
+			return reflect.Value{}, false
+		}
+		return f.get(v)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		e := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return e, e.IsValid()
+	}
+	return reflect.Value{}, false
+}
+
+// plainValue returns the value of a field as the matcher compares it: nil
+// for nil pointers, slices and maps, which encode as null, the value
+// pointed to for other pointers, and strings and booleans for types defined
+// on them other than json.Number.
+func plainValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+	case reflect.String:
+		if v.Type() == numberType {
+			return v.Interface()
+		}
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	return v.Interface()
+}
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+// structField locates a field of a struct type by its JSON name.
+type structField struct {
+	index     []int
+	omitEmpty bool
+}
+
+// get returns the field of the struct v, or of the struct v points to, or
+// false if it has no value.
+func (f structField) get(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	fv, err := v.FieldByIndexErr(f.index)
+	if err != nil || !fv.CanInterface() || f.omitEmpty && isEmptyValue(fv) {
+		return reflect.Value{}, false
+	}
+	return fv, true
+}
+
+// structFields caches the fields of struct types by JSON name.
+var structFields sync.Map // reflect.Type -> map[string]structField
+
+// fieldsOf returns the fields of the struct type t by JSON name.
+func fieldsOf(t reflect.Type) map[string]structField {
+	if f, ok := structFields.Load(t); ok {
+		return f.(map[string]structField)
+	}
+	f, _ := structFields.LoadOrStore(t, buildFields(t))
+	return f.(map[string]structField)
+}
+
+// buildFields names the fields of t like encoding/json: a json tag renames
+// a field and "-" hides it, the fields of untagged embedded structs are
+// promoted, and of several fields with the same name the shallowest wins,
+// a tagged one if they are equally deep, and none if that is still
+// ambiguous.
+func buildFields(t reflect.Type) map[string]structField {
+	type candidate struct {
+		structField
+		depth     int
+		tagged    bool
+		ambiguous bool
+	}
+	best := make(map[string]*candidate)
+	var opaque [][]int // embedded fields whose fields are not promoted
+	for _, f := range reflect.VisibleFields(t) {
+		if under(f.Index, opaque) {
+			continue
+		}
+		tag, tagged := f.Tag.Lookup("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if tag == "-" {
+			opaque = append(opaque, f.Index)
+			continue
+		}
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if name == "" && ft.Kind() == reflect.Struct {
+				continue
+			}
+			opaque = append(opaque, f.Index)
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		c := &candidate{
+			structField: structField{index: f.Index, omitEmpty: strings.Contains(","+opts+",", ",omitempty,")},
+			depth:       len(f.Index),
+			tagged:      tagged && tag != "" && tag[0] != ',',
+		}
+		switch old, ok := best[name]; {
+		case !ok, c.depth < old.depth, c.depth == old.depth && c.tagged && !old.tagged:
+			best[name] = c
+		case c.depth == old.depth && c.tagged == old.tagged:
+			old.ambiguous = true
+		}
+	}
+	fields := make(map[string]structField, len(best))
+	for name, c := range best {
+		if !c.ambiguous {
+			fields[name] = c.structField
+		}
+	}
+	return fields
+}
+
+// under reports whether index is the index of a field inside one of the
+// fields at prefixes.
+func under(index []int, prefixes [][]int) bool {
+	for _, p := range prefixes {
+		if len(index) > len(p) && reflect.DeepEqual(index[:len(p)], p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyValue reports whether v is empty for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
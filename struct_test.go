@@ -0,0 +1,132 @@
+package matcher_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+type status string
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type Audit struct {
+	Created time.Time `json:"created_at"`
+	Author  string
+}
+
+type user struct {
+	Audit
+	ID       json.Number            `json:"id"`
+	Name     string                 `json:"name"`
+	Age      int                    `json:"age"`
+	Status   status                 `json:"status"`
+	Admin    bool                   `json:"admin,omitempty"`
+	Tags     []string               `json:"tags"`
+	Address  *address               `json:"address"`
+	Labels   map[string]interface{} `json:"labels"`
+	Password string                 `json:"-"`
+	note     string
+}
+
+func TestStruct(t *testing.T) {
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	u := user{
+		Audit:    Audit{Created: created, Author: "ops"},
+		ID:       "9007199254740993",
+		Name:     "John",
+		Age:      42,
+		Status:   "active",
+		Tags:     []string{"a", "b"},
+		Address:  &address{City: "Tokyo"},
+		Labels:   map[string]interface{}{"team": "core"},
+		Password: "secret",
+		note:     "x",
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{`name = "John" AND age > 40`, true},
+		{`status = "active"`, true},
+		{`id = 9007199254740993`, true},
+		{`created_at > 2024-01-01 AND Author = "ops"`, true},
+		{`address.city = "Tokyo"`, true},
+		{`EXISTS(address.zip)`, false},
+		{`EXISTS(admin)`, false},
+		{`labels.team = "core"`, true},
+		{`ANY(tags) = "b" AND LEN(tags) = 2`, true},
+		{`EXISTS(Password) OR EXISTS(password) OR EXISTS(note) OR EXISTS(Name)`, false},
+		{`Audit = NULL`, false},
+	}
+	for _, tt := range tests {
+		m, err := matcher.NewMatcher(tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		got, err := m.TestStruct(&u)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, got, tt.query)
+
+		// TestStruct agrees with Test on the marshaled struct.
+		b, err := json.Marshal(u)
+		assert.NoError(t, err)
+		var c matcher.Context
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		assert.NoError(t, dec.Decode(&c))
+		want, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, tt.query)
+	}
+}
+
+func TestStructNil(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`address = NULL AND tags = NULL AND MISSING(address.city)`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	ok, err := m.TestStruct(user{})
+	assert.NoError(err)
+	assert.True(ok)
+
+	_, err = m.TestStruct(map[string]interface{}{})
+	assert.Error(err)
+	_, err = m.TestStruct((*user)(nil))
+	assert.Error(err)
+}
+
+type inner struct {
+	A int `json:"a"`
+	B int
+}
+
+type outer struct {
+	inner
+	Tagged inner `json:"tagged"`
+	B      string
+}
+
+func TestStructEmbedded(t *testing.T) {
+	assert := assert.New(t)
+	v := outer{inner: inner{A: 1, B: 2}, Tagged: inner{A: 3}, B: "x"}
+	for q, want := range map[string]bool{
+		`a = 1`:        true,
+		`B = "x"`:      true,
+		`tagged.a = 3`: true,
+		`tagged.B = 0`: true,
+		`EXISTS(A)`:    false,
+	} {
+		m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.NoError(err)
+		ok, err := m.TestStruct(v)
+		assert.NoError(err)
+		assert.Equal(want, ok, q)
+	}
+}
@@ -0,0 +1,96 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle/v2"
+)
+
+// SyntaxVersion is the latest version of the query grammar. A version is
+// added whenever new syntax could change how an existing query parses or
+// what it means, such as a new keyword that was a valid field name before.
+const SyntaxVersion = 2
+
+// keywords are the reserved words of each grammar version, which can not
+// be used as field names.
+var keywords = map[int][]string{
+	// 1 is the original grammar, in which NULL is an identifier that is
+	// only the null literal where a value is expected.
+	1: {"TRUE", "FALSE", "AND", "OR"},
+	// 2 reserves NULL and adds LIKE, NOT LIKE, NOT, NaN and Inf literals,
+	// parentheses, function calls, dotted paths, comparisons of fields and
+	// timestamp literals.
+	2: {"TRUE", "FALSE", "AND", "OR", "NULL", "LIKE", "NOT"},
+}
+
+// WithSyntaxVersion parses the query with version v of the grammar instead
+// of version 1, the original grammar, so that stored queries keep parsing
+// exactly as when they were written while new queries opt into the richer
+// grammar with WithSyntaxVersion(SyntaxVersion). Queries using syntax added
+// after v are rejected with a ParseError or ErrInvalidQuery.
+func WithSyntaxVersion(v int) Option {
+	return func(o *options) {
+		o.syntax = v
+	}
+}
+
+var parsers = struct {
+	sync.Mutex
+	m map[int]*participle.Parser
+}{m: make(map[int]*participle.Parser)}
+
+// parserFor returns the process wide parser of version v of the grammar,
+// building it on first use. Parsers are safe for concurrent use.
+func parserFor(v int) (*participle.Parser, error) {
+	if v < 1 || v > SyntaxVersion {
+		return nil, fmt.Errorf("%w: unknown syntax version %d", ErrInvalidQuery, v)
+	}
+	parsers.Lock()
+	defer parsers.Unlock()
+	p, ok := parsers.m[v]
+	if !ok {
+		p = newParser(v)
+		parsers.m[v] = p
+	}
+	return p, nil
+}
+
+// checkSyntax rejects parentheses, function calls, dotted paths,
+// comparisons of fields and timestamp literals in queries written for
+// version 1. Every version of the parser accepts them, as they can not
+// change how a query that parsed before parses.
+func (e *Expression) checkSyntax(v int) error {
+	if v >= 2 {
+		return nil
+	}
+	for _, x := range e.Or {
+		for _, c := range x.And {
+			if c.Group != nil {
+				return fmt.Errorf("%w: %s: parentheses need syntax version 2", ErrInvalidQuery, c)
+			}
+			if c.Compare != nil && c.Compare.Field != "" {
+				return fmt.Errorf("%w: %s: comparisons of fields need syntax version 2", ErrInvalidQuery, c)
+			}
+			if c.Call != nil {
+				return fmt.Errorf("%w: %s: functions need syntax version 2", ErrInvalidQuery, c)
+			}
+			if c.Compare != nil && c.Compare.Value != nil && c.Compare.Value.Time != nil {
+				return fmt.Errorf("%w: %s: timestamps need syntax version 2", ErrInvalidQuery, c)
+			}
+			if strings.IndexByte(c.Symbol, '.') >= 0 {
+				return fmt.Errorf("%w: %s: paths need syntax version 2", ErrInvalidQuery, c)
+			}
+		}
+	}
+	return nil
+}
+
+// syntaxVersion returns the selected grammar version.
+func (o *options) syntaxVersion() int {
+	if o.syntax == 0 {
+		return 1
+	}
+	return o.syntax
+}
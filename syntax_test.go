@@ -0,0 +1,65 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntaxVersion(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher("a = 1 or COUNT_OVER(1m) > 2", matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal("a = 1 OR COUNT_OVER(1m) > 2", m.Expression.String())
+
+	// Queries use version 1 unless they opt into a later one.
+	_, err = matcher.NewMatcher("a = 1 or COUNT_OVER(1m) > 2")
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+	_, err = matcher.NewMatcher("a = 1 or COUNT_OVER(1m) > 2", matcher.WithSyntaxVersion(1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+
+	_, err = matcher.NewMatcher("a = 1", matcher.WithSyntaxVersion(matcher.SyntaxVersion+1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+	_, err = matcher.NewMatcher("a = 1", matcher.WithSyntaxVersion(-1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+}
+
+// TestSyntaxVersion1 runs queries of the original grammar under version 1.
+func TestSyntaxVersion1(t *testing.T) {
+	c := matcher.Context{"a": 1.0, "b": "x", "null": 1.0, "NULL": 2.0, "nan": 1.0, "inf": 1.0, "n": nil, "t": true,
+		"not": 1.0, "like": "x"}
+	cases := []struct {
+		query string
+		match bool
+	}{
+		{`a = 1 AND b = "x"`, true},
+		{`a = 2 or b <> 'x'`, false},
+		{`a >= -1.5e0 And a < +2`, true},
+		{`n = NULL`, true},
+		{`null = 1`, true},
+		{`NULL = 2`, true},
+		{`nan = 1`, true},
+		{`inf = 1 AND nan = 1`, true},
+		{`t = true`, true},
+		{`not = 1`, true},
+		{`like = "x"`, true},
+	}
+	for _, x := range cases {
+		t.Run(x.query, func(t *testing.T) {
+			for _, opts := range [][]matcher.Option{nil, {matcher.WithSyntaxVersion(1)}} {
+				m, err := matcher.NewMatcher(x.query, opts...)
+				assert.NoError(t, err)
+				ok, err := m.Test(&c)
+				assert.NoError(t, err)
+				assert.Equal(t, x.match, ok)
+			}
+		})
+	}
+
+	// The original grammar rejected these.
+	for _, q := range []string{`a = NaN`, `a = null`, `order = 1`, `not a = 1`, `a LIKE "x"`, `EXISTS(a)`} {
+		_, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(1))
+		assert.Error(t, err, q)
+	}
+}
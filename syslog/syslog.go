@@ -0,0 +1,442 @@
+// Package syslog receives RFC 5424 syslog messages over UDP or TCP and passes
+// on those matching a matcher query, so matcher can pre-filter the messages
+// sent to a log aggregator.
+//
+// A message is tested against a context with
+//
+//   - facility and severity, as numbers,
+//   - version, timestamp, hostname, app_name, procid and msgid, from the
+//     header; fields with the nil value "-" are left out,
+//   - message, the free-form message,
+//   - every structured data parameter as <sd-id>_<name>, with characters that
+//     can not appear in a query identifier replaced by underscores: the
+//     parameter iut of [exampleSDID@32473 iut="3"] is
+//     exampleSDID_32473_iut.
+//
+// The header fields win over structured data parameters.
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kuwa72/matcher"
+)
+
+// nilValue is the syslog representation of an absent header field.
+const nilValue = "-"
+
+// Message is a parsed syslog message.
+type Message struct {
+	Facility       int
+	Severity       int
+	Version        int
+	Timestamp      string
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Message        string
+
+	// Raw is the message as received, without transport framing.
+	Raw []byte
+}
+
+// Parse parses an RFC 5424 message.
+func Parse(b []byte) (*Message, error) {
+	p := &parser{b: b}
+	msg := &Message{Raw: b}
+	if !p.consume('<') {
+		return nil, p.errorf("expected <PRI>")
+	}
+	pri, ok := p.number(3)
+	if !ok || !p.consume('>') || pri > 191 {
+		return nil, p.errorf("invalid PRI")
+	}
+	msg.Facility, msg.Severity = pri/8, pri%8
+	if msg.Version, ok = p.number(2); !ok || msg.Version == 0 {
+		return nil, p.errorf("invalid VERSION")
+	}
+	for _, f := range []*string{&msg.Timestamp, &msg.Hostname, &msg.AppName, &msg.ProcID, &msg.MsgID} {
+		if !p.consume(' ') {
+			return nil, p.errorf("expected header field")
+		}
+		if *f = p.token(); *f == "" {
+			return nil, p.errorf("empty header field")
+		}
+		if *f == nilValue {
+			*f = ""
+		}
+	}
+	if !p.consume(' ') {
+		return nil, p.errorf("expected STRUCTURED-DATA")
+	}
+	sd, err := p.structuredData()
+	if err != nil {
+		return nil, err
+	}
+	msg.StructuredData = sd
+	if p.consume(' ') {
+		msg.Message = string(bytes.TrimPrefix(p.b[p.i:], []byte("\xef\xbb\xbf")))
+	} else if p.i < len(p.b) {
+		return nil, p.errorf("expected MSG")
+	}
+	return msg, nil
+}
+
+// Context returns the context queries are tested against.
+func (msg *Message) Context() matcher.Context {
+	c := matcher.Context{}
+	for id, params := range msg.StructuredData {
+		for name, v := range params {
+			c[key(id+"_"+name)] = v
+		}
+	}
+	c["facility"] = float64(msg.Facility)
+	c["severity"] = float64(msg.Severity)
+	c["version"] = float64(msg.Version)
+	for k, v := range map[string]string{"timestamp": msg.Timestamp, "hostname": msg.Hostname,
+		"app_name": msg.AppName, "procid": msg.ProcID, "msgid": msg.MsgID} {
+		if v != "" {
+			c[k] = v
+		}
+	}
+	c["message"] = msg.Message
+	return c
+}
+
+func key(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// parser reads a message from left to right.
+type parser struct {
+	b []byte
+	i int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syslog: offset %d: %s", p.i, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.i < len(p.b) && p.b[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// number reads a decimal number of at most max digits.
+func (p *parser) number(max int) (int, bool) {
+	start := p.i
+	for p.i < len(p.b) && p.i-start < max && p.b[p.i] >= '0' && p.b[p.i] <= '9' {
+		p.i++
+	}
+	n, err := strconv.Atoi(string(p.b[start:p.i]))
+	return n, err == nil
+}
+
+// token reads up to the next space.
+func (p *parser) token() string {
+	start := p.i
+	for p.i < len(p.b) && p.b[p.i] != ' ' {
+		p.i++
+	}
+	return string(p.b[start:p.i])
+}
+
+// structuredData reads STRUCTURED-DATA.
+func (p *parser) structuredData() (map[string]map[string]string, error) {
+	if p.consume('-') {
+		return nil, nil
+	}
+	sd := map[string]map[string]string{}
+	for p.consume('[') {
+		id := p.name()
+		if id == "" {
+			return nil, p.errorf("empty SD-ID")
+		}
+		params := map[string]string{}
+		for p.consume(' ') {
+			name := p.name()
+			if name == "" || !p.consume('=') || !p.consume('"') {
+				return nil, p.errorf("invalid SD-PARAM")
+			}
+			var v strings.Builder
+			for {
+				if p.i >= len(p.b) {
+					return nil, p.errorf("unterminated PARAM-VALUE")
+				}
+				c := p.b[p.i]
+				p.i++
+				if c == '"' {
+					break
+				}
+				if c == '\\' && p.i < len(p.b) && strings.IndexByte(`"\]`, p.b[p.i]) >= 0 {
+					c = p.b[p.i]
+					p.i++
+				}
+				v.WriteByte(c)
+			}
+			params[name] = v.String()
+		}
+		if !p.consume(']') {
+			return nil, p.errorf("expected ]")
+		}
+		sd[id] = params
+	}
+	if len(sd) == 0 {
+		return nil, p.errorf("expected STRUCTURED-DATA")
+	}
+	return sd, nil
+}
+
+// name reads an SD-ID or PARAM-NAME.
+func (p *parser) name() string {
+	start := p.i
+	for p.i < len(p.b) && p.b[p.i] > ' ' && p.b[p.i] < 127 && strings.IndexByte(`="]`, p.b[p.i]) < 0 {
+		p.i++
+	}
+	return string(p.b[start:p.i])
+}
+
+// Server receives messages and calls Handle for those matching Matcher.
+type Server struct {
+	Matcher *matcher.Matcher
+	// Handle is called for every matching message. It is called
+	// concurrently for messages of different TCP connections.
+	Handle func(*Message)
+	// ErrorLog logs messages that can not be parsed or tested; the standard
+	// logger when nil.
+	ErrorLog *log.Logger
+}
+
+// ListenAndServe listens on addr, udp://host:port or tcp://host:port, and
+// serves until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	network, hostport, err := splitAddr(addr)
+	if err != nil {
+		return err
+	}
+	var lc net.ListenConfig
+	if network == "udp" {
+		conn, err := lc.ListenPacket(ctx, network, hostport)
+		if err != nil {
+			return err
+		}
+		return s.ServeUDP(ctx, conn)
+	}
+	ln, err := lc.Listen(ctx, network, hostport)
+	if err != nil {
+		return err
+	}
+	return s.ServeTCP(ctx, ln)
+}
+
+// ServeUDP reads a message from every datagram received on conn until ctx is
+// done. conn is closed on return.
+func (s *Server) ServeUDP(ctx context.Context, conn net.PacketConn) error {
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		s.receive(append([]byte(nil), buf[:n]...))
+	}
+}
+
+// ServeTCP accepts connections on ln until ctx is done and reads messages
+// framed by octet counting or by newlines from them. ln is closed on return.
+func (s *Server) ServeTCP(ctx context.Context, ln net.Listener) error {
+	stop := closeOnDone(ctx, ln)
+	defer stop()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop := closeOnDone(ctx, conn)
+			defer stop()
+			r := bufio.NewReader(conn)
+			for {
+				b, err := readFrame(r)
+				if err != nil {
+					if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+						s.logf("syslog: %s: %v", conn.RemoteAddr(), err)
+					}
+					return
+				}
+				s.receive(b)
+			}
+		}()
+	}
+}
+
+// receive tests a message and hands it to Handle if it matches.
+func (s *Server) receive(b []byte) {
+	b = bytes.TrimRight(b, "\r\n")
+	if len(b) == 0 {
+		return
+	}
+	msg, err := Parse(b)
+	if err != nil {
+		s.logf("%v: %q", err, b)
+		return
+	}
+	c := msg.Context()
+	ok, err := s.Matcher.Test(&c)
+	if err != nil {
+		s.logf("syslog: %v: %q", err, b)
+		return
+	}
+	if ok {
+		s.Handle(msg)
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// readFrame reads an octet counted or newline terminated message.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	c, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if c[0] < '0' || c[0] > '9' {
+		b, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(b) > 0 {
+			err = nil
+		}
+		return b, err
+	}
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil || n > 1<<20 {
+		return nil, fmt.Errorf("invalid frame length %q", length)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+// closeOnDone closes c when ctx is done, until the returned function is
+// called.
+func closeOnDone(ctx context.Context, c io.Closer) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		c.Close()
+	}()
+	return func() { close(done) }
+}
+
+// Forwarder sends messages to a syslog server, over UDP or over TCP with
+// octet counting framing.
+//
+// A Forwarder is safe for concurrent use by multiple goroutines.
+type Forwarder struct {
+	// Addr is the server address, udp://host:port or tcp://host:port.
+	Addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Forward sends the raw message, reconnecting once if the connection broke.
+func (f *Forwarder) Forward(msg *Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	network, _, err := splitAddr(f.Addr)
+	if err != nil {
+		return err
+	}
+	b := msg.Raw
+	if network == "tcp" {
+		b = append([]byte(strconv.Itoa(len(b))+" "), b...)
+	}
+	for attempt := 0; ; attempt++ {
+		if f.conn == nil {
+			if err := f.dial(); err != nil {
+				return err
+			}
+		}
+		_, err := f.conn.Write(b)
+		if err == nil || attempt > 0 {
+			return err
+		}
+		f.conn.Close()
+		f.conn = nil
+	}
+}
+
+// Close closes the connection to the server.
+func (f *Forwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
+
+func (f *Forwarder) dial() error {
+	network, hostport, err := splitAddr(f.Addr)
+	if err != nil {
+		return err
+	}
+	f.conn, err = net.Dial(network, hostport)
+	return err
+}
+
+// splitAddr splits a udp:// or tcp:// address.
+func splitAddr(addr string) (network, hostport string, err error) {
+	i := strings.Index(addr, "://")
+	if i < 0 || addr[:i] != "udp" && addr[:i] != "tcp" {
+		return "", "", fmt.Errorf("syslog: address %q is not udp://host:port or tcp://host:port", addr)
+	}
+	return addr[:i], addr[i+3:], nil
+}
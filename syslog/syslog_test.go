@@ -0,0 +1,128 @@
+package syslog_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/syslog"
+	"github.com/stretchr/testify/assert"
+)
+
+const example = `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high \"x\" \]"] ` + "\xef\xbb\xbf" + `An application event log entry...`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	msg, err := syslog.Parse([]byte(example))
+	assert.NoError(err)
+	assert.Equal(matcher.Context{
+		"facility":                      float64(20),
+		"severity":                      float64(5),
+		"version":                       float64(1),
+		"timestamp":                     "2003-10-11T22:14:15.003Z",
+		"hostname":                      "mymachine.example.com",
+		"app_name":                      "evntslog",
+		"msgid":                         "ID47",
+		"message":                       "An application event log entry...",
+		"exampleSDID_32473_iut":         "3",
+		"exampleSDID_32473_eventSource": "Application",
+		"exampleSDID_32473_eventID":     "1011",
+		"examplePriority_32473_class":   `high "x" ]`,
+	}, msg.Context())
+
+	msg, err = syslog.Parse([]byte(`<34>1 - - - - - -`))
+	assert.NoError(err)
+	assert.Equal(&syslog.Message{Facility: 4, Severity: 2, Version: 1, Raw: []byte(`<34>1 - - - - - -`)}, msg)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`<34> - - - - - -`,
+		`<999>1 - - - - - -`,
+		`<34>1 - - - - -`,
+		`<34>1 - - - - - [id a=1]`,
+		`<34>1 - - - - - [id a="1`,
+		`<34>1 - - - - - -msg`,
+		`<34>Oct 11 22:14:15 mymachine su: 'su root' failed`,
+	} {
+		_, err := syslog.Parse([]byte(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func newServer(t *testing.T, query string) (*syslog.Server, chan string) {
+	m, err := matcher.NewMatcher(query)
+	assert.NoError(t, err)
+	got := make(chan string, 10)
+	return &syslog.Server{Matcher: m, Handle: func(msg *syslog.Message) {
+		got <- msg.Message
+	}}, got
+}
+
+func TestServeUDP(t *testing.T) {
+	s, got := newServer(t, `severity <= 3`)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.ServeUDP(ctx, conn) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	assert.NoError(t, err)
+	for _, m := range []string{`<14>1 - - - - - - info`, `<11>1 - - - - - - error`} {
+		client.Write([]byte(m))
+	}
+	assert.Equal(t, "error", <-got)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestServeTCP(t *testing.T) {
+	s, got := newServer(t, `app_name = "web"`)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.ServeTCP(ctx, ln) }()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	framed := `<14>1 - - web - - - first`
+	fmt.Fprintf(client, "%d %s", len(framed), framed)
+	fmt.Fprint(client, "<14>1 - - db - - - skipped\n<14>1 - - web - - - second\n")
+	assert.Equal(t, "first", <-got)
+	assert.Equal(t, "second", <-got)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestForwarder(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		line, _ := bufio.NewReader(conn).ReadString('!')
+		received <- line
+	}()
+
+	f := &syslog.Forwarder{Addr: "tcp://" + ln.Addr().String()}
+	defer f.Close()
+	msg, err := syslog.Parse([]byte(`<14>1 - - web - - - hi!`))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Forward(msg))
+	assert.Equal(t, "23 <14>1 - - web - - - hi!", <-received)
+
+	assert.Error(t, (&syslog.Forwarder{Addr: "host:514"}).Forward(msg))
+}
@@ -0,0 +1,110 @@
+package matcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayouts are the layouts string literals are parsed with when compared
+// with time.Time values.
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// Timestamp is a date or time literal, written like 2024-01-01 or
+// 2024-01-01T09:30:00Z without quotes.
+type Timestamp string
+
+func (t *Timestamp) Capture(values []string) error {
+	if _, ok := parseTime(values[0], time.UTC); !ok {
+		return fmt.Errorf("invalid timestamp %s", values[0])
+	}
+	*t = Timestamp(values[0])
+	return nil
+}
+
+// parseTime parses an RFC 3339 timestamp or a date. Timestamps and dates
+// without a zone are in loc.
+func parseTime(s string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareTime compares a time.Time context value with a timestamp literal,
+// or a string literal holding an RFC 3339 timestamp or a date. Timestamps and
+// dates without a zone are in the location of the context value.
+func (x *Condition) compareTime(t time.Time) (bool, error) {
+	var s string
+	switch v := x.Compare.Value; {
+	case v.Time != nil:
+		s = string(*v.Time)
+	case v.String != nil:
+		s = *v.String
+	default:
+		return x.mismatch(t)
+	}
+	lit, ok := parseTime(s, t.Location())
+	if !ok {
+		return x.mismatch(t)
+	}
+	switch {
+	case t.Before(lit):
+		return holds(x.Compare.Operator, -1), nil
+	case t.After(lit):
+		return holds(x.Compare.Operator, 1), nil
+	}
+	return holds(x.Compare.Operator, 0), nil
+}
+
+// compareTimestamp compares a timestamp literal with a context value that is
+// not a time.Time: strings holding timestamps or dates are compared as
+// times, in UTC if they have no zone.
+func (x *Condition) compareTimestamp(ctxVal interface{}) (bool, error) {
+	if s, ok := ctxVal.(string); ok {
+		if t, ok := parseTime(s, time.UTC); ok {
+			return x.compareTime(t)
+		}
+	}
+	return x.mismatch(ctxVal)
+}
+
+// compareDuration compares a duration literal, or a string literal holding
+// a duration, with a time.Duration context value or a string holding a
+// duration such as "1m30s".
+func (x *Condition) compareDuration(ctxVal interface{}) (bool, error) {
+	var lit time.Duration
+	switch v := x.Compare.Value; {
+	case v.Duration != nil:
+		lit = time.Duration(*v.Duration)
+	case v.String != nil:
+		d, err := time.ParseDuration(*v.String)
+		if err != nil {
+			return x.mismatch(ctxVal)
+		}
+		lit = d
+	default:
+		return x.mismatch(ctxVal)
+	}
+	var d time.Duration
+	switch v := ctxVal.(type) {
+	case time.Duration:
+		d = v
+	case string:
+		p, err := time.ParseDuration(v)
+		if err != nil {
+			return x.mismatch(ctxVal)
+		}
+		d = p
+	default:
+		return x.mismatch(ctxVal)
+	}
+	switch {
+	case d < lit:
+		return holds(x.Compare.Operator, -1), nil
+	case d > lit:
+		return holds(x.Compare.Operator, 1), nil
+	}
+	return holds(x.Compare.Operator, 0), nil
+}
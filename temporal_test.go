@@ -0,0 +1,141 @@
+package matcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeValues(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		query string
+		want  bool
+	}{
+		{`created = "2024-05-01T12:00:00Z"`, true},
+		{`created = "2024-05-01T14:00:00+02:00"`, true},
+		{`created > "2024-05-01"`, true},
+		{`created < "2024-05-02"`, true},
+		{`created >= "2024-05-01T12:00:01"`, false},
+		{`created <> "2024-05-01T12:00:00.000000001Z"`, true},
+		{`created = "yesterday"`, false},
+		{`created <> NULL`, true},
+	} {
+		m, err := matcher.NewMatcher(tc.query)
+		assert.NoError(t, err)
+		c := matcher.Context{"created": created}
+		got, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, tc.query)
+	}
+
+	m, err := matcher.NewMatcher(`created = 1714564800`)
+	assert.NoError(t, err)
+	_, err = m.Test(&matcher.Context{"created": created})
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+}
+
+func TestTimestampLiterals(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		query string
+		value interface{}
+		want  bool
+	}{
+		{`created = 2024-05-01T12:00:00Z`, created, true},
+		{`created > 2024-05-01`, created, true},
+		{`created < 2024-05-01T14:00:00+02:00`, created, false},
+		{`created > 2024-01-01`, "2024-05-01T12:00:00Z", true},
+		{`created = 2024-05-01T12:00:00Z`, "2024-05-01T14:00:00+02:00", true},
+		{`created < 2024-05-01T12:00:00.5Z`, "2024-05-01T12:00:00Z", true},
+		{`created > 2024-01-01`, "2023-12-31", false},
+	} {
+		m, err := matcher.NewMatcher(tc.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tc.query) {
+			continue
+		}
+		c := matcher.Context{"created": tc.value}
+		got, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "%s with %v", tc.query, tc.value)
+	}
+
+	assert := assert.New(t)
+	for _, tc := range []struct {
+		query string
+		value interface{}
+	}{
+		{`created > 2024-01-01`, "tomorrow"},
+		{`created != 2024-01-01`, 1714564800.0},
+	} {
+		m, err := matcher.NewMatcher(tc.query, matcher.WithSyntaxVersion(2))
+		assert.NoError(err)
+		_, err = m.Test(&matcher.Context{"created": tc.value})
+		assert.ErrorIs(err, matcher.ErrTypeMismatch, "%s with %v", tc.query, tc.value)
+	}
+
+	m, err := matcher.NewMatcher(`created >= 2024-01-01 AND created < 2024-02-01T00:00:00Z`, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`created >= 2024-01-01 AND created < 2024-02-01T00:00:00Z`, m.Expression.String())
+	rows := m.Expression.Rows()
+	assert.Equal("time", rows[0].Type)
+	e, err := matcher.FromRows(rows)
+	assert.NoError(err)
+	assert.Equal(m.Expression.String(), e.String())
+
+	_, err = matcher.NewMatcher(`created > 2024-13-01`)
+	assert.Error(err)
+	_, err = matcher.NewMatcher(`created > 2024-01-01`, matcher.WithSyntaxVersion(1))
+	assert.ErrorIs(err, matcher.ErrInvalidQuery)
+}
+
+func TestDurationValues(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		value interface{}
+		want  bool
+	}{
+		{`elapsed > 5m`, 6 * time.Minute, true},
+		{`elapsed > 5m`, 5 * time.Minute, false},
+		{`elapsed = 1h30m`, 90 * time.Minute, true},
+		{`elapsed <= 1.5s`, 1500 * time.Millisecond, true},
+		{`elapsed < "2s"`, time.Second, true},
+		{`elapsed < 2s`, "1s500ms", true},
+		{`elapsed < 2s`, "slow", false},
+		{`elapsed < 2s`, 1.0, false},
+		{`elapsed = 2`, 2 * time.Second, false},
+		{`elapsed != 2s`, true, true},
+	} {
+		m, err := matcher.NewMatcher(tc.query, matcher.WithMismatchPolicy(matcher.MismatchFalse))
+		assert.NoError(t, err)
+		c := matcher.Context{"elapsed": tc.value}
+		got, err := m.Test(&c)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "%s with %v", tc.query, tc.value)
+	}
+
+	m, err := matcher.NewMatcher(`elapsed > 90m`)
+	assert.NoError(t, err)
+	assert.Equal(t, "elapsed > 1h30m", m.Expression.String())
+	m, err = matcher.NewMatcher(`elapsed > 1s`, matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, err)
+	c := matcher.Context{"elapsed": 1}
+	_, err = m.Test(&c)
+	assert.ErrorIs(t, err, matcher.ErrTypeMismatch)
+}
+
+func TestTimeValuesDataset(t *testing.T) {
+	d := matcher.NewDataset([]matcher.Context{
+		{"day": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"day": "2024-05-01"},
+		{"day": "2024-05-02"},
+	})
+	d.Index("day")
+	m, err := matcher.NewMatcher(`day = "2024-05-01"`)
+	assert.NoError(t, err)
+	got, err := d.Filter(m)
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+}
@@ -0,0 +1,222 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by TenantStore.AddRule when a rule would take
+// a tenant over its limits.
+var ErrQuotaExceeded = errors.New("matcher: tenant quota exceeded")
+
+// TenantLimits are the quotas of a tenant. A limit of 0 means no limit.
+type TenantLimits struct {
+	// MaxRules is the maximum number of rules.
+	MaxRules int
+	// MaxComplexity is the maximum sum of the complexity scores of the
+	// rules, see Expression.Complexity.
+	MaxComplexity int
+}
+
+// TenantMetrics are the statistics of a tenant.
+type TenantMetrics struct {
+	Rules      int
+	Complexity int
+	// Evaluations, Matches and Errors count the rules evaluated, those that
+	// matched and those that failed to evaluate.
+	Evaluations int64
+	Matches     int64
+	Errors      int64
+	// Time is the total evaluation time.
+	Time time.Duration
+}
+
+// TenantStore holds the rules of many tenants, such as customers writing
+// their own rules, with quotas per tenant. The rules of a tenant are
+// compiled with the options of the store followed by those of the tenant, so
+// functions registered with WithFunction for a tenant are only available to
+// its rules.
+//
+// A TenantStore is safe for concurrent use.
+type TenantStore struct {
+	defaults TenantLimits
+	opts     []Option
+
+	mu      sync.RWMutex
+	tenants map[string]*tenant
+}
+
+type tenant struct {
+	limits TenantLimits
+	opts   []Option
+	// rules is replaced, never modified, so that evaluations can use it
+	// after releasing the lock.
+	rules      []*Rule
+	complexity int
+
+	evaluations, matches, errors, nanos int64 // atomic
+}
+
+// NewTenantStore returns a store applying defaults to tenants without limits
+// of their own and compiling every rule with opts.
+func NewTenantStore(defaults TenantLimits, opts ...Option) *TenantStore {
+	return &TenantStore{defaults: defaults, opts: opts, tenants: make(map[string]*tenant)}
+}
+
+// SetTenant sets the limits and options of a tenant. Rules added before
+// keep the options they were compiled with and are not checked against the
+// new limits.
+func (s *TenantStore) SetTenant(id string, limits TenantLimits, opts ...Option) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(id)
+	t.limits, t.opts = limits, opts
+}
+
+// tenant returns the tenant id, creating it. s.mu must be held for writing.
+func (s *TenantStore) tenant(id string) *tenant {
+	t, ok := s.tenants[id]
+	if !ok {
+		t = &tenant{limits: s.defaults}
+		s.tenants[id] = t
+	}
+	return t
+}
+
+// AddRule compiles a rule of a tenant, replacing its rule with the same
+// name. It fails with ErrQuotaExceeded if the tenant would exceed its
+// limits. The rule is compiled under the lock, so that it always gets the
+// options the tenant has when it is added.
+func (s *TenantStore) AddRule(id string, r Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var opts []Option
+	if t, ok := s.tenants[id]; ok {
+		opts = t.opts
+	}
+
+	all := append(append(append([]Option(nil), s.opts...), opts...), WithRuleID(r.Name))
+	m, err := NewMatcher(r.Query, all...)
+	if err != nil {
+		return fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	r.matcher = m
+
+	t := s.tenant(id)
+	rules := make([]*Rule, 0, len(t.rules)+1)
+	complexity := m.Expression.Complexity().Score
+	for _, o := range t.rules {
+		if o.Name != r.Name {
+			rules = append(rules, o)
+			complexity += o.matcher.Expression.Complexity().Score
+		}
+	}
+	rules = append(rules, &r)
+	if t.limits.MaxRules > 0 && len(rules) > t.limits.MaxRules {
+		return fmt.Errorf("%w: tenant %s: more than %d rules", ErrQuotaExceeded, id, t.limits.MaxRules)
+	}
+	if t.limits.MaxComplexity > 0 && complexity > t.limits.MaxComplexity {
+		return fmt.Errorf("%w: tenant %s: complexity %d over %d", ErrQuotaExceeded, id, complexity, t.limits.MaxComplexity)
+	}
+	sortRules(rules)
+	t.rules, t.complexity = rules, complexity
+	return nil
+}
+
+// RemoveRule removes the rule name of a tenant and reports whether it
+// existed.
+func (s *TenantStore) RemoveRule(id, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[id]
+	if !ok {
+		return false
+	}
+	rules := make([]*Rule, 0, len(t.rules))
+	for _, r := range t.rules {
+		if r.Name == name {
+			t.complexity -= r.matcher.Expression.Complexity().Score
+		} else {
+			rules = append(rules, r)
+		}
+	}
+	removed := len(rules) < len(t.rules)
+	t.rules = rules
+	return removed
+}
+
+// Rules returns the rules of a tenant in evaluation order.
+func (s *TenantStore) Rules(id string) []*Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.tenants[id]; ok {
+		return t.rules
+	}
+	return nil
+}
+
+// EvaluateAll returns every rule of a tenant matching the context, in
+// priority order, like RuleSet.EvaluateAll.
+func (s *TenantStore) EvaluateAll(id string, c *Context) ([]*Rule, error) {
+	s.mu.RLock()
+	t := s.tenants[id]
+	var rules []*Rule
+	if t != nil {
+		rules = t.rules
+	}
+	s.mu.RUnlock()
+	if t == nil {
+		return nil, nil
+	}
+
+	start := time.Now()
+	defer func() { atomic.AddInt64(&t.nanos, int64(time.Since(start))) }()
+	var out []*Rule
+	for _, r := range rules {
+		atomic.AddInt64(&t.evaluations, 1)
+		ok, err := r.matcher.Test(c)
+		if err != nil {
+			atomic.AddInt64(&t.errors, 1)
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		if ok {
+			atomic.AddInt64(&t.matches, 1)
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Metrics returns the statistics of a tenant.
+func (s *TenantStore) Metrics(id string) TenantMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tenants[id]
+	if !ok {
+		return TenantMetrics{}
+	}
+	return TenantMetrics{
+		Rules:       len(t.rules),
+		Complexity:  t.complexity,
+		Evaluations: atomic.LoadInt64(&t.evaluations),
+		Matches:     atomic.LoadInt64(&t.matches),
+		Errors:      atomic.LoadInt64(&t.errors),
+		Time:        time.Duration(atomic.LoadInt64(&t.nanos)),
+	}
+}
+
+// Tenants returns the IDs of the tenants, sorted.
+func (s *TenantStore) Tenants() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.tenants))
+	for id := range s.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,60 @@
+package matcher_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantStore(t *testing.T) {
+	assert := assert.New(t)
+	s := matcher.NewTenantStore(matcher.TenantLimits{MaxRules: 2}, matcher.WithMismatchPolicy(matcher.MismatchFalse))
+	assert.NoError(s.AddRule("acme", matcher.Rule{Name: "low", Query: "total < 10"}))
+	assert.NoError(s.AddRule("acme", matcher.Rule{Name: "big", Query: "total > 100", Priority: 1}))
+	assert.ErrorIs(s.AddRule("acme", matcher.Rule{Name: "third", Query: "a = 1"}), matcher.ErrQuotaExceeded)
+	// Replacing a rule does not count against the quota.
+	assert.NoError(s.AddRule("acme", matcher.Rule{Name: "low", Query: "total < 20"}))
+	assert.Error(s.AddRule("acme", matcher.Rule{Name: "bad", Query: "total <"}))
+
+	rules, err := s.EvaluateAll("acme", &matcher.Context{"total": 15.0})
+	assert.NoError(err)
+	assert.Len(rules, 1)
+	assert.Equal("low", rules[0].Name)
+	rules, err = s.EvaluateAll("other", &matcher.Context{"total": 15.0})
+	assert.NoError(err)
+	assert.Empty(rules)
+	_, err = s.EvaluateAll("acme", &matcher.Context{"total": true})
+	assert.NoError(err)
+
+	m := s.Metrics("acme")
+	assert.Equal(2, m.Rules)
+	assert.Equal(2, m.Complexity)
+	assert.Equal(int64(4), m.Evaluations)
+	assert.Equal(int64(1), m.Matches)
+
+	assert.True(s.RemoveRule("acme", "big"))
+	assert.False(s.RemoveRule("acme", "big"))
+	assert.Equal(1, s.Metrics("acme").Complexity)
+	assert.Equal([]string{"acme"}, s.Tenants())
+}
+
+func TestTenantStoreIsolation(t *testing.T) {
+	assert := assert.New(t)
+	s := matcher.NewTenantStore(matcher.TenantLimits{}, matcher.WithSyntaxVersion(2))
+	s.SetTenant("acme", matcher.TenantLimits{MaxComplexity: 10},
+		matcher.WithFunction("domain", func(args []*matcher.Arg) (matcher.Func, error) {
+			return func(ctx matcher.Context) (interface{}, error) {
+				email, _ := ctx[args[0].Symbol].(string)
+				return email[strings.LastIndex(email, "@")+1:], nil
+			}, nil
+		}))
+	assert.NoError(s.AddRule("acme", matcher.Rule{Name: "a", Query: `DOMAIN(email) = "example.com"`}))
+	assert.ErrorIs(s.AddRule("other", matcher.Rule{Name: "a", Query: `DOMAIN(email) = "example.com"`}), matcher.ErrInvalidQuery)
+	assert.ErrorIs(s.AddRule("acme", matcher.Rule{Name: "b", Query: `DOMAIN(email) = "a" and DOMAIN(from) = "b"`}), matcher.ErrQuotaExceeded)
+
+	rules, err := s.EvaluateAll("acme", &matcher.Context{"email": "kim@example.com"})
+	assert.NoError(err)
+	assert.Len(rules, 1)
+}
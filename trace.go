@@ -0,0 +1,109 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Trace records how a query was evaluated against a context, for debugging
+// UIs and logs. It encodes to JSON; unlike Debug it prints nothing.
+type Trace struct {
+	Query   string `json:"query"`
+	Matched bool   `json:"matched"`
+	// Error is the evaluation error, if any.
+	Error    string        `json:"error,omitempty"`
+	Branches []TraceBranch `json:"branches"`
+}
+
+// TraceBranch records an OR branch.
+type TraceBranch struct {
+	Matched    bool             `json:"matched"`
+	Conditions []TraceCondition `json:"conditions"`
+}
+
+// TraceCondition records a condition: its parts, and if it was evaluated,
+// the value it tested and the result. Conditions after the first false one
+// of a branch, and branches after the first matching one, are not evaluated.
+type TraceCondition struct {
+	Predicate string `json:"predicate"`
+	Field     string `json:"field,omitempty"`
+	Function  string `json:"function,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+	Literal   string `json:"literal,omitempty"`
+
+	Evaluated bool `json:"evaluated"`
+	// Present reports whether there was a value to test: the context had
+	// the field, or the function was called.
+	Present bool `json:"present"`
+	// Value is the tested value in JSON, or its Go syntax in a JSON string
+	// if it can not be encoded.
+	Value  json.RawMessage `json:"value,omitempty"`
+	Result bool            `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Trace evaluates the query against the context like Test and records the
+// evaluation. Adaptive ordering is not applied.
+func (m *Matcher) Trace(c *Context) *Trace {
+	ctx := *c
+	if m.now != nil {
+		ctx = make(Context)
+		m.resolve(*c, ctx)
+	}
+
+	t := &Trace{Query: m.Expression.String(), Branches: make([]TraceBranch, len(m.Expression.Or))}
+	for i, x := range m.Expression.Or {
+		b := &t.Branches[i]
+		b.Conditions = make([]TraceCondition, len(x.And))
+		for j, cond := range x.And {
+			tc := &b.Conditions[j]
+			tc.Predicate = cond.String()
+			if cond.Call != nil {
+				tc.Function = cond.Call.String()
+			} else {
+				tc.Field = cond.Symbol
+			}
+			if cond.Compare != nil {
+				tc.Operator = cond.Compare.Operator
+				tc.Literal = cond.Compare.operand()
+			}
+		}
+	}
+
+	for i, x := range m.Expression.Or {
+		b := &t.Branches[i]
+		b.Matched = true
+		for j, cond := range x.And {
+			tc := &b.Conditions[j]
+			v, found, ok, err := cond.eval(ctx)
+			tc.Evaluated, tc.Present, tc.Result = true, found, ok
+			if found {
+				tc.Value = traceValue(v)
+			}
+			if err != nil {
+				tc.Error = err.Error()
+				t.Error = err.Error()
+				b.Matched = false
+				return t
+			}
+			if !ok {
+				b.Matched = false
+				break
+			}
+		}
+		if b.Matched {
+			t.Matched = true
+			return t
+		}
+	}
+	return t
+}
+
+// traceValue encodes a value for a trace.
+func traceValue(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%#v", v))
+	}
+	return b
+}
@@ -0,0 +1,54 @@
+package matcher_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	m, err := matcher.NewMatcher(`a = 1 and b > 2 or EXISTS(c) and d = "x" or e = 1`, matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	c := matcher.Context{"a": 1, "b": 1, "c": nil, "d": "x"}
+	tr := m.Trace(&c)
+	b, err := json.Marshal(tr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"query": "a = 1 AND b > 2 OR EXISTS(c) AND d = \"x\" OR e = 1",
+		"matched": true,
+		"branches": [
+			{"matched": false, "conditions": [
+				{"predicate": "a = 1", "field": "a", "operator": "=", "literal": "1", "evaluated": true, "present": true, "value": 1, "result": true},
+				{"predicate": "b > 2", "field": "b", "operator": ">", "literal": "2", "evaluated": true, "present": true, "value": 1, "result": false}
+			]},
+			{"matched": true, "conditions": [
+				{"predicate": "EXISTS(c)", "function": "EXISTS(c)", "evaluated": true, "present": true, "value": true, "result": true},
+				{"predicate": "d = \"x\"", "field": "d", "operator": "=", "literal": "\"x\"", "evaluated": true, "present": true, "value": "x", "result": true}
+			]},
+			{"matched": false, "conditions": [
+				{"predicate": "e = 1", "field": "e", "operator": "=", "literal": "1", "evaluated": false, "present": false, "result": false}
+			]}
+		]
+	}`, string(b))
+}
+
+func TestTraceError(t *testing.T) {
+	m, err := matcher.NewMatcher(`a > 1 and b = 1`, matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, err)
+	c := matcher.Context{"a": true, "b": math.NaN()}
+	tr := m.Trace(&c)
+	assert.False(t, tr.Matched)
+	assert.Equal(t, "matcher: type mismatch: a > 1: bool value", tr.Error)
+	assert.Equal(t, tr.Error, tr.Branches[0].Conditions[0].Error)
+	assert.False(t, tr.Branches[0].Conditions[1].Evaluated)
+
+	m, err = matcher.NewMatcher(`b = 1`)
+	assert.NoError(t, err)
+	tr = m.Trace(&c)
+	assert.Equal(t, json.RawMessage(`"NaN"`), tr.Branches[0].Conditions[0].Value)
+	_, err = json.Marshal(tr)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,126 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Typed is a Matcher of values of the struct type T, or of pointers to
+// structs, whose fields are found once when it is built instead of for
+// every value tested.
+type Typed[T any] struct {
+	*Matcher
+	ptr       bool
+	accessors map[string]accessor
+}
+
+// accessor returns a field of a struct, or false if it has no value.
+type accessor func(v reflect.Value) (reflect.Value, bool)
+
+// For compiles a query for values of type T, naming fields like TestStruct.
+// T must be a struct type or a pointer to one.
+func For[T any](query string, opts ...Option) (*Typed[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("For: %v is not a struct type", t)
+	}
+	m, err := NewMatcher(query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tm := &Typed[T]{Matcher: m, ptr: t.Kind() == reflect.Ptr, accessors: make(map[string]accessor)}
+	for _, k := range m.symbols() {
+		tm.accessors[k] = accessorOf(st, k)
+	}
+	return tm, nil
+}
+
+// Test reports whether v matches the query.
+func (m *Typed[T]) Test(v T) (bool, error) {
+	rv := reflect.ValueOf(&v).Elem()
+	if m.ptr {
+		if rv.IsNil() {
+			return false, errors.New("Test: nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	return m.TestResolver(typedResolver{v: rv, accessors: m.accessors})
+}
+
+// typedResolver resolves keys with the accessors of a Typed.
+type typedResolver struct {
+	v         reflect.Value
+	accessors map[string]accessor
+}
+
+// Lookup implements Resolver.
+func (r typedResolver) Lookup(key string) (interface{}, bool) {
+	get, ok := r.accessors[key]
+	if !ok {
+		return nil, false
+	}
+	f, ok := get(r.v)
+	if !ok {
+		return nil, false
+	}
+	return plainValue(f), true
+}
+
+// accessorOf returns the accessor of key in values of the struct type t,
+// resolving it like structResolver: the field named key, or else the path
+// key names. Struct fields along the path are found now; maps and
+// interfaces are looked into when the accessor runs.
+func accessorOf(t reflect.Type, key string) accessor {
+	if f, ok := fieldsOf(t)[key]; ok {
+		return f.get
+	}
+	if strings.IndexByte(key, '.') < 0 {
+		return missing
+	}
+	var steps []accessor
+	names := strings.Split(key, ".")
+	for i, name := range names {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			rest := names[i:]
+			steps = append(steps, func(v reflect.Value) (reflect.Value, bool) {
+				for _, name := range rest {
+					var ok bool
+					if v, ok = member(v, name); !ok {
+						return reflect.Value{}, false
+					}
+				}
+				return v, true
+			})
+			break
+		}
+		f, ok := fieldsOf(t)[name]
+		if !ok {
+			return missing
+		}
+		steps = append(steps, f.get)
+		t = t.FieldByIndex(f.index).Type
+	}
+	return func(v reflect.Value) (reflect.Value, bool) {
+		for _, step := range steps {
+			var ok bool
+			if v, ok = step(v); !ok {
+				return reflect.Value{}, false
+			}
+		}
+		return v, true
+	}
+}
+
+// missing is the accessor of keys values of a type never have.
+func missing(reflect.Value) (reflect.Value, bool) {
+	return reflect.Value{}, false
+}
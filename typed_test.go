@@ -0,0 +1,72 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFor(t *testing.T) {
+	users := []user{
+		{Name: "John", Age: 42, Address: &address{City: "Tokyo"}, Labels: map[string]interface{}{"team": "core"}},
+		{Name: "Mary", Age: 30},
+	}
+	tests := []struct {
+		query string
+		want  []bool
+	}{
+		{`name = "John"`, []bool{true, false}},
+		{`age >= 30 AND age < 40`, []bool{false, true}},
+		{`address.city = "Tokyo"`, []bool{true, false}},
+		{`labels.team = "core"`, []bool{true, false}},
+		{`MISSING(address.zip)`, []bool{true, true}},
+		{`nosuch.field = 1 OR Name = "Mary"`, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		m, err := matcher.For[user](tt.query, matcher.WithSyntaxVersion(2))
+		if !assert.NoError(t, err, tt.query) {
+			continue
+		}
+		pm, err := matcher.For[*user](tt.query, matcher.WithSyntaxVersion(2))
+		assert.NoError(t, err)
+		for i, u := range users {
+			got, err := m.Test(u)
+			assert.NoError(t, err, tt.query)
+			assert.Equal(t, tt.want[i], got, "%s with %s", tt.query, u.Name)
+
+			got, err = pm.Test(&users[i])
+			assert.NoError(t, err, tt.query)
+			assert.Equal(t, tt.want[i], got, "%s with %s", tt.query, u.Name)
+
+			want, err := m.TestStruct(u)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got, "%s with %s", tt.query, u.Name)
+		}
+	}
+}
+
+func TestForInvalid(t *testing.T) {
+	assert := assert.New(t)
+	_, err := matcher.For[map[string]interface{}](`a = 1`)
+	assert.Error(err)
+	_, err = matcher.For[user](`a =`)
+	assert.Error(err)
+
+	m, err := matcher.For[*user](`name = "John"`)
+	assert.NoError(err)
+	_, err = m.Test(nil)
+	assert.Error(err)
+}
+
+func BenchmarkFor(b *testing.B) {
+	m, err := matcher.For[user](`name = "John" AND age > 40 AND address.city = "Tokyo"`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	u := user{Name: "John", Age: 42, Address: &address{City: "Tokyo"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Test(u)
+	}
+}
@@ -0,0 +1,61 @@
+package matcher
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithUnicodeNormalization normalizes string literals and the context strings
+// compared with them to the given form before comparing, so that for
+// example a composed and a decomposed "café" are equal under norm.NFC.
+func WithUnicodeNormalization(f norm.Form) Option {
+	return func(o *options) {
+		o.normalize = true
+		o.form = f
+	}
+}
+
+// compileString applies the string options to the comparison of a string
+// literal with string context values. Other context values fall back to
+// compare.
+func (x *Condition) compileString(o *options) {
+	lit := *x.Compare.Value.String
+	if o.normalize {
+		lit = o.form.String(lit)
+	}
+	op := x.Compare.Operator
+	x.test = func(ctxVal interface{}) (bool, error) {
+		s, ok := ctxVal.(string)
+		if !ok {
+			return x.compare(ctxVal)
+		}
+		if o.normalize && !o.form.IsNormalString(s) {
+			s = o.form.String(s)
+		}
+		if o.collator != nil {
+			return holds(op, o.collator.compare(s, lit)), nil
+		}
+		return holds(op, strings.Compare(s, lit)), nil
+	}
+}
+
+// holds reports whether a comparison with the given result of a three-way
+// comparison satisfies the operator.
+func holds(op string, c int) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "<>", "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
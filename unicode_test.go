@@ -0,0 +1,45 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestUnicodeNormalization(t *testing.T) {
+	composed := "caf\u00e9"
+	decomposed := "cafe\u0301"
+	cases := []struct {
+		query string
+		value interface{}
+		plain bool
+		nfc   bool
+	}{
+		{`name = "` + composed + `"`, decomposed, false, true},
+		{`name = "` + decomposed + `"`, composed, false, true},
+		{`name != "` + composed + `"`, decomposed, true, false},
+		{`name >= "` + composed + `"`, decomposed, false, true},
+		{`name = "` + composed + `"`, 1.0, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := matcher.Context{"name": c.value}
+
+			m, err := matcher.NewMatcher(c.query)
+			assert.NoError(err)
+			ok, err := m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.plain, ok)
+
+			m, err = matcher.NewMatcher(c.query, matcher.WithUnicodeNormalization(norm.NFC))
+			assert.NoError(err)
+			ok, err = m.Test(&ctx)
+			assert.NoError(err)
+			assert.Equal(c.nfc, ok)
+		})
+	}
+}
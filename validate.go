@@ -0,0 +1,58 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidQuery is returned by NewMatcher for a query that parses but can
+// never be evaluated, such as ordering against a boolean.
+var ErrInvalidQuery = errors.New("matcher: invalid query")
+
+// validate checks that every operator can be applied to its literal.
+func (e *Expression) validate() error {
+	for _, x := range e.Or {
+		for _, c := range x.And {
+			if err := c.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (x *Condition) validate() error {
+	if x.Group != nil {
+		return x.Group.validate()
+	}
+	if x.Compare == nil {
+		if x.Call == nil {
+			return fmt.Errorf("%w: %s: missing comparison", ErrInvalidQuery, x.Symbol)
+		}
+		return nil
+	}
+	// The parser captures the words of an operator as written and without
+	// the space between them.
+	switch strings.ToUpper(x.Compare.Operator) {
+	case "LIKE":
+		x.Compare.Operator = "LIKE"
+	case "NOTLIKE", "NOT LIKE":
+		x.Compare.Operator = "NOT LIKE"
+	}
+	v := x.Compare.Value
+	if v == nil {
+		return nil
+	}
+	switch x.Compare.Operator {
+	case ">", ">=", "<", "<=":
+		if v.Boolean != nil {
+			return fmt.Errorf("%w: %s: booleans can not be ordered", ErrInvalidQuery, x)
+		}
+	case "LIKE", "NOT LIKE":
+		if v.String == nil {
+			return fmt.Errorf("%w: %s: the pattern must be a string", ErrInvalidQuery, x)
+		}
+	}
+	return nil
+}
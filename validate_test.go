@@ -0,0 +1,23 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBooleanOrdering(t *testing.T) {
+	for _, q := range []string{"flag >= TRUE", "a = 1 or flag < FALSE", "a = 1 and flag > true"} {
+		_, err := matcher.NewMatcher(q)
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, q)
+	}
+
+	_, err := matcher.NewMatcher("flag >= TRUE")
+	assert.EqualError(t, err, "matcher: invalid query: flag >= TRUE: booleans can not be ordered")
+
+	for _, q := range []string{"flag = TRUE", "flag <> FALSE", "a > 1", "a >= NULL"} {
+		_, err := matcher.NewMatcher(q)
+		assert.NoError(t, err, q)
+	}
+}
@@ -0,0 +1,124 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning is a non-fatal issue of a query: a condition that is likely not
+// what its author meant, but that still evaluates.
+type Warning struct {
+	// Predicate is the condition in query syntax.
+	Predicate string
+	Message   string
+}
+
+func (w Warning) String() string {
+	return w.Predicate + ": " + w.Message
+}
+
+// Warnings returns the issues found in the query when it was compiled, in
+// query order, so that rule-management tools can report them without
+// rejecting the rule.
+func (m *Matcher) Warnings() []Warning {
+	return m.warnings
+}
+
+// warnings checks the compiled expression for likely mistakes.
+func (e *Expression) warnings() []Warning {
+	var out []Warning
+	seenBranch := make(map[string]bool)
+	for _, x := range e.Or {
+		s := x.String()
+		if seenBranch[s] {
+			out = append(out, Warning{s, "duplicate OR branch"})
+			continue
+		}
+		seenBranch[s] = true
+		seen := make(map[string]bool)
+		equal := make(map[string]*Condition)
+		for _, c := range x.And {
+			s := c.String()
+			if seen[s] {
+				out = append(out, Warning{s, "duplicate condition"})
+				continue
+			}
+			seen[s] = true
+			if msg := c.warning(); msg != "" {
+				out = append(out, Warning{s, msg})
+			}
+			if c.Group != nil {
+				out = append(out, c.Group.warnings()...)
+				continue
+			}
+			if c.Call != nil || c.Not || c.Compare.Operator != "=" || c.Compare.Field != "" || c.Compare.Value.Null || c.approximate() ||
+				c.Compare.Value.String != nil && c.opts != nil && (c.opts.normalize || c.opts.collator != nil) {
+				continue
+			}
+			if o, ok := equal[c.Symbol]; ok {
+				// Literals of different types can equal the same value, as
+				// 1 and "1" do for the string "1", unless types are strict.
+				if c.Compare.Value.kind() == o.Compare.Value.kind() || c.opts != nil && c.opts.strict {
+					out = append(out, Warning{s, fmt.Sprintf("contradicts %s, the AND chain never matches", o)})
+				}
+			} else {
+				equal[c.Symbol] = c
+			}
+		}
+	}
+	return out
+}
+
+// warning returns the issue of a single condition, if any.
+func (x *Condition) warning() string {
+	if x.Compare == nil || x.Not {
+		return ""
+	}
+	if x.Compare.Field != "" {
+		if x.Compare.Field == x.Symbol {
+			return "compares the field with itself"
+		}
+		return ""
+	}
+	v, op := x.Compare.Value, x.Compare.Operator
+	if isLike(op) {
+		if !strings.ContainsAny(*v.String, "%_") {
+			return "pattern without % or _ wildcards, the condition is an equality"
+		}
+		return ""
+	}
+	ordering := op != "=" && op != "<>" && op != "!="
+	switch {
+	case v.Null && ordering:
+		return "NULL can not be ordered, the condition never holds"
+	case v.Float != nil && *v.Float != *v.Float && (x.opts == nil || x.opts.nan == NaNIEEE):
+		if ordering || op == "=" {
+			return "no number, NaN included, is equal to or ordered with NaN; see WithNaNPolicy"
+		}
+		return "every number, NaN included, is unequal to NaN; see WithNaNPolicy"
+	case v.String != nil:
+		if _, ok := parseNumber(*v.String); !ok {
+			return ""
+		}
+		if ordering {
+			return fmt.Sprintf("string literal %s orders strings lexically; write %s to compare numbers", v.literal(), *v.String)
+		}
+		return fmt.Sprintf("string literal %s does not equal the number %s; write %s to compare numbers", v.literal(), *v.String, *v.String)
+	}
+	return ""
+}
+
+// kind returns the type of a literal that is not NULL.
+func (v *Value) kind() string {
+	switch {
+	case v.Float != nil:
+		return "number"
+	case v.String != nil:
+		return "string"
+	case v.Boolean != nil:
+		return "boolean"
+	case v.Duration != nil:
+		return "duration"
+	}
+	return "timestamp"
+}
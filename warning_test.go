@@ -0,0 +1,47 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnings(t *testing.T) {
+	assert := assert.New(t)
+	q := `a = 1 and a = 2 and b > NULL and c = NaN and d = "10" and e >= "2.5" and f = "x" ` +
+		`or a = 1 and a = 1 and a = 1.0 or c <> NaN or c <> NaN`
+	m, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	var got []string
+	for _, w := range m.Warnings() {
+		got = append(got, w.String())
+	}
+	assert.Equal([]string{
+		"a = 2: contradicts a = 1, the AND chain never matches",
+		"b > NULL: NULL can not be ordered, the condition never holds",
+		"c = NaN: no number, NaN included, is equal to or ordered with NaN; see WithNaNPolicy",
+		`d = "10": string literal "10" does not equal the number 10; write 10 to compare numbers`,
+		`e >= "2.5": string literal "2.5" orders strings lexically; write 2.5 to compare numbers`,
+		"a = 1: duplicate condition",
+		"a = 1: duplicate condition",
+		"c <> NaN: every number, NaN included, is unequal to NaN; see WithNaNPolicy",
+		"c <> NaN: duplicate OR branch",
+	}, got)
+
+	m, err = matcher.NewMatcher(`a = 1 and a = "1"`)
+	assert.NoError(err)
+	assert.Equal([]matcher.Warning{{Predicate: `a = "1"`, Message: `string literal "1" does not equal the number 1; write 1 to compare numbers`}}, m.Warnings())
+	m, err = matcher.NewMatcher(`a = TRUE and a = 1`)
+	assert.NoError(err)
+	assert.Empty(m.Warnings())
+	m, err = matcher.NewMatcher(`a = TRUE and a = 1`, matcher.WithStrictTypes())
+	assert.NoError(err)
+	assert.Equal([]matcher.Warning{{Predicate: "a = 1", Message: "contradicts a = TRUE, the AND chain never matches"}}, m.Warnings())
+
+	m, err = matcher.NewMatcher("a = 1 and a = 1.00000001 or c = NaN",
+		matcher.WithFloatEpsilon(1e-6), matcher.WithNaNPolicy(matcher.NaNNeverMatches), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Empty(m.Warnings())
+}
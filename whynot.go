@@ -0,0 +1,106 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Failure is a condition that does not hold for a context.
+type Failure struct {
+	// Predicate is the condition in query syntax.
+	Predicate string
+	// Field is the field of the condition, empty for a function call.
+	Field string
+	// Present reports whether there was a value to test.
+	Present bool
+	// Value is the tested value.
+	Value interface{}
+	// Err is the evaluation error, if any.
+	Err error
+}
+
+func (f Failure) String() string {
+	switch {
+	case f.Err != nil:
+		return fmt.Sprintf("%s failed: %v", f.Predicate, f.Err)
+	case f.Field != "" && !f.Present:
+		return fmt.Sprintf("%s is missing (%s)", f.Field, f.Predicate)
+	case f.Field != "":
+		return fmt.Sprintf("%s is %s (%s)", f.Field, traceValue(f.Value), f.Predicate)
+	}
+	return fmt.Sprintf("%s is false", f.Predicate)
+}
+
+// BranchReport explains why an OR branch does not match.
+type BranchReport struct {
+	// Branch is the index of the OR branch.
+	Branch int
+	// Failures are the conditions of the branch that do not hold, all of
+	// which must be fixed for the branch to match.
+	Failures []Failure
+	// Unevaluated are the calls of the branch that were not evaluated, see
+	// WhyNot.
+	Unevaluated []string
+}
+
+func (b BranchReport) String() string {
+	reasons := make([]string, len(b.Failures))
+	for i, f := range b.Failures {
+		reasons[i] = f.String()
+	}
+	s := fmt.Sprintf("branch %d failed", b.Branch+1)
+	switch {
+	case len(reasons) == 0:
+		return s + " because of one of " + strings.Join(b.Unevaluated, ", ")
+	case len(reasons) == 1:
+		s += " only"
+	}
+	s += " because " + strings.Join(reasons, " and ")
+	if len(b.Unevaluated) > 0 {
+		s += "; not evaluated: " + strings.Join(b.Unevaluated, ", ")
+	}
+	return s
+}
+
+// WhyNot explains why the context does not match the query: for every OR
+// branch, it lists all the conditions that do not hold, not just the first
+// one. It returns nil if the context matches a branch without calls.
+//
+// Calls of functions other than EXISTS, MISSING, ANY and ALL are not
+// evaluated, as evaluating them may change their state; they are listed as
+// Unevaluated. A branch with such calls is reported even if its other
+// conditions hold, in which case one of its calls must have failed for a
+// context that does not match.
+func (m *Matcher) WhyNot(c *Context) []BranchReport {
+	ctx := *c
+	if m.now != nil {
+		ctx = make(Context)
+		m.resolve(*c, ctx)
+	}
+
+	var reports []BranchReport
+	for i, x := range m.Expression.Or {
+		r := BranchReport{Branch: i}
+		for _, cond := range x.And {
+			if cond.Call != nil && !cond.quantified && (cond.Compare != nil || !isExistence(cond.Call)) ||
+				cond.Group != nil && !cond.Group.pure() {
+				r.Unevaluated = append(r.Unevaluated, cond.String())
+				continue
+			}
+			v, found, ok, err := cond.eval(ctx)
+			if ok && err == nil {
+				continue
+			}
+			f := Failure{Predicate: cond.String(), Present: found, Value: v, Err: err}
+			if cond.Call == nil || cond.quantified {
+				f.Field = cond.field()
+			}
+			r.Failures = append(r.Failures, f)
+		}
+		if len(r.Failures) == 0 && len(r.Unevaluated) == 0 {
+			return nil
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
@@ -0,0 +1,34 @@
+package matcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhyNot(t *testing.T) {
+	assert := assert.New(t)
+	m, err := matcher.NewMatcher(`total > 100 and country = "JP" or age >= 30 and EXISTS(vip) or COUNT_OVER(1m) > 5 and flagged = TRUE`,
+		matcher.WithMismatchPolicy(matcher.MismatchError), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	reports := m.WhyNot(&matcher.Context{"total": 50.0, "country": "US", "age": 29.0, "vip": true, "flagged": true})
+	var got []string
+	for _, r := range reports {
+		got = append(got, r.String())
+	}
+	assert.Equal([]string{
+		`branch 1 failed because total is 50 (total > 100) and country is "US" (country = "JP")`,
+		`branch 2 failed only because age is 29 (age >= 30)`,
+		`branch 3 failed because of one of COUNT_OVER(1m) > 5`,
+	}, got)
+
+	reports = m.WhyNot(&matcher.Context{"total": true, "country": "JP", "age": 31.0})
+	assert.Len(reports, 3)
+	assert.ErrorIs(reports[0].Failures[0].Err, matcher.ErrTypeMismatch)
+	assert.Equal("branch 2 failed only because EXISTS(vip) is false", reports[1].String())
+	assert.Equal(`branch 3 failed only because flagged is missing (flagged = TRUE); not evaluated: COUNT_OVER(1m) > 5`, reports[2].String())
+
+	assert.Nil(m.WhyNot(&matcher.Context{"total": 150.0, "country": "JP"}))
+}
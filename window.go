@@ -0,0 +1,90 @@
+package matcher
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// countOver compiles COUNT_OVER(window[, field]), which records the context
+// and returns how many contexts it recorded within the last window, the
+// current one included, as a number. With a field, contexts are counted per
+// value of that field.
+//
+// Like every stateful function it only sees the contexts evaluation reaches,
+// so in `level = "error" AND COUNT_OVER(5m) > 10` it counts errors.
+func countOver(c *Call, o *options) (Func, error) {
+	args := c.Args
+	if len(args) < 1 || len(args) > 2 || args[0].Duration == nil {
+		return nil, errors.New("want COUNT_OVER(window[, field])")
+	}
+	var field string
+	if len(args) == 2 {
+		if field = args[1].Symbol; field == "" {
+			return nil, errors.New("second argument must be a field")
+		}
+	}
+	w := &slidingWindow{size: time.Duration(*args[0].Duration), groups: make(map[string][]time.Time)}
+	now := o.clock()
+	return func(ctx Context) (interface{}, error) {
+		var key string
+		if field != "" {
+			v, _ := lookup(ctx, field)
+			key = keyString(v)
+		}
+		return float64(w.add(key, now())), nil
+	}, nil
+}
+
+// slidingWindow counts events per key over the last size. Events may be
+// added out of order: they are kept sorted, and only expire once the latest
+// event is a whole window past them.
+type slidingWindow struct {
+	size time.Duration
+
+	mu        sync.Mutex
+	groups    map[string][]time.Time
+	latest    time.Time
+	lastSweep time.Time
+}
+
+// add records an event of key at t and returns the number of events of key
+// within the window ending at t.
+func (w *slidingWindow) add(key string, t time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t.After(w.latest) {
+		w.latest = t
+	}
+	horizon := w.latest.Add(-w.size)
+	events := expire(w.groups[key], horizon)
+	i := sort.Search(len(events), func(i int) bool { return events[i].After(t) })
+	events = append(events, time.Time{})
+	copy(events[i+1:], events[i:])
+	events[i] = t
+	w.groups[key] = events
+
+	// Drop keys that have not been seen for a whole window.
+	if w.latest.Sub(w.lastSweep) > w.size {
+		for k, e := range w.groups {
+			if e = expire(e, horizon); len(e) == 0 {
+				delete(w.groups, k)
+			} else {
+				w.groups[k] = e
+			}
+		}
+		w.lastSweep = w.latest
+	}
+	return len(expire(events[:i+1], t.Add(-w.size)))
+}
+
+// expire drops the events before start from the ordered events.
+func expire(events []time.Time, start time.Time) []time.Time {
+	i := 0
+	for i < len(events) && !events[i].After(start) {
+		i++
+	}
+	return events[i:]
+}
@@ -0,0 +1,102 @@
+package matcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuwa72/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock for tests that only moves when told to.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)}
+}
+
+func TestCountOver(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`level = "error" and COUNT_OVER(5m) > 2`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+	assert.Equal(`level = "error" AND COUNT_OVER(5m) > 2`, m.Expression.String())
+
+	test := func(level string) bool {
+		ok, err := m.Test(&matcher.Context{"level": level})
+		assert.NoError(err)
+		return ok
+	}
+
+	assert.False(test("error"))
+	clock.advance(time.Minute)
+	assert.False(test("info")) // not counted
+	assert.False(test("error"))
+	clock.advance(time.Minute)
+	assert.True(test("error")) // third error within 5m
+	clock.advance(4*time.Minute + time.Second)
+	assert.False(test("error")) // the first two errors left the window
+	assert.True(test("error"))
+}
+
+func TestCountOverGroups(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`COUNT_OVER(1m, host) >= 2`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	test := func(host interface{}) bool {
+		ok, err := m.Test(&matcher.Context{"host": host})
+		assert.NoError(err)
+		return ok
+	}
+	assert.False(test("a"))
+	assert.False(test("b"))
+	assert.True(test("a"))
+	assert.False(test(1.0))
+	assert.True(test(1))
+	clock.advance(2 * time.Minute)
+	assert.False(test("b"))
+}
+
+func TestCountOverOutOfOrder(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock()
+	m, err := matcher.NewMatcher(`COUNT_OVER(5m) > 2`, matcher.WithClock(clock.now), matcher.WithSyntaxVersion(2))
+	assert.NoError(err)
+
+	test := func() bool {
+		ok, err := m.Test(&matcher.Context{})
+		assert.NoError(err)
+		return ok
+	}
+	clock.advance(10 * time.Minute)
+	assert.False(test())
+	// A late event is counted without expiring the later one.
+	clock.advance(-6 * time.Minute)
+	assert.False(test())
+	clock.advance(7 * time.Minute)
+	assert.False(test()) // the late event left the window
+	assert.True(test())
+}
+
+func TestCountOverArgs(t *testing.T) {
+	for _, q := range []string{"COUNT_OVER() > 1", "COUNT_OVER(5) > 1", "COUNT_OVER(5m, 1) > 1", "COUNT_OVER(5m, a, b) > 1"} {
+		_, err := matcher.NewMatcher(q, matcher.WithSyntaxVersion(2))
+		assert.ErrorIs(t, err, matcher.ErrInvalidQuery, q)
+	}
+}
+
+func TestCountOverIsNotReordered(t *testing.T) {
+	m, err := matcher.NewMatcher(`COUNT_OVER(1m) > 100 and a = 1`, matcher.WithAdaptiveOrdering(1), matcher.WithSyntaxVersion(2))
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err := m.Test(&matcher.Context{"a": 2.0})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "COUNT_OVER(1m) > 100 AND a = 1", m.EvaluationOrder())
+}
@@ -0,0 +1,101 @@
+// Package zapmatcher filters zap log entries with matcher rules. It is a
+// module of its own to keep zap out of the dependencies of the matcher module.
+//
+// The context of an entry has the keys level (such as "info"), message,
+// logger and caller, and the fields of the entry and its logger under their
+// keys as encoded by a zapcore.MapObjectEncoder: numbers, strings, booleans,
+// durations as int64 nanoseconds and times as time.Time. Fields win over the
+// entry keys.
+package zapmatcher
+
+import (
+	"github.com/kuwa72/matcher"
+	"go.uber.org/zap/zapcore"
+)
+
+// Verdict is the Action of a matcher.Rule keeping or dropping the entries it
+// matches. A rule with a zapcore.Level as Action re-levels them instead.
+type Verdict int
+
+const (
+	Keep Verdict = iota
+	Drop
+)
+
+// core is a zapcore.Core applying rules before writing to another core.
+type core struct {
+	zapcore.Core
+	rules  *matcher.RuleSet
+	fields []zapcore.Field
+}
+
+// NewCore returns a core writing entries to c after applying the first
+// matching rule to them; entries no rule matches are kept. A re-leveled entry
+// is dropped when c is not enabled for its new level. Only entries enabled in
+// c are seen at all, so rules can raise or lower the level of logged entries
+// but can not bring back entries below the level of c. A rule failing to
+// evaluate keeps the entry unchanged.
+func NewCore(c zapcore.Core, rules []matcher.Rule, opts ...matcher.Option) (zapcore.Core, error) {
+	s, err := matcher.NewRuleSet(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &core{Core: c, rules: s}, nil
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{
+		Core:   c.Core.With(fields),
+		rules:  c.rules,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ctx := entryContext(ent, c.fields, fields)
+	r, err := c.rules.EvaluateFirst(&ctx)
+	if err != nil || r == nil {
+		return c.Core.Write(ent, fields)
+	}
+	switch a := r.Action.(type) {
+	case Verdict:
+		if a == Drop {
+			return nil
+		}
+	case zapcore.Level:
+		if !c.Core.Enabled(a) {
+			return nil
+		}
+		ent.Level = a
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// entryContext builds the context of an entry.
+func entryContext(ent zapcore.Entry, with, fields []zapcore.Field) matcher.Context {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range with {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	c := make(matcher.Context, len(enc.Fields)+4)
+	c["level"] = ent.Level.String()
+	c["message"] = ent.Message
+	c["logger"] = ent.LoggerName
+	if ent.Caller.Defined {
+		c["caller"] = ent.Caller.TrimmedPath()
+	}
+	for k, v := range enc.Fields {
+		c[k] = v
+	}
+	return c
+}
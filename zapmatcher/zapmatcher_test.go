@@ -0,0 +1,49 @@
+package zapmatcher_test
+
+import (
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/zapmatcher"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCore(t *testing.T) {
+	assert := assert.New(t)
+	obs, logs := observer.New(zapcore.InfoLevel)
+	c, err := zapmatcher.NewCore(obs, []matcher.Rule{
+		{Name: "health", Query: `path = "/healthz"`, Action: zapmatcher.Drop},
+		{Name: "timeouts", Query: `level = "error" and message = "timeout" and attempt < 3`, Action: zapcore.WarnLevel},
+		{Name: "noise", Query: `component = "cache" and level = "info"`, Action: zapcore.DebugLevel},
+		{Name: "payments", Query: `component = "payments"`, Priority: 1, Action: zapmatcher.Keep},
+	})
+	assert.NoError(err)
+	log := zap.New(c)
+
+	log.Info("request", zap.String("path", "/healthz"))
+	log.Info("request", zap.String("path", "/orders"))
+	log.Error("timeout", zap.Int("attempt", 1))
+	log.Error("timeout", zap.Int("attempt", 5))
+	log.With(zap.String("component", "cache")).Info("miss")
+	log.With(zap.String("component", "payments")).Info("charged", zap.String("path", "/healthz"))
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Level.String()+" "+e.Message)
+	}
+	assert.Equal([]string{"info request", "warn timeout", "error timeout", "info charged"}, got)
+}
+
+func TestCoreEvalError(t *testing.T) {
+	obs, logs := observer.New(zapcore.InfoLevel)
+	c, err := zapmatcher.NewCore(obs, []matcher.Rule{
+		{Name: "strict", Query: `attempt > 1`, Action: zapmatcher.Drop},
+	}, matcher.WithMismatchPolicy(matcher.MismatchError))
+	assert.NoError(t, err)
+
+	zap.New(c).Info("retry", zap.Bool("attempt", true))
+	assert.Equal(t, 1, logs.Len())
+}
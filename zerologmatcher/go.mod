@@ -0,0 +1,21 @@
+module github.com/kuwa72/matcher/zerologmatcher
+
+go 1.23
+
+require (
+	github.com/kuwa72/matcher v0.1.0
+	github.com/rs/zerolog v1.35.1
+	github.com/stretchr/testify v1.7.2
+)
+
+require (
+	github.com/alecthomas/participle/v2 v2.0.0-alpha9 // indirect
+	github.com/alecthomas/repr v0.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,100 @@
+// Package zerologmatcher filters zerolog events with matcher rules. It is a
+// module of its own to keep zerolog out of the dependencies of the matcher
+// module.
+//
+// Filtering happens in a writer rather than a zerolog.Hook because hooks do
+// not see the fields of an event. The context of an event is its top-level
+// JSON object as written by zerolog, so it has the keys level, message and
+// time under the names configured in zerolog besides the fields of the event.
+// Numbers are json.Number values.
+package zerologmatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/kuwa72/matcher"
+	"github.com/rs/zerolog"
+)
+
+// Verdict is the Action of a matcher.Rule keeping or dropping the events it
+// matches. A rule with a zerolog.Level as Action re-levels them instead.
+type Verdict int
+
+const (
+	Keep Verdict = iota
+	Drop
+)
+
+// Writer is a zerolog.LevelWriter applying the first matching rule to each
+// event before writing it to another writer; events no rule matches are kept.
+// A re-leveled event has its level field rewritten, which re-encodes the
+// event with its fields sorted by name. Only events enabled in the logger
+// reach the writer, so rules can raise or lower the level of logged events
+// but can not bring back events below the level of the logger. Events that
+// are not JSON objects and events a rule fails to evaluate on are written
+// unchanged.
+//
+// A Writer is safe for concurrent use if the writer it writes to is.
+type Writer struct {
+	w     io.Writer
+	rules *matcher.RuleSet
+}
+
+// NewWriter returns a writer writing events to w after applying rules.
+func NewWriter(w io.Writer, rules []matcher.Rule, opts ...matcher.Option) (*Writer, error) {
+	s, err := matcher.NewRuleSet(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, rules: s}, nil
+}
+
+// Write implements io.Writer for events written without a level.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return w.write(level, p)
+	}
+	c := matcher.Context(doc)
+	r, err := w.rules.EvaluateFirst(&c)
+	if err != nil || r == nil {
+		return w.write(level, p)
+	}
+	switch a := r.Action.(type) {
+	case Verdict:
+		if a == Drop {
+			return len(p), nil
+		}
+	case zerolog.Level:
+		doc[zerolog.LevelFieldName] = a.String()
+		out, err := json.Marshal(doc)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.HasSuffix(p, []byte("\n")) {
+			out = append(out, '\n')
+		}
+		if _, err := w.write(a, out); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return w.write(level, p)
+}
+
+// write writes p to the underlying writer, with its level if it takes one.
+func (w *Writer) write(level zerolog.Level, p []byte) (int, error) {
+	if lw, ok := w.w.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,59 @@
+package zerologmatcher_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kuwa72/matcher"
+	"github.com/kuwa72/matcher/zerologmatcher"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	w, err := zerologmatcher.NewWriter(&buf, []matcher.Rule{
+		{Name: "health", Query: `path = "/healthz"`, Action: zerologmatcher.Drop},
+		{Name: "timeouts", Query: `level = "error" and message = "timeout" and attempt < 3`, Action: zerolog.WarnLevel},
+		{Name: "payments", Query: `component = "payments"`, Priority: 1, Action: zerologmatcher.Keep},
+	})
+	assert.NoError(err)
+	log := zerolog.New(w)
+
+	log.Info().Str("path", "/healthz").Msg("request")
+	log.Info().Str("path", "/orders").Msg("request")
+	log.Error().Int("attempt", 1).Msg("timeout")
+	log.Error().Int("attempt", 5).Msg("timeout")
+	log.Info().Str("component", "payments").Str("path", "/healthz").Msg("charged")
+
+	assert.Equal(`{"level":"info","path":"/orders","message":"request"}
+{"attempt":1,"level":"warn","message":"timeout"}
+{"level":"error","attempt":5,"message":"timeout"}
+{"level":"info","component":"payments","path":"/healthz","message":"charged"}
+`, buf.String())
+}
+
+// levels records the levels events are written with.
+type levels struct {
+	bytes.Buffer
+	got []zerolog.Level
+}
+
+func (l *levels) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	l.got = append(l.got, level)
+	return l.Write(p)
+}
+
+func TestWriterLevelWriter(t *testing.T) {
+	var out levels
+	w, err := zerologmatcher.NewWriter(&out, []matcher.Rule{
+		{Name: "quiet", Query: `component = "cache"`, Action: zerolog.DebugLevel},
+	})
+	assert.NoError(t, err)
+	log := zerolog.New(w)
+
+	log.Info().Str("component", "cache").Msg("miss")
+	log.Info().Msg("hello")
+	assert.Equal(t, []zerolog.Level{zerolog.DebugLevel, zerolog.InfoLevel}, out.got)
+}